@@ -7,6 +7,8 @@ package main
 */
 import "C"
 
+import "unsafe"
+
 //export varnam_reindex_dictionary
 func varnam_reindex_dictionary(varnamHandleID C.int) C.int {
 	handle := getVarnamHandle(varnamHandleID)
@@ -14,3 +16,94 @@ func varnam_reindex_dictionary(varnamHandleID C.int) C.int {
 
 	return checkError(handle.err)
 }
+
+//export varnam_compact_learnings
+func varnam_compact_learnings(varnamHandleID C.int, changed unsafe.Pointer) C.int {
+	handle := getVarnamHandle(varnamHandleID)
+
+	count, err := handle.varnam.CompactLearnings()
+	handle.err = err
+	if err != nil {
+		return checkError(err)
+	}
+
+	*(*C.int)(changed) = C.int(count)
+
+	return C.VARNAM_SUCCESS
+}
+
+//export varnam_list_profiles
+func varnam_list_profiles(varnamHandleID C.int, resultPointer **C.varray) C.int {
+	handle := getVarnamHandle(varnamHandleID)
+
+	profiles, err := handle.varnam.ListProfiles()
+	handle.err = err
+	if err != nil {
+		return checkError(err)
+	}
+
+	ptr := C.varray_init()
+	for _, profile := range profiles {
+		// Note that C.CString uses malloc()
+		C.varray_push(ptr, unsafe.Pointer(C.CString(profile)))
+	}
+	*resultPointer = ptr
+
+	return C.VARNAM_SUCCESS
+}
+
+//export varnam_create_profile
+func varnam_create_profile(varnamHandleID C.int, profile *C.char) C.int {
+	handle := getVarnamHandle(varnamHandleID)
+	handle.err = handle.varnam.CreateProfile(C.GoString(profile))
+
+	return checkError(handle.err)
+}
+
+//export varnam_delete_profile
+func varnam_delete_profile(varnamHandleID C.int, profile *C.char) C.int {
+	handle := getVarnamHandle(varnamHandleID)
+	handle.err = handle.varnam.DeleteProfile(C.GoString(profile))
+
+	return checkError(handle.err)
+}
+
+// Empty profile removes the base profile
+//
+//export varnam_set_base_profile
+func varnam_set_base_profile(varnamHandleID C.int, profile *C.char) C.int {
+	handle := getVarnamHandle(varnamHandleID)
+	handle.err = handle.varnam.SetBaseProfile(C.GoString(profile))
+
+	return checkError(handle.err)
+}
+
+//export varnam_attach_dictionary
+func varnam_attach_dictionary(varnamHandleID C.int, dictPath *C.char) C.int {
+	handle := getVarnamHandle(varnamHandleID)
+	handle.err = handle.varnam.AttachDictionary(C.GoString(dictPath))
+
+	return checkError(handle.err)
+}
+
+//export varnam_detach_dictionary
+func varnam_detach_dictionary(varnamHandleID C.int, dictPath *C.char) C.int {
+	handle := getVarnamHandle(varnamHandleID)
+	handle.err = handle.varnam.DetachDictionary(C.GoString(dictPath))
+
+	return checkError(handle.err)
+}
+
+//export varnam_get_attached_dictionaries
+func varnam_get_attached_dictionaries(varnamHandleID C.int, resultPointer **C.varray) C.int {
+	handle := getVarnamHandle(varnamHandleID)
+
+	ptr := C.varray_init()
+	for _, dictPath := range handle.varnam.GetAttachedDictionaries() {
+		// Note that C.CString uses malloc()
+		C.varray_push(ptr, unsafe.Pointer(C.CString(dictPath)))
+	}
+	*resultPointer = ptr
+
+	return C.VARNAM_SUCCESS
+}
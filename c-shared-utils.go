@@ -5,7 +5,11 @@ package main
 */
 import "C"
 
-import "github.com/varnamproject/govarnam/govarnam"
+import (
+	"unsafe"
+
+	"github.com/varnamproject/govarnam/govarnam"
+)
 
 func cSymbolToGoSymbol(symbol C.struct_Symbol_t) govarnam.Symbol {
 	var goSymbol govarnam.Symbol
@@ -40,3 +44,12 @@ func goSymbolToCSymbol(symbol govarnam.Symbol) *C.struct_Symbol_t {
 		C.int(symbol.Flags),
 	)
 }
+
+func makeCStringsArray(items []string) *C.varray {
+	ptr := C.varray_init()
+	for _, item := range items {
+		// Note that C.CString uses malloc()
+		C.varray_push(ptr, unsafe.Pointer(C.CString(item)))
+	}
+	return ptr
+}
@@ -9,9 +9,22 @@ Suggestion* makeSuggestion(char* word, int weight, int learned_on)
   sug->Word = word;
   sug->Weight = weight;
   sug->LearnedOn = learned_on;
+  sug->Source = 0;
+  sug->Score = NULL;
   return sug;
 }
 
+ScoreBreakdown* makeScoreBreakdown(int symbol_weight, int learned_weight, int recency, int pattern_length_bonus, int edit_penalty)
+{
+  ScoreBreakdown *score = (ScoreBreakdown*) malloc (sizeof(ScoreBreakdown));
+  score->SymbolWeight = symbol_weight;
+  score->LearnedWeight = learned_weight;
+  score->Recency = recency;
+  score->PatternLengthBonus = pattern_length_bonus;
+  score->EditPenalty = edit_penalty;
+  return score;
+}
+
 TransliterationResult* makeResult(varray* exact_words, varray* exact_matches, varray* dictionary_suggestions, varray* pattern_dictionary_suggestions, varray* tokenizer_suggestions, varray* greedy_tokenized)
 {
   TransliterationResult *result = (TransliterationResult*) malloc (sizeof(TransliterationResult));
@@ -31,6 +44,8 @@ void destroySuggestions(void* pointer)
     Suggestion* sug = (Suggestion*) pointer;
     free(sug->Word);
     sug->Word = NULL;
+    free(sug->Score);
+    sug->Score = NULL;
     free(sug);
     sug = NULL;
   }
@@ -58,6 +73,37 @@ void destroyTransliterationResult(TransliterationResult* result)
   result = NULL;
 }
 
+TextSegment* makeTextSegment(int start, int end, char* text, int is_word, varray* suggestions)
+{
+  TextSegment *segment = (TextSegment*) malloc (sizeof(TextSegment));
+  segment->Start = start;
+  segment->End = end;
+  segment->Text = text;
+  segment->IsWord = is_word;
+  segment->Suggestions = suggestions;
+
+  return segment;
+}
+
+void destroyTextSegment(void* pointer)
+{
+  if (pointer != NULL) {
+    TextSegment* segment = (TextSegment*) pointer;
+    free(segment->Text);
+    segment->Text = NULL;
+    destroySuggestionsArray(segment->Suggestions);
+    segment->Suggestions = NULL;
+    free(segment);
+    segment = NULL;
+  }
+}
+
+void destroyTextSegmentsArray(varray* pointer)
+{
+  varray_free(pointer, &destroyTextSegment);
+  pointer = NULL;
+}
+
 SchemeDetails* makeSchemeDetails(char* Identifier, char* LangCode, char* DisplayName, char* Author, char* CompiledDate, int IsStable)
 {
   SchemeDetails* sd = (SchemeDetails*) malloc (sizeof(SchemeDetails));
@@ -136,3 +182,66 @@ void destroySymbolArray(void* cSymbols)
 {
   varray_free(cSymbols, &destroySymbol);
 }
+
+VSTIssue* makeVSTIssue(int Severity, char* Check, int SymbolID, char* Message)
+{
+  VSTIssue *issue = (VSTIssue*) malloc (sizeof(VSTIssue));
+  issue->Severity = Severity;
+  issue->Check = Check;
+  issue->SymbolID = SymbolID;
+  issue->Message = Message;
+  return issue;
+}
+
+void destroyVSTIssue(void* pointer)
+{
+  if (pointer != NULL) {
+    VSTIssue* issue = (VSTIssue*) pointer;
+    free(issue->Check);
+    free(issue->Message);
+    issue->Check = NULL;
+    issue->Message = NULL;
+    free(issue);
+    issue = NULL;
+  }
+}
+
+VSTReport* makeVSTReport(int SchemaVersion, int SymbolsCount, varray* Issues)
+{
+  VSTReport *report = (VSTReport*) malloc (sizeof(VSTReport));
+  report->SchemaVersion = SchemaVersion;
+  report->SymbolsCount = SymbolsCount;
+  report->Issues = Issues;
+  return report;
+}
+
+void destroyVSTReport(VSTReport* report)
+{
+  varray_free(report->Issues, &destroyVSTIssue);
+  report->Issues = NULL;
+  free(report);
+  report = NULL;
+}
+
+MergeReport* makeMergeReport(varray* AddedWords, varray* UpdatedWords, varray* RemovedWords, int AddedPatterns, int RemovedPatterns)
+{
+  MergeReport *report = (MergeReport*) malloc (sizeof(MergeReport));
+  report->AddedWords = AddedWords;
+  report->UpdatedWords = UpdatedWords;
+  report->RemovedWords = RemovedWords;
+  report->AddedPatterns = AddedPatterns;
+  report->RemovedPatterns = RemovedPatterns;
+  return report;
+}
+
+void destroyMergeReport(MergeReport* report)
+{
+  varray_free(report->AddedWords, &free);
+  varray_free(report->UpdatedWords, &free);
+  varray_free(report->RemovedWords, &free);
+  report->AddedWords = NULL;
+  report->UpdatedWords = NULL;
+  report->RemovedWords = NULL;
+  free(report);
+  report = NULL;
+}
@@ -16,14 +16,34 @@ package main
 import "C"
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"unsafe"
 
 	"github.com/varnamproject/govarnam/govarnam"
 )
 
+// Error of functions not tied to a varnam handle
 var generalError error
+var generalErrorMutex = sync.Mutex{}
+
+func setGeneralError(err error) C.int {
+	generalErrorMutex.Lock()
+	generalError = err
+	generalErrorMutex.Unlock()
+
+	return checkError(err)
+}
+
+func getGeneralError() error {
+	generalErrorMutex.Lock()
+	defer generalErrorMutex.Unlock()
+
+	return generalError
+}
 
 var backgroundContext = context.Background()
 var cancelFuncs = map[C.int]interface{}{}
@@ -56,6 +76,25 @@ func makeContext(id C.int) (context.Context, func()) {
 	return ctx, cancel
 }
 
+// Note that C.CString uses malloc(), the
+// suggestion should be freed with destroySuggestions()
+func makeCSuggestion(sug govarnam.Suggestion) unsafe.Pointer {
+	cSug := C.makeSuggestion(C.CString(sug.Word), C.int(sug.Weight), C.int(sug.LearnedOn))
+	cSug.Source = C.int(sug.Source)
+
+	if sug.Score != nil {
+		cSug.Score = C.makeScoreBreakdown(
+			C.int(sug.Score.SymbolWeight),
+			C.int(sug.Score.LearnedWeight),
+			C.int(sug.Score.Recency),
+			C.int(sug.Score.PatternLengthBonus),
+			C.int(sug.Score.EditPenalty),
+		)
+	}
+
+	return unsafe.Pointer(cSug)
+}
+
 func makeCTransliterationResult(ctx context.Context, goResult govarnam.TransliterationResult, resultPointer **C.struct_TransliterationResult_t) C.int {
 	select {
 	case <-ctx.Done():
@@ -67,37 +106,37 @@ func makeCTransliterationResult(ctx context.Context, goResult govarnam.Translite
 
 		cExactWords := C.varray_init()
 		for _, sug := range goResult.ExactWords {
-			cSug := unsafe.Pointer(C.makeSuggestion(C.CString(sug.Word), C.int(sug.Weight), C.int(sug.LearnedOn)))
+			cSug := makeCSuggestion(sug)
 			C.varray_push(cExactWords, cSug)
 		}
 
 		cExactMatches := C.varray_init()
 		for _, sug := range goResult.ExactMatches {
-			cSug := unsafe.Pointer(C.makeSuggestion(C.CString(sug.Word), C.int(sug.Weight), C.int(sug.LearnedOn)))
+			cSug := makeCSuggestion(sug)
 			C.varray_push(cExactMatches, cSug)
 		}
 
 		cDictionarySuggestions := C.varray_init()
 		for _, sug := range goResult.DictionarySuggestions {
-			cSug := unsafe.Pointer(C.makeSuggestion(C.CString(sug.Word), C.int(sug.Weight), C.int(sug.LearnedOn)))
+			cSug := makeCSuggestion(sug)
 			C.varray_push(cDictionarySuggestions, cSug)
 		}
 
 		cPatternDictionarySuggestions := C.varray_init()
 		for _, sug := range goResult.PatternDictionarySuggestions {
-			cSug := unsafe.Pointer(C.makeSuggestion(C.CString(sug.Word), C.int(sug.Weight), C.int(sug.LearnedOn)))
+			cSug := makeCSuggestion(sug)
 			C.varray_push(cPatternDictionarySuggestions, cSug)
 		}
 
 		cTokenizerSuggestions := C.varray_init()
 		for _, sug := range goResult.TokenizerSuggestions {
-			cSug := unsafe.Pointer(C.makeSuggestion(C.CString(sug.Word), C.int(sug.Weight), C.int(sug.LearnedOn)))
+			cSug := makeCSuggestion(sug)
 			C.varray_push(cTokenizerSuggestions, cSug)
 		}
 
 		cGreedyTokenized := C.varray_init()
 		for _, sug := range goResult.GreedyTokenized {
-			cSug := unsafe.Pointer(C.makeSuggestion(C.CString(sug.Word), C.int(sug.Weight), C.int(sug.LearnedOn)))
+			cSug := makeCSuggestion(sug)
 			C.varray_push(cGreedyTokenized, cSug)
 		}
 
@@ -124,37 +163,67 @@ func varnam_set_vst_lookup_dir(path *C.char) {
 
 type varnamHandle struct {
 	varnam *govarnam.Varnam
-	err    error
+
+	errMutex sync.Mutex
+	err      error
+}
+
+// Keep error for varnam_get_last_error() and return status code
+func (handle *varnamHandle) checkError(err error) C.int {
+	handle.errMutex.Lock()
+	handle.err = err
+	handle.errMutex.Unlock()
+
+	return checkError(err)
+}
+
+func (handle *varnamHandle) getError() error {
+	handle.errMutex.Lock()
+	defer handle.errMutex.Unlock()
+
+	return handle.err
 }
 
 // For storing varnam instances
 var varnamHandles = map[C.int]*varnamHandle{}
 var varnamHandlesMapMutex = sync.RWMutex{}
 
+// IDs are not reused, a closed handle's ID is never given again
+var lastVarnamHandleID = C.int(-1)
+
+func addVarnamHandle(varnam *govarnam.Varnam, err error) C.int {
+	varnamHandlesMapMutex.Lock()
+	defer varnamHandlesMapMutex.Unlock()
+
+	lastVarnamHandleID++
+	varnamHandles[lastVarnamHandleID] = &varnamHandle{varnam: varnam, err: err}
+
+	return lastVarnamHandleID
+}
+
 //export varnam_init
 func varnam_init(vstFile *C.char, learningsFile *C.char, id unsafe.Pointer) C.int {
-	handleID := C.int(len(varnamHandles))
-	*(*C.int)(id) = handleID
-
 	varnamGo, err := govarnam.Init(C.GoString(vstFile), C.GoString(learningsFile))
 
-	varnamHandlesMapMutex.Lock()
-	varnamHandles[handleID] = &varnamHandle{varnamGo, err}
-	varnamHandlesMapMutex.Unlock()
+	*(*C.int)(id) = addVarnamHandle(varnamGo, err)
 
 	return checkError(err)
 }
 
 //export varnam_init_from_id
 func varnam_init_from_id(schemeID *C.char, id unsafe.Pointer) C.int {
-	handleID := C.int(len(varnamHandles))
-	*(*C.int)(id) = handleID
-
 	varnamGo, err := govarnam.InitFromID(C.GoString(schemeID))
 
-	varnamHandlesMapMutex.Lock()
-	varnamHandles[handleID] = &varnamHandle{varnamGo, err}
-	varnamHandlesMapMutex.Unlock()
+	*(*C.int)(id) = addVarnamHandle(varnamGo, err)
+
+	return checkError(err)
+}
+
+//export varnam_init_from_id_with_profile
+func varnam_init_from_id_with_profile(schemeID *C.char, profile *C.char, id unsafe.Pointer) C.int {
+	varnamGo, err := govarnam.InitFromIDWithProfile(C.GoString(schemeID), C.GoString(profile))
+
+	*(*C.int)(id) = addVarnamHandle(varnamGo, err)
 
 	return checkError(err)
 }
@@ -172,16 +241,25 @@ func getVarnamHandle(id C.int) *varnamHandle {
 //export varnam_close
 func varnam_close(varnamHandleID C.int) C.int {
 	handle := getVarnamHandle(varnamHandleID)
-	handle.err = handle.varnam.Close()
+	err := handle.varnam.Close()
 
-	if handle.err != nil {
-		return checkError(handle.err)
+	if err != nil {
+		return handle.checkError(err)
 	}
 
 	varnamHandlesMapMutex.Lock()
 	delete(varnamHandles, varnamHandleID)
 	varnamHandlesMapMutex.Unlock()
 
+	// Sessions can't be used without the handle
+	sessionsMapMutex.Lock()
+	for sessionID, item := range sessions {
+		if item.varnamHandleID == varnamHandleID {
+			delete(sessions, sessionID)
+		}
+	}
+	sessionsMapMutex.Unlock()
+
 	return C.VARNAM_SUCCESS
 }
 
@@ -204,7 +282,7 @@ func varnam_transliterate(varnamHandleID C.int, id C.int, word *C.char, resultPo
 
 		cResult := C.varray_init()
 		for _, sug := range result {
-			cSug := unsafe.Pointer(C.makeSuggestion(C.CString(sug.Word), C.int(sug.Weight), C.int(sug.LearnedOn)))
+			cSug := makeCSuggestion(sug)
 			C.varray_push(cResult, cSug)
 		}
 		*resultPointer = cResult
@@ -230,6 +308,43 @@ func varnam_transliterate_advanced(varnamHandleID C.int, id C.int, word *C.char,
 	}
 }
 
+//export varnam_transliterate_text
+func varnam_transliterate_text(varnamHandleID C.int, id C.int, text *C.char, resultPointer **C.varray) C.int {
+	ctx, cancel := makeContext(id)
+	defer cancel()
+
+	handle := getVarnamHandle(varnamHandleID)
+
+	segments, err := handle.varnam.TransliterateText(ctx, C.GoString(text))
+	if err != nil {
+		if ctx.Err() != nil {
+			return C.VARNAM_CANCELLED
+		}
+		handle.checkError(err)
+		return C.VARNAM_ERROR
+	}
+
+	cResult := C.varray_init()
+	for _, segment := range segments {
+		cSugs := C.varray_init()
+		for _, sug := range segment.Suggestions {
+			cSug := makeCSuggestion(sug)
+			C.varray_push(cSugs, cSug)
+		}
+
+		var cIsWord C.int
+		if segment.IsWord {
+			cIsWord = C.int(1)
+		}
+
+		cSegment := unsafe.Pointer(C.makeTextSegment(C.int(segment.Start), C.int(segment.End), C.CString(segment.Text), cIsWord, cSugs))
+		C.varray_push(cResult, cSegment)
+	}
+	*resultPointer = cResult
+
+	return C.VARNAM_SUCCESS
+}
+
 //export varnam_transliterate_greedy_tokenized
 func varnam_transliterate_greedy_tokenized(varnamHandleID C.int, word *C.char, resultPointer **C.varray) C.int {
 	handle := getVarnamHandle(varnamHandleID)
@@ -238,7 +353,7 @@ func varnam_transliterate_greedy_tokenized(varnamHandleID C.int, word *C.char, r
 
 	ptr := C.varray_init()
 	for _, sug := range result {
-		cSug := unsafe.Pointer(C.makeSuggestion(C.CString(sug.Word), C.int(sug.Weight), C.int(sug.LearnedOn)))
+		cSug := makeCSuggestion(sug)
 		C.varray_push(ptr, cSug)
 	}
 	*resultPointer = ptr
@@ -252,13 +367,13 @@ func varnam_reverse_transliterate(varnamHandleID C.int, word *C.char, resultPoin
 	sugs, err := handle.varnam.ReverseTransliterate(C.GoString(word))
 
 	if err != nil {
-		handle.err = err
+		handle.checkError(err)
 		return C.VARNAM_ERROR
 	}
 
 	cResult := C.varray_init()
 	for _, sug := range sugs {
-		cSug := unsafe.Pointer(C.makeSuggestion(C.CString(sug.Word), C.int(sug.Weight), C.int(sug.LearnedOn)))
+		cSug := makeCSuggestion(sug)
 		C.varray_push(cResult, cSug)
 	}
 	*resultPointer = cResult
@@ -308,22 +423,22 @@ func varnam_set_dictionary_match_exact(varnamHandleID C.int, val C.int) {
 //export varnam_learn
 func varnam_learn(varnamHandleID C.int, word *C.char, weight C.int) C.int {
 	handle := getVarnamHandle(varnamHandleID)
-	handle.err = handle.varnam.Learn(C.GoString(word), int(weight))
-	return checkError(handle.err)
+	err := handle.varnam.Learn(C.GoString(word), int(weight))
+	return handle.checkError(err)
 }
 
 //export varnam_train
 func varnam_train(varnamHandleID C.int, pattern *C.char, word *C.char) C.int {
 	handle := getVarnamHandle(varnamHandleID)
-	handle.err = handle.varnam.Train(C.GoString(pattern), C.GoString(word))
-	return checkError(handle.err)
+	err := handle.varnam.Train(C.GoString(pattern), C.GoString(word))
+	return handle.checkError(err)
 }
 
 //export varnam_unlearn
 func varnam_unlearn(varnamHandleID C.int, word *C.char) C.int {
 	handle := getVarnamHandle(varnamHandleID)
-	handle.err = handle.varnam.Unlearn(C.GoString(word))
-	return checkError(handle.err)
+	err := handle.varnam.Unlearn(C.GoString(word))
+	return handle.checkError(err)
 }
 
 //export varnam_learn_from_file
@@ -332,7 +447,7 @@ func varnam_learn_from_file(varnamHandleID C.int, filePath *C.char, resultPointe
 	learnStatus, err := handle.varnam.LearnFromFile(C.GoString(filePath))
 
 	if err != nil {
-		handle.err = err
+		handle.checkError(err)
 		return C.VARNAM_ERROR
 	}
 
@@ -349,7 +464,7 @@ func varnam_train_from_file(varnamHandleID C.int, filePath *C.char, resultPointe
 	learnStatus, err := handle.varnam.TrainFromFile(C.GoString(filePath))
 
 	if err != nil {
-		handle.err = err
+		handle.checkError(err)
 		return C.VARNAM_ERROR
 	}
 
@@ -364,9 +479,9 @@ func varnam_get_last_error(varnamHandleID C.int) *C.char {
 	var err error
 
 	if varnamHandleID == -1 {
-		err = generalError
+		err = getGeneralError()
 	} else {
-		err = getVarnamHandle(varnamHandleID).err
+		err = getVarnamHandle(varnamHandleID).getError()
 	}
 
 	if err != nil {
@@ -393,17 +508,36 @@ func varnam_cancel(id C.int) C.int {
 //export varnam_export
 func varnam_export(varnamHandleID C.int, filePath *C.char, wordsPerFile C.int) C.int {
 	handle := getVarnamHandle(varnamHandleID)
-	handle.err = handle.varnam.Export(C.GoString(filePath), int(wordsPerFile))
+	err := handle.varnam.Export(C.GoString(filePath), int(wordsPerFile))
 
-	return checkError(handle.err)
+	return handle.checkError(err)
 }
 
 //export varnam_import
 func varnam_import(varnamHandleID C.int, filePath *C.char) C.int {
 	handle := getVarnamHandle(varnamHandleID)
-	handle.err = handle.varnam.Import(C.GoString(filePath))
+	err := handle.varnam.Import(C.GoString(filePath))
+
+	return handle.checkError(err)
+}
+
+//export varnam_merge_learnings
+func varnam_merge_learnings(varnamHandleID C.int, filePath *C.char, strategy C.int, dryRun C.int, resultPointer **C.MergeReport) C.int {
+	handle := getVarnamHandle(varnamHandleID)
+	report, err := handle.varnam.MergeLearnings(C.GoString(filePath), int(strategy), cintToBool(dryRun))
+	if err != nil {
+		return handle.checkError(err)
+	}
+
+	*resultPointer = C.makeMergeReport(
+		makeCStringsArray(report.AddedWords),
+		makeCStringsArray(report.UpdatedWords),
+		makeCStringsArray(report.RemovedWords),
+		C.int(report.AddedPatterns),
+		C.int(report.RemovedPatterns),
+	)
 
-	return checkError(handle.err)
+	return C.VARNAM_SUCCESS
 }
 
 //export varnam_get_vst_path
@@ -449,7 +583,9 @@ func varnam_search_symbol_table(varnamHandleID C.int, id C.int, searchCriteria C
 	case <-ctx.Done():
 		return C.VARNAM_CANCELLED
 	default:
-		results, handle.err = handle.varnam.SearchSymbolTable(ctx, goSearchCriteria)
+		var err error
+		results, err = handle.varnam.SearchSymbolTable(ctx, goSearchCriteria)
+		handle.checkError(err)
 
 		cResult := C.varray_init()
 		for _, symbol := range results {
@@ -472,13 +608,13 @@ func varnam_get_recently_learned_words(varnamHandleID C.int, id C.int, offset C.
 	result, err := handle.varnam.GetRecentlyLearntWords(ctx, int(offset), int(limit))
 
 	if err != nil {
-		handle.err = err
+		handle.checkError(err)
 		return C.VARNAM_ERROR
 	}
 
 	ptr := C.varray_init()
 	for _, sug := range result {
-		cSug := unsafe.Pointer(C.makeSuggestion(C.CString(sug.Word), C.int(sug.Weight), C.int(sug.LearnedOn)))
+		cSug := makeCSuggestion(sug)
 		C.varray_push(ptr, cSug)
 	}
 	*resultPointer = ptr
@@ -497,7 +633,7 @@ func varnam_get_suggestions(varnamHandleID C.int, id C.int, word *C.char, result
 
 	ptr := C.varray_init()
 	for _, sug := range result {
-		cSug := unsafe.Pointer(C.makeSuggestion(C.CString(sug.Word), C.int(sug.Weight), C.int(sug.LearnedOn)))
+		cSug := makeCSuggestion(sug)
 		C.varray_push(ptr, cSug)
 	}
 	*resultPointer = ptr
@@ -505,6 +641,111 @@ func varnam_get_suggestions(varnamHandleID C.int, id C.int, word *C.char, result
 	return C.VARNAM_SUCCESS
 }
 
+//export varnam_predict_next_words
+func varnam_predict_next_words(varnamHandleID C.int, id C.int, previousWords *C.char, limit C.int, resultPointer **C.varray) C.int {
+	ctx, cancel := makeContext(id)
+	defer cancel()
+
+	handle := getVarnamHandle(varnamHandleID)
+
+	// previousWords are separated by whitespace
+	result := handle.varnam.PredictNextWords(ctx, strings.Fields(C.GoString(previousWords)), int(limit))
+
+	ptr := C.varray_init()
+	for _, sug := range result {
+		cSug := makeCSuggestion(sug)
+		C.varray_push(ptr, cSug)
+	}
+	*resultPointer = ptr
+
+	return C.VARNAM_SUCCESS
+}
+
+type typingSession struct {
+	varnamHandleID C.int
+	session        *govarnam.Session
+}
+
+// For storing typing sessions
+var sessions = map[C.int]typingSession{}
+var sessionsMapMutex = sync.RWMutex{}
+
+// Like varnam handle IDs, session IDs are not reused
+var lastSessionID = C.int(-1)
+
+func getSession(id C.int) *govarnam.Session {
+	sessionsMapMutex.RLock()
+	defer sessionsMapMutex.RUnlock()
+	if item, ok := sessions[id]; ok {
+		return item.session
+	}
+	log.Fatal("Varnam session not found")
+	return nil
+}
+
+//export varnam_session_new
+func varnam_session_new(varnamHandleID C.int, id unsafe.Pointer) C.int {
+	session := getVarnamHandle(varnamHandleID).varnam.NewSession()
+
+	sessionsMapMutex.Lock()
+	defer sessionsMapMutex.Unlock()
+
+	lastSessionID++
+	sessions[lastSessionID] = typingSession{varnamHandleID, session}
+	*(*C.int)(id) = lastSessionID
+
+	return C.VARNAM_SUCCESS
+}
+
+//export varnam_session_append
+func varnam_session_append(sessionID C.int, text *C.char) C.int {
+	getSession(sessionID).Append(C.GoString(text))
+	return C.VARNAM_SUCCESS
+}
+
+//export varnam_session_backspace
+func varnam_session_backspace(sessionID C.int) C.int {
+	getSession(sessionID).Backspace()
+	return C.VARNAM_SUCCESS
+}
+
+//export varnam_session_reset
+func varnam_session_reset(sessionID C.int) C.int {
+	getSession(sessionID).Reset()
+	return C.VARNAM_SUCCESS
+}
+
+//export varnam_session_get_input
+func varnam_session_get_input(sessionID C.int) *C.char {
+	return C.CString(getSession(sessionID).Input())
+}
+
+//export varnam_session_suggestions
+func varnam_session_suggestions(sessionID C.int, id C.int, resultPointer **C.struct_TransliterationResult_t) C.int {
+	ctx, cancel := makeContext(id)
+	defer cancel()
+
+	result, err := getSession(sessionID).Suggestions(ctx)
+	if err != nil {
+		return C.VARNAM_CANCELLED
+	}
+
+	return makeCTransliterationResult(ctx, result, resultPointer)
+}
+
+//export varnam_session_close
+func varnam_session_close(sessionID C.int) C.int {
+	sessionsMapMutex.Lock()
+	defer sessionsMapMutex.Unlock()
+
+	if _, ok := sessions[sessionID]; !ok {
+		return C.VARNAM_MISUSE
+	}
+	delete(sessions, sessionID)
+
+	return C.VARNAM_SUCCESS
+}
+
 func makeGoSchemeDetails(sd *C.struct_SchemeDetails_t) govarnam.SchemeDetails {
 	return govarnam.SchemeDetails{
 		Identifier:   C.GoString(sd.Identifier),
@@ -543,17 +784,15 @@ func varnam_get_scheme_details(varnamHandleID C.int) *C.struct_SchemeDetails_t {
 
 //export varnam_get_vst_dir
 func varnam_get_vst_dir() *C.char {
-	var dir string
-	dir, generalError = govarnam.FindVSTDir()
+	dir, err := govarnam.FindVSTDir()
+	setGeneralError(err)
 	return C.CString(dir)
 }
 
 //export varnam_get_all_scheme_details
 func varnam_get_all_scheme_details() *C.varray {
-	var schemeDetails []govarnam.SchemeDetails
-	schemeDetails, generalError = govarnam.GetAllSchemeDetails()
-
-	if generalError != nil {
+	schemeDetails, err := govarnam.GetAllSchemeDetails()
+	if setGeneralError(err) != C.VARNAM_SUCCESS {
 		return nil
 	}
 
@@ -568,14 +807,9 @@ func varnam_get_all_scheme_details() *C.varray {
 
 //export vm_init
 func vm_init(vstPath *C.char, id unsafe.Pointer) C.int {
-	handleID := C.int(len(varnamHandles))
-	*(*C.int)(id) = handleID
-
 	varnamGo, err := govarnam.VMInit(C.GoString(vstPath))
 
-	varnamHandlesMapMutex.Lock()
-	varnamHandles[handleID] = &varnamHandle{varnamGo, err}
-	varnamHandlesMapMutex.Unlock()
+	*(*C.int)(id) = addVarnamHandle(varnamGo, err)
 
 	return checkError(err)
 }
@@ -600,7 +834,7 @@ func vm_create_token(varnamHandleID C.int, pattern *C.char, value1 *C.char, valu
 	// 	tag = C.CString("")
 	// }
 
-	handle.err = handle.varnam.VMCreateToken(
+	err := handle.varnam.VMCreateToken(
 		C.GoString(pattern),
 		C.GoString(value1),
 		C.GoString(value2),
@@ -613,7 +847,7 @@ func vm_create_token(varnamHandleID C.int, pattern *C.char, value1 *C.char, valu
 		cintToBool(buffered),
 	)
 
-	return checkError(handle.err)
+	return handle.checkError(err)
 }
 
 //export vm_delete_token
@@ -622,16 +856,48 @@ func vm_delete_token(varnamHandleID C.int, searchCriteria C.struct_Symbol_t) C.i
 
 	goSearchCriteria := cSymbolToGoSymbol(searchCriteria)
 
-	handle.err = handle.varnam.VMDeleteToken(goSearchCriteria)
-	return checkError(handle.err)
+	err := handle.varnam.VMDeleteToken(goSearchCriteria)
+	return handle.checkError(err)
+}
+
+//export vm_create_stemrule
+func vm_create_stemrule(varnamHandleID C.int, oldEnding *C.char, newEnding *C.char) C.int {
+	handle := getVarnamHandle(varnamHandleID)
+
+	err := handle.varnam.VMCreateStemRule(C.GoString(oldEnding), C.GoString(newEnding))
+	return handle.checkError(err)
+}
+
+//export vm_delete_stemrule
+func vm_delete_stemrule(varnamHandleID C.int, oldEnding *C.char) C.int {
+	handle := getVarnamHandle(varnamHandleID)
+
+	err := handle.varnam.VMDeleteStemRule(C.GoString(oldEnding))
+	return handle.checkError(err)
+}
+
+//export vm_create_stem_exception
+func vm_create_stem_exception(varnamHandleID C.int, oldEnding *C.char, exception *C.char) C.int {
+	handle := getVarnamHandle(varnamHandleID)
+
+	err := handle.varnam.VMCreateStemException(C.GoString(oldEnding), C.GoString(exception))
+	return handle.checkError(err)
+}
+
+//export vm_delete_stem_exception
+func vm_delete_stem_exception(varnamHandleID C.int, oldEnding *C.char, exception *C.char) C.int {
+	handle := getVarnamHandle(varnamHandleID)
+
+	err := handle.varnam.VMDeleteStemException(C.GoString(oldEnding), C.GoString(exception))
+	return handle.checkError(err)
 }
 
 //export vm_flush_buffer
 func vm_flush_buffer(varnamHandleID C.int) C.int {
 	handle := getVarnamHandle(varnamHandleID)
 
-	handle.err = handle.varnam.VMFlushBuffer()
-	return checkError(handle.err)
+	err := handle.varnam.VMFlushBuffer()
+	return handle.checkError(err)
 }
 
 //export varnam_config
@@ -660,17 +926,146 @@ func varnam_config(varnamHandleID C.int, key C.int, value C.int) C.int {
 	case C.VARNAM_CONFIG_SET_DICTIONARY_MATCH_EXACT:
 		handle.varnam.DictionaryMatchExact = cintToBool(value)
 		break
+	case C.VARNAM_CONFIG_SET_CACHE_SIZE:
+		handle.varnam.EnableCache(int(value))
+		break
+	case C.VARNAM_CONFIG_EXPLAIN_SUGGESTIONS:
+		handle.varnam.ExplainSuggestions = cintToBool(value)
+		break
+	case C.VARNAM_CONFIG_SET_RANKER:
+		switch value {
+		case C.VARNAM_RANKER_DEFAULT:
+			handle.varnam.Ranker = nil
+		case C.VARNAM_RANKER_WEIGHTED:
+			// With default values
+			handle.varnam.Ranker = govarnam.NewWeightedRanker()
+		default:
+			return handle.checkError(fmt.Errorf("unknown ranker %d", value))
+		}
+		break
+	case C.VARNAM_CONFIG_RANKER_EXACT_WORD_WEIGHT,
+		C.VARNAM_CONFIG_RANKER_EXACT_MATCH_WEIGHT,
+		C.VARNAM_CONFIG_RANKER_DICTIONARY_WEIGHT,
+		C.VARNAM_CONFIG_RANKER_PATTERN_DICTIONARY_WEIGHT,
+		C.VARNAM_CONFIG_RANKER_TOKENIZER_WEIGHT,
+		C.VARNAM_CONFIG_RANKER_GREEDY_WEIGHT,
+		C.VARNAM_CONFIG_RANKER_RECENCY_BONUS,
+		C.VARNAM_CONFIG_RANKER_RECENCY_HALF_LIFE,
+		C.VARNAM_CONFIG_RANKER_SHORT_WORD_LENGTH:
+		ranker, ok := handle.varnam.Ranker.(*govarnam.WeightedRanker)
+		if !ok {
+			return handle.checkError(fmt.Errorf("weighted ranker is not set, set it with VARNAM_CONFIG_SET_RANKER"))
+		}
+		handle.varnam.Ranker = setWeightedRankerConfig(ranker, key, int(value))
+		break
+	case C.VARNAM_CONFIG_SET_LEARNING_HALF_LIFE:
+		handle.varnam.LearningHalfLife = int(value)
+		break
+	case C.VARNAM_CONFIG_SET_FUZZY_MATCH_MAX_EDITS:
+		handle.varnam.FuzzyMatchMaxEdits = int(value)
+		break
+	case C.VARNAM_CONFIG_SET_FUZZY_MATCH_COST_LIMIT:
+		handle.varnam.FuzzyMatchCostLimit = int(value)
+		break
+	case C.VARNAM_CONFIG_PHONETIC_MATCH:
+		handle.varnam.PhoneticMatch = cintToBool(value)
+		break
+	case C.VARNAM_CONFIG_SET_LAYER_WEIGHT_POLICY:
+		handle.varnam.LayerWeightPolicy = int(value)
+		break
 	}
 
 	return C.VARNAM_SUCCESS
 }
 
+// Changes a copy of the ranker, the one in use may be
+// read by a transliteration at the same time
+func setWeightedRankerConfig(current *govarnam.WeightedRanker, key C.int, value int) *govarnam.WeightedRanker {
+	ranker := *current
+	ranker.SourceWeights = map[int]int{}
+	for source, weight := range current.SourceWeights {
+		ranker.SourceWeights[source] = weight
+	}
+
+	switch key {
+	case C.VARNAM_CONFIG_RANKER_EXACT_WORD_WEIGHT:
+		ranker.SourceWeights[govarnam.VARNAM_SOURCE_EXACT_WORD] = value
+	case C.VARNAM_CONFIG_RANKER_EXACT_MATCH_WEIGHT:
+		ranker.SourceWeights[govarnam.VARNAM_SOURCE_EXACT_MATCH] = value
+	case C.VARNAM_CONFIG_RANKER_DICTIONARY_WEIGHT:
+		ranker.SourceWeights[govarnam.VARNAM_SOURCE_DICTIONARY] = value
+	case C.VARNAM_CONFIG_RANKER_PATTERN_DICTIONARY_WEIGHT:
+		ranker.SourceWeights[govarnam.VARNAM_SOURCE_PATTERN_DICTIONARY] = value
+	case C.VARNAM_CONFIG_RANKER_TOKENIZER_WEIGHT:
+		ranker.SourceWeights[govarnam.VARNAM_SOURCE_TOKENIZER] = value
+	case C.VARNAM_CONFIG_RANKER_GREEDY_WEIGHT:
+		ranker.SourceWeights[govarnam.VARNAM_SOURCE_GREEDY] = value
+	case C.VARNAM_CONFIG_RANKER_RECENCY_BONUS:
+		ranker.RecencyBonus = value
+	case C.VARNAM_CONFIG_RANKER_RECENCY_HALF_LIFE:
+		ranker.RecencyHalfLife = value
+	case C.VARNAM_CONFIG_RANKER_SHORT_WORD_LENGTH:
+		ranker.ShortWordLength = value
+	}
+
+	return &ranker
+}
+
 //export vm_set_scheme_details
 func vm_set_scheme_details(varnamHandleID C.int, sd *C.struct_SchemeDetails_t) C.int {
 	handle := getVarnamHandle(varnamHandleID)
 
-	handle.err = handle.varnam.VMSetSchemeDetails(makeGoSchemeDetails(sd))
-	return checkError(handle.err)
+	err := handle.varnam.VMSetSchemeDetails(makeGoSchemeDetails(sd))
+	return handle.checkError(err)
+}
+
+//export vm_compile_scheme
+func vm_compile_scheme(srcPath *C.char, vstPath *C.char) C.int {
+	return setGeneralError(govarnam.CompileScheme(C.GoString(srcPath), C.GoString(vstPath)))
+}
+
+//export vm_decompile_vst
+func vm_decompile_vst(vstPath *C.char, srcPath *C.char) C.int {
+	return setGeneralError(govarnam.DecompileVSTToFile(C.GoString(vstPath), C.GoString(srcPath)))
+}
+
+//export vm_validate_vst
+func vm_validate_vst(vstPath *C.char, resultPointer **C.VSTReport) C.int {
+	report, err := govarnam.ValidateVST(C.GoString(vstPath))
+	if err != nil {
+		return setGeneralError(err)
+	}
+
+	cIssues := C.varray_init()
+	for _, issue := range report.Issues {
+		cIssue := unsafe.Pointer(C.makeVSTIssue(C.int(issue.Severity), C.CString(issue.Check), C.int(issue.SymbolID), C.CString(issue.Message)))
+		C.varray_push(cIssues, cIssue)
+	}
+	*resultPointer = C.makeVSTReport(C.int(report.SchemaVersion), C.int(report.SymbolsCount), cIssues)
+
+	return C.VARNAM_SUCCESS
+}
+
+//export vm_diff_vst
+func vm_diff_vst(vstPathA *C.char, vstPathB *C.char, format C.int, resultPointer **C.char) C.int {
+	diff, err := govarnam.DiffVST(C.GoString(vstPathA), C.GoString(vstPathB))
+	if err != nil {
+		return setGeneralError(err)
+	}
+
+	var output string
+	if format == C.VARNAM_DIFF_FORMAT_JSON {
+		jsonData, err := json.Marshal(diff)
+		if err != nil {
+			return setGeneralError(err)
+		}
+		output = string(jsonData)
+	} else {
+		output = diff.Text()
+	}
+	*resultPointer = C.CString(output)
+
+	return C.VARNAM_SUCCESS
 }
 
 func main() {}
@@ -17,11 +17,87 @@
 #define VARNAM_CONFIG_SET_PATTERN_DICTIONARY_SUGGESTIONS_LIMIT 105
 #define VARNAM_CONFIG_SET_TOKENIZER_SUGGESTIONS_LIMIT 106
 #define VARNAM_CONFIG_SET_DICTIONARY_MATCH_EXACT 107
+// Maximum transliteration results to cache, 0 disables it
+#define VARNAM_CONFIG_SET_CACHE_SIZE 108
+#define VARNAM_CONFIG_EXPLAIN_SUGGESTIONS 109
+
+// Value is one of VARNAM_RANKER_*. Setting VARNAM_RANKER_WEIGHTED
+// resets its values to defaults
+#define VARNAM_CONFIG_SET_RANKER 110
+// These need VARNAM_RANKER_WEIGHTED to be set first.
+// Source weights are percentages. Set the ranker & these
+// before using the handle from multiple threads.
+#define VARNAM_CONFIG_RANKER_EXACT_WORD_WEIGHT 111
+#define VARNAM_CONFIG_RANKER_EXACT_MATCH_WEIGHT 112
+#define VARNAM_CONFIG_RANKER_DICTIONARY_WEIGHT 113
+#define VARNAM_CONFIG_RANKER_PATTERN_DICTIONARY_WEIGHT 114
+#define VARNAM_CONFIG_RANKER_TOKENIZER_WEIGHT 115
+#define VARNAM_CONFIG_RANKER_GREEDY_WEIGHT 116
+#define VARNAM_CONFIG_RANKER_RECENCY_BONUS 117
+#define VARNAM_CONFIG_RANKER_RECENCY_HALF_LIFE 118
+#define VARNAM_CONFIG_RANKER_SHORT_WORD_LENGTH 119
+
+// Learnt weights halve every this many seconds, 0 disables it
+#define VARNAM_CONFIG_SET_LEARNING_HALF_LIFE 120
+// Typos tolerated in input when no dictionary word is found, at most 2.
+// 0 disables it
+#define VARNAM_CONFIG_SET_FUZZY_MATCH_MAX_EDITS 121
+// Maximum inputs with typos fixed searched for a transliteration.
+// Default is VARNAM_FUZZY_MATCH_COST_LIMIT
+#define VARNAM_CONFIG_SET_FUZZY_MATCH_COST_LIMIT 122
+// Suggest learnt words that sound like the input
+#define VARNAM_CONFIG_PHONETIC_MATCH 123
+// How weights of a word in more than one dictionary layer
+// are combined, one of VARNAM_LAYER_WEIGHT_*
+#define VARNAM_CONFIG_SET_LAYER_WEIGHT_POLICY 124
+
+#define VARNAM_FUZZY_MATCH_COST_LIMIT 200
+
+// Profile used by varnam_init_from_id()
+#define VARNAM_DEFAULT_PROFILE "default"
+
+// Weight in the upper layer, the default
+#define VARNAM_LAYER_WEIGHT_OVERRIDE 0
+#define VARNAM_LAYER_WEIGHT_MAX 1
+#define VARNAM_LAYER_WEIGHT_SUM 2
+
+// Weights of a word in both are combined with
+// maximum or sum in varnam_merge_learnings()
+#define VARNAM_MERGE_WEIGHT_MAX 0
+#define VARNAM_MERGE_WEIGHT_SUM 1
+
+#define VARNAM_RANKER_DEFAULT 0
+#define VARNAM_RANKER_WEIGHTED 1
+
+#define VARNAM_VST_ISSUE_ERROR 1
+#define VARNAM_VST_ISSUE_WARNING 2
+
+#define VARNAM_DIFF_FORMAT_TEXT 0
+#define VARNAM_DIFF_FORMAT_JSON 1
+
+#define VARNAM_SOURCE_EXACT_WORD 1
+#define VARNAM_SOURCE_EXACT_MATCH 2
+#define VARNAM_SOURCE_DICTIONARY 3
+#define VARNAM_SOURCE_PATTERN_DICTIONARY 4
+#define VARNAM_SOURCE_TOKENIZER 5
+#define VARNAM_SOURCE_GREEDY 6
+
+typedef struct ScoreBreakdown_t {
+  int SymbolWeight;
+  int LearnedWeight;
+  int Recency;
+  int PatternLengthBonus;
+  int EditPenalty;
+} ScoreBreakdown;
 
 typedef struct Suggestion_t {
   char* Word;
   int Weight;
   int LearnedOn;
+  // One of VARNAM_SOURCE_*, 0 if not from a transliteration
+  int Source;
+  // NULL unless VARNAM_CONFIG_EXPLAIN_SUGGESTIONS is set
+  ScoreBreakdown* Score;
 } Suggestion;
 
 typedef struct TransliterationResult_t {
@@ -34,12 +110,25 @@ typedef struct TransliterationResult_t {
 } TransliterationResult;
 
 Suggestion* makeSuggestion(char* word, int weight, int learned_on);
+ScoreBreakdown* makeScoreBreakdown(int symbol_weight, int learned_weight, int recency, int pattern_length_bonus, int edit_penalty);
 
 TransliterationResult* makeResult(varray* exact_words, varray* exact_matches, varray* dictionary_suggestions, varray* pattern_dictionary_suggestions, varray* tokenizer_suggestions, varray* greedy_tokenized);
 
 void destroySuggestionsArray(varray* pointer);
 void destroyTransliterationResult(TransliterationResult*);
 
+typedef struct TextSegment_t {
+  int Start;
+  int End;
+  char* Text;
+  int IsWord;
+  varray* Suggestions;
+} TextSegment;
+
+TextSegment* makeTextSegment(int start, int end, char* text, int is_word, varray* suggestions);
+
+void destroyTextSegmentsArray(varray* pointer);
+
 typedef struct SchemeDetails_t {
   char* Identifier;
   char* LangCode;
@@ -79,4 +168,35 @@ Symbol* makeSymbol(int Identifier, int Type, int MatchType, char* Pattern, char*
 
 void destroySymbolArray(void* cSymbols);
 
+typedef struct VSTIssue_t {
+  int Severity;
+  char* Check;
+  int SymbolID;
+  char* Message;
+} VSTIssue;
+
+VSTIssue* makeVSTIssue(int Severity, char* Check, int SymbolID, char* Message);
+
+typedef struct VSTReport_t {
+  int SchemaVersion;
+  int SymbolsCount;
+  varray* Issues;
+} VSTReport;
+
+VSTReport* makeVSTReport(int SchemaVersion, int SymbolsCount, varray* Issues);
+
+void destroyVSTReport(VSTReport* report);
+
+typedef struct MergeReport_t {
+  varray* AddedWords;
+  varray* UpdatedWords;
+  varray* RemovedWords;
+  int AddedPatterns;
+  int RemovedPatterns;
+} MergeReport;
+
+MergeReport* makeMergeReport(varray* AddedWords, varray* UpdatedWords, varray* RemovedWords, int AddedPatterns, int RemovedPatterns);
+
+void destroyMergeReport(MergeReport* report);
+
 #endif /* __C_SHARED_H__ */
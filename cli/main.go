@@ -11,15 +11,51 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/varnamproject/govarnam/govarnamgo"
+	"github.com/varnamproject/govarnam/govarnamgo/native"
 )
 
-var varnam *govarnamgo.VarnamHandle
+var varnam *native.VarnamHandle
+
+// Show source & score of suggestions. Set by -explain
+var explain bool
+
+var sourceNames = map[int]string{
+	native.VARNAM_SOURCE_EXACT_WORD:         "exact word",
+	native.VARNAM_SOURCE_EXACT_MATCH:        "exact match",
+	native.VARNAM_SOURCE_DICTIONARY:         "dictionary",
+	native.VARNAM_SOURCE_PATTERN_DICTIONARY: "pattern dictionary",
+	native.VARNAM_SOURCE_TOKENIZER:          "tokenizer",
+	native.VARNAM_SOURCE_GREEDY:             "greedy",
+}
+
+func printExplainedSugs(sugs []native.Suggestion) {
+	for _, sug := range sugs {
+		source, found := sourceNames[sug.Source]
+		if !found {
+			source = "unknown"
+		}
+		fmt.Printf("%s %d (%s)\n", sug.Word, sug.Weight, source)
+
+		if sug.Score != nil {
+			fmt.Printf("    symbols %d + learned %d + recency %d + pattern length %d + typos %d\n", sug.Score.SymbolWeight, sug.Score.LearnedWeight, sug.Score.Recency, sug.Score.PatternLengthBonus, sug.Score.EditPenalty)
+		}
+		if sug.LearnedOn != 0 {
+			fmt.Printf("    learned on %s\n", time.Unix(int64(sug.LearnedOn), 0).String())
+		}
+	}
+}
+
+func printSugs(sugs []native.Suggestion) {
+	if explain {
+		printExplainedSugs(sugs)
+		return
+	}
 
-func printSugs(sugs []govarnamgo.Suggestion) {
 	for _, sug := range sugs {
 		if sug.LearnedOn == 0 {
 			fmt.Println(sug.Word + " " + fmt.Sprint(sug.Weight))
@@ -29,6 +65,33 @@ func printSugs(sugs []govarnamgo.Suggestion) {
 	}
 }
 
+var layerWeightPolicies = map[string]int{
+	"override": native.VARNAM_LAYER_WEIGHT_OVERRIDE,
+	"max":      native.VARNAM_LAYER_WEIGHT_MAX,
+	"sum":      native.VARNAM_LAYER_WEIGHT_SUM,
+}
+
+var mergeWeightStrategies = map[string]int{
+	"max": native.VARNAM_MERGE_WEIGHT_MAX,
+	"sum": native.VARNAM_MERGE_WEIGHT_SUM,
+}
+
+func printMergeReport(report native.MergeReport, dryRun bool) {
+	if dryRun {
+		fmt.Println("Dry run, nothing was changed")
+	}
+	for _, word := range report.AddedWords {
+		fmt.Println("+ " + word)
+	}
+	for _, word := range report.UpdatedWords {
+		fmt.Println("~ " + word)
+	}
+	for _, word := range report.RemovedWords {
+		fmt.Println("- " + word)
+	}
+	fmt.Printf("Words added: %d, updated: %d, removed: %d. Patterns added: %d, removed: %d\n", len(report.AddedWords), len(report.UpdatedWords), len(report.RemovedWords), report.AddedPatterns, report.RemovedPatterns)
+}
+
 func main() {
 	versionFlag := flag.Bool("version", false, "Show version information")
 
@@ -36,6 +99,18 @@ func main() {
 	schemeFlag := flag.String("s", "", "Scheme ID")
 
 	reIndexFlag := flag.Bool("reindex", false, "Reindex user dictionary database")
+	compactFlag := flag.Bool("compact-learnings", false, "Store decayed weights of learnt words. Needs -learning-half-life")
+	halfLifeFlag := flag.Int("learning-half-life", 0, "Days after which weight of a learnt word halves. 0 disables it")
+	fuzzyFlag := flag.Int("fuzzy", 0, "Number of typos (at most 2) tolerated in input when finding words in dictionary")
+	phoneticFlag := flag.Bool("phonetic", false, "Suggest learnt words that sound like the input")
+
+	profileFlag := flag.String("profile", "default", "Learnings profile to use. Made if it doesn't exist")
+	baseProfileFlag := flag.String("base-profile", "", "Learnings profile searched too, read only")
+	attachFlag := flag.String("attach", "", "Dictionaries searched too, read only. Separate paths with commas")
+	layerWeightFlag := flag.String("layer-weight", "override", "How weights of a word in more than one dictionary are combined: override, max or sum")
+	listProfilesFlag := flag.Bool("list-profiles", false, "List learnings profiles of the language")
+	createProfileFlag := flag.Bool("create-profile", false, "Make a learnings profile. Argument: Profile name")
+	deleteProfileFlag := flag.Bool("delete-profile", false, "Delete a learnings profile and its words. Argument: Profile name")
 
 	learnFlag := flag.Bool("learn", false, "Learn a word")
 	unlearnFlag := flag.Bool("unlearn", false, "Unlearn a word")
@@ -47,17 +122,95 @@ func main() {
 	exportFlag := flag.Bool("export", false, "Export learnings to file")
 	exportWordsPerFile := flag.Int("export-words-per-file", 30000, "Words per export file")
 	importFlag := flag.Bool("import", false, "Import learnings from file")
+	mergeFlag := flag.Bool("merge", false, "Merge learnings of another dictionary or an export file. Argument: File path")
+	mergeWeightFlag := flag.String("merge-weight", "max", "How weights of a word in both are combined when merging: max or sum")
+	dryRunFlag := flag.Bool("dry-run", false, "Show what would change without changing anything. Used with -merge")
 
 	indicDigitsFlag := flag.Bool("digits", false, "Use indic digits")
 
 	advanced := flag.Bool("advanced", false, "Show transliteration result in advanced mode")
+	explainFlag := flag.Bool("explain", false, "Show where each suggestion came from and how its weight was made")
+	textFlag := flag.Bool("text", false, "Transliterate a text with multiple words, punctuations etc.")
+	predictFlag := flag.Bool("predict", false, "Predict next words. Arguments: Previous words")
+	predictLimitFlag := flag.Int("predict-limit", 10, "Number of words to predict")
 	reverseTransliterate := flag.Bool("reverse", false, "Reverse transliterate. Find which pattern to use for a specific word")
 
+	compileFlag := flag.Bool("compile", false, "Compile a scheme source file to VST. 2 Arguments: Source file & VST path")
+	decompileFlag := flag.Bool("decompile", false, "Decompile a VST to scheme source file. 2 Arguments: VST path & Source file")
+	validateFlag := flag.Bool("validate", false, "Check a VST for problems. Argument: VST path")
+	diffFlag := flag.Bool("diff", false, "Show changes in a VST compared to another. 2 Arguments: Old VST path & New VST path")
+	jsonFlag := flag.Bool("json", false, "Output in JSON. Used with -diff")
+
+	stdioFlag := flag.Bool("stdio", false, "Serve line-delimited JSON-RPC over stdin & stdout. For editor integrations")
+
 	flag.Parse()
 
 	if *versionFlag {
-		fmt.Println(govarnamgo.GetVersion())
-		fmt.Println(govarnamgo.GetBuild())
+		fmt.Println(native.GetVersion())
+		fmt.Println(native.GetBuild())
+		return
+	}
+
+	if *compileFlag {
+		args := flag.Args()
+
+		err := native.CompileScheme(args[0], args[1])
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Printf("Compiled %s to %s\n", args[0], args[1])
+		return
+	}
+
+	if *decompileFlag {
+		args := flag.Args()
+
+		err := native.DecompileVST(args[0], args[1])
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Printf("Decompiled %s to %s\n", args[0], args[1])
+		return
+	}
+
+	if *validateFlag {
+		args := flag.Args()
+
+		report, err := native.ValidateVST(args[0])
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		errorsCount := 0
+		for _, issue := range report.Issues {
+			severity := "warning"
+			if issue.Severity == native.VARNAM_VST_ISSUE_ERROR {
+				severity = "error"
+				errorsCount++
+			}
+
+			if issue.SymbolID != 0 {
+				fmt.Printf("%s: [%s] symbol %d: %s\n", severity, issue.Check, issue.SymbolID, issue.Message)
+			} else {
+				fmt.Printf("%s: [%s] %s\n", severity, issue.Check, issue.Message)
+			}
+		}
+
+		fmt.Printf("%d symbols, schema version %d. %d errors, %d warnings\n", report.SymbolsCount, report.SchemaVersion, errorsCount, len(report.Issues)-errorsCount)
+		if errorsCount != 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *diffFlag {
+		args := flag.Args()
+
+		output, err := native.DiffVST(args[0], args[1], *jsonFlag)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Print(output)
 		return
 	}
 
@@ -67,24 +220,83 @@ func main() {
 	}
 
 	var err error
-	varnam, err = govarnamgo.InitFromID(*schemeFlag)
+	varnam, err = native.InitFromIDWithProfile(*schemeFlag, *profileFlag)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 
-	varnam.Debug(*debugFlag)
+	if *baseProfileFlag != "" {
+		err = varnam.SetBaseProfile(*baseProfileFlag)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
 
-	config := govarnamgo.Config{IndicDigits: *indicDigitsFlag, DictionarySuggestionsLimit: 10, PatternDictionarySuggestionsLimit: 10, TokenizerSuggestionsLimit: 10, TokenizerSuggestionsAlways: true}
+	if *attachFlag != "" {
+		for _, dictPath := range strings.Split(*attachFlag, ",") {
+			err = varnam.AttachDictionary(dictPath)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+		}
+	}
+
+	layerWeightPolicy, found := layerWeightPolicies[*layerWeightFlag]
+	if !found {
+		log.Fatalf("Unknown layer weight policy %q", *layerWeightFlag)
+	}
+
+	// Debug outputs go to stdout, which is for messages in stdio mode
+	varnam.Debug(*debugFlag && !*stdioFlag)
+
+	explain = *explainFlag
+
+	config := native.Config{IndicDigits: *indicDigitsFlag, DictionarySuggestionsLimit: 10, PatternDictionarySuggestionsLimit: 10, TokenizerSuggestionsLimit: 10, TokenizerSuggestionsAlways: true, ExplainSuggestions: explain, LearningHalfLife: *halfLifeFlag * 24 * 60 * 60, FuzzyMatchMaxEdits: *fuzzyFlag, PhoneticMatch: *phoneticFlag, LayerWeightPolicy: layerWeightPolicy}
 	varnam.SetConfig(config)
 
 	args := flag.Args()
 
+	if *stdioFlag {
+		err := serveStdio(os.Stdin, os.Stdout)
+		varnam.Close()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		return
+	}
+
 	if *reIndexFlag {
 		err := varnam.ReIndexDictionary()
 		if err != nil {
 			log.Fatal(err.Error())
 		}
 		fmt.Println("Successfully re-indexed dictionary.")
+	} else if *listProfilesFlag {
+		profiles, err := varnam.ListProfiles()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		for _, profile := range profiles {
+			fmt.Println(profile)
+		}
+	} else if *createProfileFlag {
+		err := varnam.CreateProfile(args[0])
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Printf("Made profile %s\n", args[0])
+	} else if *deleteProfileFlag {
+		err := varnam.DeleteProfile(args[0])
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Printf("Deleted profile %s\n", args[0])
+	} else if *compactFlag {
+		changed, err := varnam.CompactLearnings()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Printf("Compacted weights of %d words\n", changed)
 	} else if *trainFlag {
 		pattern := args[0]
 		word := args[1]
@@ -129,7 +341,9 @@ func main() {
 			log.Fatal(err.Error())
 		}
 	} else if *exportFlag {
-		err := varnam.Export(args[0], *exportWordsPerFile)
+		err := varnam.ExportWithProgress(args[0], *exportWordsPerFile, func(progress native.LearningsProgress) {
+			fmt.Printf("Exported %d words, %d patterns\n", progress.Words, progress.Patterns)
+		})
 		if err == nil {
 			fmt.Println("Finished exporting to file")
 		} else {
@@ -143,13 +357,26 @@ func main() {
 		}
 
 		for _, match := range matches {
-			err := varnam.Import(match)
+			err := varnam.ImportWithProgress(match, func(progress native.LearningsProgress) {
+				fmt.Printf("Inserted %d words, %d patterns\n", progress.Words, progress.Patterns)
+			})
 			if err == nil {
 				fmt.Printf("Finished importing from file %s\n", match)
 			} else {
 				log.Fatal(err.Error())
 			}
 		}
+	} else if *mergeFlag {
+		strategy, found := mergeWeightStrategies[*mergeWeightFlag]
+		if !found {
+			log.Fatalf("Unknown merge weight strategy %q", *mergeWeightFlag)
+		}
+
+		report, err := varnam.MergeLearnings(args[0], strategy, *dryRunFlag)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		printMergeReport(report, *dryRunFlag)
 	} else if *reverseTransliterate {
 		sugs, err := varnam.ReverseTransliterate(args[0])
 		if err != nil {
@@ -169,8 +396,29 @@ func main() {
 			fmt.Println(sug.Word + " " + fmt.Sprint(sug.Weight))
 			lastWeight = sug.Weight
 		}
+	} else if *textFlag {
+		segments, err := varnam.TransliterateText(context.Background(), strings.Join(args, " "))
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		output := ""
+		for _, segment := range segments {
+			if segment.IsWord && len(segment.Suggestions) > 0 {
+				output += segment.Suggestions[0].Word
+			} else {
+				output += segment.Text
+			}
+		}
+		fmt.Println(output)
+	} else if *predictFlag {
+		sugs, err := varnam.PredictNextWords(context.Background(), args, *predictLimitFlag)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		printSugs(sugs)
 	} else if *advanced {
-		var result govarnamgo.TransliterationResult
+		var result native.TransliterationResult
 
 		result, _ = varnam.TransliterateAdvanced(context.Background(), args[0])
 
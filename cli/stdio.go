@@ -0,0 +1,428 @@
+package main
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+/**
+ * JSON-RPC 2.0 over stdio. One message per line.
+ * Editor plugins spawn `varnamcli -s ml -stdio` and talk to it.
+ *
+ * --> {"jsonrpc": "2.0", "id": 1, "method": "Transliterate", "params": {"word": "nithyam"}}
+ * <-- {"jsonrpc": "2.0", "id": 1, "result": [{"word": "നിത്യം", "weight": 8, "learned_on": 0}]}
+ *
+ * A request can be cancelled with the notification:
+ * --> {"jsonrpc": "2.0", "method": "$/cancel", "params": {"id": 1}}
+ *
+ * Cancelled reads get a RequestCancelled error. Writes (Learn, Train,
+ * Unlearn) are done anyway, and their result is sent.
+ *
+ * ID of a request can't be reused till its response is sent.
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/varnamproject/govarnam/govarnamgo/native"
+)
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+
+	// Varnam returned an error
+	rpcVarnamError = -32000
+
+	// Same as LSP's RequestCancelled
+	rpcRequestCancelled = -32800
+)
+
+// Maximum length of a message line
+const rpcMaxMessageSize = 1 << 20
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err *rpcError) Error() string {
+	return err.Message
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcWordParams struct {
+	Word string `json:"word"`
+}
+
+type rpcLearnParams struct {
+	Word   string `json:"word"`
+	Weight int    `json:"weight"`
+}
+
+type rpcTrainParams struct {
+	Pattern string `json:"pattern"`
+	Word    string `json:"word"`
+}
+
+type rpcRecentlyLearntParams struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+type rpcCancelParams struct {
+	ID json.RawMessage `json:"id"`
+}
+
+type stdioServer struct {
+	writeLock sync.Mutex
+	out       io.Writer
+
+	cancelsLock sync.Mutex
+	cancels     map[string]context.CancelFunc
+
+	// Requests waiting to be run. Not a channel so that
+	// reading never blocks and a cancel can always be read.
+	queueLock sync.Mutex
+	queueCond *sync.Cond
+	queue     []stdioCall
+	closed    bool
+}
+
+type stdioCall struct {
+	ctx     context.Context
+	request rpcRequest
+}
+
+// Request IDs can be numbers or strings
+func rpcIDKey(id json.RawMessage) string {
+	var buf bytes.Buffer
+	if json.Compact(&buf, id) != nil {
+		return string(id)
+	}
+	return buf.String()
+}
+
+func (s *stdioServer) send(response rpcResponse) {
+	response.JSONRPC = "2.0"
+	if response.ID == nil {
+		response.ID = json.RawMessage("null")
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	s.out.Write(append(data, '\n'))
+}
+
+func (s *stdioServer) sendError(id json.RawMessage, code int, msg string) {
+	s.send(rpcResponse{ID: id, Error: &rpcError{code, msg}})
+}
+
+func decodeParams(params json.RawMessage, value interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+
+	err := json.Unmarshal(params, value)
+	if err != nil {
+		return &rpcError{rpcInvalidParams, err.Error()}
+	}
+	return nil
+}
+
+func varnamError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// Read methods return these when cancelled
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return &rpcError{rpcRequestCancelled, "request cancelled"}
+	}
+
+	return &rpcError{rpcVarnamError, err.Error()}
+}
+
+// JSON encodes nil slices as null
+func nonNilSuggestions(sugs []native.Suggestion) []native.Suggestion {
+	if sugs == nil {
+		return []native.Suggestion{}
+	}
+	return sugs
+}
+
+// Methods are same as of VarnamHandle
+func (s *stdioServer) call(ctx context.Context, method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "Transliterate", "TransliterateAdvanced", "Unlearn", "GetSuggestions":
+		var args rpcWordParams
+		err := decodeParams(params, &args)
+		if err != nil {
+			return nil, err
+		}
+
+		switch method {
+		case "Transliterate":
+			sugs, err := varnam.Transliterate(ctx, args.Word)
+			return nonNilSuggestions(sugs), varnamError(err)
+
+		case "TransliterateAdvanced":
+			result, err := varnam.TransliterateAdvanced(ctx, args.Word)
+			result.ExactWords = nonNilSuggestions(result.ExactWords)
+			result.ExactMatches = nonNilSuggestions(result.ExactMatches)
+			result.DictionarySuggestions = nonNilSuggestions(result.DictionarySuggestions)
+			result.PatternDictionarySuggestions = nonNilSuggestions(result.PatternDictionarySuggestions)
+			result.TokenizerSuggestions = nonNilSuggestions(result.TokenizerSuggestions)
+			result.GreedyTokenized = nonNilSuggestions(result.GreedyTokenized)
+			return result, varnamError(err)
+
+		case "Unlearn":
+			return true, varnamError(varnam.Unlearn(args.Word))
+
+		case "GetSuggestions":
+			sugs, err := varnam.GetSuggestions(ctx, args.Word)
+			return nonNilSuggestions(sugs), varnamError(err)
+		}
+
+	case "Learn":
+		var args rpcLearnParams
+		err := decodeParams(params, &args)
+		if err != nil {
+			return nil, err
+		}
+		return true, varnamError(varnam.Learn(args.Word, args.Weight))
+
+	case "Train":
+		var args rpcTrainParams
+		err := decodeParams(params, &args)
+		if err != nil {
+			return nil, err
+		}
+		return true, varnamError(varnam.Train(args.Pattern, args.Word))
+
+	case "GetRecentlyLearntWords":
+		args := rpcRecentlyLearntParams{Limit: 30}
+		err := decodeParams(params, &args)
+		if err != nil {
+			return nil, err
+		}
+
+		sugs, err := varnam.GetRecentlyLearntWords(ctx, args.Offset, args.Limit)
+		return nonNilSuggestions(sugs), varnamError(err)
+
+	case "SearchSymbolTable":
+		// Fields not given are not searched for
+		symbol := native.NewSearchSymbol()
+		err := decodeParams(params, &symbol)
+		if err != nil {
+			return nil, err
+		}
+
+		symbols := varnam.SearchSymbolTable(ctx, symbol)
+		if ctx.Err() != nil {
+			return nil, varnamError(ctx.Err())
+		}
+		if symbols == nil {
+			symbols = []native.Symbol{}
+		}
+		return symbols, nil
+	}
+
+	return nil, &rpcError{rpcMethodNotFound, fmt.Sprintf("method '%s' not found", method)}
+}
+
+func (s *stdioServer) handle(c stdioCall) {
+	key := rpcIDKey(c.request.ID)
+	defer func() {
+		s.cancelsLock.Lock()
+		if cancel, found := s.cancels[key]; found {
+			cancel()
+			delete(s.cancels, key)
+		}
+		s.cancelsLock.Unlock()
+	}()
+
+	// Learn, Train & Unlearn aren't stopped by a cancel,
+	// so their result is sent even when cancelled
+	result, err := s.call(c.ctx, c.request.Method, c.request.Params)
+
+	if err != nil {
+		if rpcErr, ok := err.(*rpcError); ok {
+			s.sendError(c.request.ID, rpcErr.Code, rpcErr.Message)
+		} else {
+			s.sendError(c.request.ID, rpcVarnamError, err.Error())
+		}
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		s.sendError(c.request.ID, rpcVarnamError, err.Error())
+		return
+	}
+
+	s.send(rpcResponse{ID: c.request.ID, Result: data})
+}
+
+func (s *stdioServer) cancel(params json.RawMessage) {
+	var args rpcCancelParams
+	if decodeParams(params, &args) != nil || args.ID == nil {
+		return
+	}
+
+	s.cancelsLock.Lock()
+	defer s.cancelsLock.Unlock()
+
+	if cancel, found := s.cancels[rpcIDKey(args.ID)]; found {
+		cancel()
+	}
+}
+
+func (s *stdioServer) push(c stdioCall) {
+	s.queueLock.Lock()
+	s.queue = append(s.queue, c)
+	s.queueLock.Unlock()
+
+	s.queueCond.Signal()
+}
+
+// Get the next request, false if queue is closed & empty
+func (s *stdioServer) pop() (stdioCall, bool) {
+	s.queueLock.Lock()
+	defer s.queueLock.Unlock()
+
+	for len(s.queue) == 0 && !s.closed {
+		s.queueCond.Wait()
+	}
+	if len(s.queue) == 0 {
+		return stdioCall{}, false
+	}
+
+	c := s.queue[0]
+	s.queue[0] = stdioCall{}
+	s.queue = s.queue[1:]
+	return c, true
+}
+
+func (s *stdioServer) closeQueue() {
+	s.queueLock.Lock()
+	s.closed = true
+	s.queueLock.Unlock()
+
+	s.queueCond.Broadcast()
+}
+
+// Read messages from in till EOF. Requests are run one
+// after the other in the order they came, so that a word
+// learnt is available to the next transliteration.
+// Reading happens parallelly so that a cancel is
+// handled while a request is running.
+func serveStdio(in io.Reader, out io.Writer) error {
+	s := &stdioServer{
+		out:     out,
+		cancels: map[string]context.CancelFunc{},
+	}
+	s.queueCond = sync.NewCond(&s.queueLock)
+
+	done := make(chan bool)
+
+	go func() {
+		for {
+			c, ok := s.pop()
+			if !ok {
+				break
+			}
+			s.handle(c)
+		}
+		close(done)
+	}()
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 4096), rpcMaxMessageSize)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var request rpcRequest
+		err := json.Unmarshal(line, &request)
+		if err != nil {
+			s.sendError(nil, rpcParseError, err.Error())
+			continue
+		}
+
+		if request.Method == "$/cancel" {
+			s.cancel(request.Params)
+			continue
+		}
+
+		// Notifications of other methods are not supported
+		if request.ID == nil {
+			continue
+		}
+
+		if request.JSONRPC != "2.0" || request.Method == "" {
+			s.sendError(request.ID, rpcInvalidRequest, "invalid request")
+			continue
+		}
+
+		// A cancel of a reused ID would be ambiguous
+		key := rpcIDKey(request.ID)
+
+		s.cancelsLock.Lock()
+		_, inFlight := s.cancels[key]
+		s.cancelsLock.Unlock()
+
+		if inFlight {
+			s.sendError(request.ID, rpcInvalidRequest, fmt.Sprintf("request id %s is already in use", key))
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		s.cancelsLock.Lock()
+		s.cancels[key] = cancel
+		s.cancelsLock.Unlock()
+
+		s.push(stdioCall{ctx, request})
+	}
+
+	s.closeQueue()
+	<-done
+
+	return scanner.Err()
+}
@@ -0,0 +1,500 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+/**
+ * Transliteration cache. IMEs transliterate on every key press
+ * with growing prefixes of a word ("n", "na", "nam"...). Results
+ * are cached, and for a new word the tokens & dictionary search
+ * state of its prefix are reused.
+ */
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStats counters of the transliteration cache
+type CacheStats struct {
+	// Maximum number of entries. 0 if cache is disabled
+	Size int `json:"size"`
+
+	// Transliterations found in cache
+	Hits uint64 `json:"hits"`
+
+	// Transliterations not found in cache
+	Misses uint64 `json:"misses"`
+
+	// Tokenizations & dictionary searches that
+	// continued from the results of a prefix
+	PrefixHits uint64 `json:"prefix_hits"`
+}
+
+// Least recently used cache. Not safe for concurrent use
+type lruCache struct {
+	size  int
+	items map[interface{}]*list.Element
+
+	// Front is the most recently used
+	order *list.List
+}
+
+type lruItem struct {
+	key   interface{}
+	value interface{}
+}
+
+func newLRUCache(size int) *lruCache {
+	return &lruCache{
+		size:  size,
+		items: map[interface{}]*list.Element{},
+		order: list.New(),
+	}
+}
+
+func (cache *lruCache) get(key interface{}) (interface{}, bool) {
+	element, found := cache.items[key]
+	if !found {
+		return nil, false
+	}
+	cache.order.MoveToFront(element)
+	return element.Value.(*lruItem).value, true
+}
+
+func (cache *lruCache) add(key interface{}, value interface{}) {
+	if element, found := cache.items[key]; found {
+		element.Value.(*lruItem).value = value
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	cache.items[key] = cache.order.PushFront(&lruItem{key, value})
+
+	if cache.order.Len() > cache.size {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+func (cache *lruCache) clear() {
+	cache.items = map[interface{}]*list.Element{}
+	cache.order.Init()
+}
+
+type tokensCacheKey struct {
+	word        string
+	matchType   int
+	partial     bool
+	indicDigits bool
+}
+
+type cachedTokens struct {
+	tokens []Token
+
+	// Position in word where each token starts
+	starts []int
+}
+
+// Config that changes the result of transliterate()
+type resultCacheKey struct {
+	word string
+
+	indicDigits                bool
+	dictionaryMatchExact       bool
+	dictionaryLimit            int
+	patternDictionaryLimit     int
+	tokenizerLimit             int
+	tokenizerSuggestionsAlways bool
+	patternWordPartializers    int
+	explainSuggestions         bool
+	learningHalfLife           int
+	decayPeriod                int64
+	fuzzyMatchMaxEdits         int
+	fuzzyMatchCostLimit        int
+	phoneticMatch              bool
+	layerWeightPolicy          int
+}
+
+type cachedResult struct {
+	tokens []Token
+	result TransliterationResult
+}
+
+// Dictionary search state after searching a list of tokens.
+// Key is made with getTokensCacheKey()
+type dictionaryWalkCacheKey string
+
+// All methods can be called on a nil cache,
+// which is a disabled cache
+type transliterationCache struct {
+	mutex sync.Mutex
+
+	// Depends only on VST
+	tokens *lruCache
+
+	// Results & dictionary walks, depends on dictionary.
+	// Cleared when dictionary changes.
+	results *lruCache
+
+	// Incremented when results are cleared. A transliteration
+	// started before it is not added to cache.
+	generation uint64
+
+	// SQLite data_version of dictionary when results were
+	// last checked. See checkDictVersion()
+	dictVersion int64
+
+	stats CacheStats
+}
+
+func newTransliterationCache(size int) *transliterationCache {
+	return &transliterationCache{
+		tokens:  newLRUCache(size),
+		results: newLRUCache(size),
+		stats:   CacheStats{Size: size},
+	}
+}
+
+// Tokens & results are modified by their users,
+// so cache keeps and gives out copies.
+func copyTokens(tokens []Token) []Token {
+	result := make([]Token, len(tokens))
+	for i := range tokens {
+		result[i] = tokens[i]
+		result[i].symbols = append([]Symbol(nil), tokens[i].symbols...)
+	}
+	return result
+}
+
+func copySuggestions(sugs []Suggestion) []Suggestion {
+	if sugs == nil {
+		return nil
+	}
+
+	result := append([]Suggestion{}, sugs...)
+	for i := range result {
+		if result[i].Score != nil {
+			score := *result[i].Score
+			result[i].Score = &score
+		}
+	}
+	return result
+}
+
+func copyTransliterationResult(result TransliterationResult) TransliterationResult {
+	return TransliterationResult{
+		ExactWords:                   copySuggestions(result.ExactWords),
+		ExactMatches:                 copySuggestions(result.ExactMatches),
+		DictionarySuggestions:        copySuggestions(result.DictionarySuggestions),
+		PatternDictionarySuggestions: copySuggestions(result.PatternDictionarySuggestions),
+		TokenizerSuggestions:         copySuggestions(result.TokenizerSuggestions),
+		GreedyTokenized:              copySuggestions(result.GreedyTokenized),
+	}
+}
+
+// Tokens having same symbols at same positions have the same key.
+// Key of tokens[:i] is a prefix of key of tokens.
+func getTokensCacheKey(tokens []Token) string {
+	var key strings.Builder
+	for _, token := range tokens {
+		key.WriteString(strconv.Itoa(token.tokenType))
+		key.WriteByte(' ')
+		key.WriteString(strconv.Itoa(token.position))
+		key.WriteByte(' ')
+		key.WriteString(token.character)
+		for _, symbol := range token.symbols {
+			key.WriteByte(' ')
+			key.WriteString(strconv.Itoa(symbol.Type))
+			key.WriteByte(' ')
+			key.WriteString(symbol.Value1)
+			key.WriteByte(' ')
+			key.WriteString(symbol.Value2)
+		}
+		key.WriteByte('\n')
+	}
+	return key.String()
+}
+
+func (cache *transliterationCache) getGeneration() uint64 {
+	if cache == nil {
+		return 0
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	return cache.generation
+}
+
+func (cache *transliterationCache) getResult(key resultCacheKey) ([]Token, TransliterationResult, bool) {
+	if cache == nil {
+		return nil, TransliterationResult{}, false
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	value, found := cache.results.get(key)
+	if !found {
+		cache.stats.Misses++
+		return nil, TransliterationResult{}, false
+	}
+	cache.stats.Hits++
+
+	cached := value.(cachedResult)
+	return copyTokens(cached.tokens), copyTransliterationResult(cached.result), true
+}
+
+func (cache *transliterationCache) addResult(key resultCacheKey, tokens []Token, result TransliterationResult, generation uint64) {
+	if cache == nil {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if generation != cache.generation {
+		return
+	}
+
+	cache.results.add(key, cachedResult{copyTokens(tokens), copyTransliterationResult(result)})
+}
+
+func (cache *transliterationCache) getTokens(key tokensCacheKey) (cachedTokens, bool) {
+	if cache == nil {
+		return cachedTokens{}, false
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	value, found := cache.tokens.get(key)
+	if !found {
+		return cachedTokens{}, false
+	}
+
+	cached := value.(cachedTokens)
+	return cachedTokens{copyTokens(cached.tokens), cached.starts}, true
+}
+
+// Get tokens of the longest prefix of key.word in cache.
+// Returns the tokens and length of prefix in runes.
+func (cache *transliterationCache) getPrefixTokens(key tokensCacheKey) (cachedTokens, int, bool) {
+	if cache == nil {
+		return cachedTokens{}, 0, false
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	runes := []rune(key.word)
+	for length := len(runes) - 1; length > 0; length-- {
+		key.word = string(runes[:length])
+
+		value, found := cache.tokens.get(key)
+		if found {
+			cache.stats.PrefixHits++
+
+			cached := value.(cachedTokens)
+			return cachedTokens{copyTokens(cached.tokens), cached.starts}, length, true
+		}
+	}
+
+	return cachedTokens{}, 0, false
+}
+
+func (cache *transliterationCache) addTokens(key tokensCacheKey, tokens cachedTokens) {
+	if cache == nil {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.tokens.add(key, cachedTokens{copyTokens(tokens.tokens), append([]int{}, tokens.starts...)})
+}
+
+// Get dictionary search state of the longest prefix of tokens
+// in cache. Returns the state and number of tokens searched.
+func (cache *transliterationCache) getDictionaryWalk(tokens []Token) (dictionaryWalk, int) {
+	if cache == nil {
+		return dictionaryWalk{}, 0
+	}
+
+	// Keys of all prefixes
+	keys := make([]string, len(tokens))
+	for i := range tokens {
+		key := getTokensCacheKey(tokens[i : i+1])
+		if i > 0 {
+			key = keys[i-1] + key
+		}
+		keys[i] = key
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	for i := len(tokens) - 1; i >= 0; i-- {
+		value, found := cache.results.get(dictionaryWalkCacheKey(keys[i]))
+		if found {
+			cache.stats.PrefixHits++
+			return value.(dictionaryWalk).copy(), i + 1
+		}
+	}
+
+	return dictionaryWalk{}, 0
+}
+
+func (cache *transliterationCache) addDictionaryWalk(tokens []Token, walk dictionaryWalk, generation uint64) {
+	if cache == nil {
+		return
+	}
+
+	key := dictionaryWalkCacheKey(getTokensCacheKey(tokens))
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if generation != cache.generation {
+		return
+	}
+
+	cache.results.add(key, walk.copy())
+}
+
+// Clear results that depend on dictionary
+func (cache *transliterationCache) clearResults() {
+	if cache == nil {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.results.clear()
+	cache.generation++
+}
+
+// Clear results if dictionary was changed by another
+// instance or process since the last check
+func (cache *transliterationCache) checkDictVersion(version int64) {
+	if cache == nil {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if version != cache.dictVersion {
+		cache.results.clear()
+		cache.generation++
+		cache.dictVersion = version
+	}
+}
+
+func (cache *transliterationCache) clear() {
+	if cache == nil {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.tokens.clear()
+	cache.results.clear()
+	cache.generation++
+}
+
+func (cache *transliterationCache) getStats() CacheStats {
+	if cache == nil {
+		return CacheStats{}
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	return cache.stats
+}
+
+func (varnam *Varnam) getResultCacheKey(word string) resultCacheKey {
+	return resultCacheKey{
+		word:                       word,
+		indicDigits:                varnam.LangRules.IndicDigits,
+		dictionaryMatchExact:       varnam.DictionaryMatchExact,
+		dictionaryLimit:            varnam.DictionarySuggestionsLimit,
+		patternDictionaryLimit:     varnam.PatternDictionarySuggestionsLimit,
+		tokenizerLimit:             varnam.TokenizerSuggestionsLimit,
+		tokenizerSuggestionsAlways: varnam.TokenizerSuggestionsAlways,
+		patternWordPartializers:    len(varnam.PatternWordPartializers),
+		explainSuggestions:         varnam.ExplainSuggestions,
+		learningHalfLife:           varnam.LearningHalfLife,
+		decayPeriod:                decayPeriod(time.Now().Unix(), varnam.LearningHalfLife),
+		fuzzyMatchMaxEdits:         varnam.FuzzyMatchMaxEdits,
+		fuzzyMatchCostLimit:        varnam.FuzzyMatchCostLimit,
+		phoneticMatch:              varnam.PhoneticMatch,
+		layerWeightPolicy:          varnam.LayerWeightPolicy,
+	}
+}
+
+// Get SQLite data_version of dictionary. It changes when another
+// connection, of this instance or not, writes to the file. It's
+// per connection, so one connection is kept for reading it.
+// Returns -1 on error, which is never a cached version.
+func (varnam *Varnam) getDictVersion(ctx context.Context) int64 {
+	varnam.dictVersionMutex.Lock()
+	defer varnam.dictVersionMutex.Unlock()
+
+	if varnam.dictVersionConn == nil {
+		conn, err := varnam.dictConn.Conn(ctx)
+		if err != nil {
+			return -1
+		}
+		varnam.dictVersionConn = conn
+	}
+
+	var version int64
+	err := varnam.dictVersionConn.QueryRowContext(ctx, "PRAGMA data_version").Scan(&version)
+	if err != nil {
+		varnam.closeDictVersionConn()
+		return -1
+	}
+	return version
+}
+
+// Called before dictConn is closed
+func (varnam *Varnam) closeDictVersionConn() {
+	if varnam.dictVersionConn != nil {
+		varnam.dictVersionConn.Close()
+		varnam.dictVersionConn = nil
+	}
+}
+
+// EnableCache cache transliteration results, keeping at most
+// size results. Cache is cleared on learning. 0 disables it.
+func (varnam *Varnam) EnableCache(size int) {
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
+	if size <= 0 {
+		varnam.cache = nil
+	} else {
+		varnam.cache = newTransliterationCache(size)
+	}
+}
+
+// CacheStats get hit & miss counters of cache
+func (varnam *Varnam) CacheStats() CacheStats {
+	varnam.mutex.RLock()
+	defer varnam.mutex.RUnlock()
+
+	return varnam.cache.getStats()
+}
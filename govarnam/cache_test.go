@@ -0,0 +1,133 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Results with cache should be same as without it
+func TestCacheSameResults(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+
+	for _, word := range concurrentWords {
+		checkError(varnam.Learn(word, 0))
+	}
+
+	var words []string
+	added := map[string]bool{}
+	for _, word := range []string{"nithyam", "vayalil", "vayal", "namaskaaram"} {
+		// Like typing in an IME
+		for i := 1; i <= len(word); i++ {
+			if !added[word[:i]] {
+				words = append(words, word[:i])
+				added[word[:i]] = true
+			}
+		}
+	}
+
+	var expected []TransliterationResult
+	for _, word := range words {
+		expected = append(expected, varnam.TransliterateAdvanced(word))
+	}
+
+	varnam.EnableCache(100)
+
+	// Second round is from cache
+	for round := 0; round < 2; round++ {
+		for i, word := range words {
+			result := varnam.TransliterateAdvanced(word)
+			if !reflect.DeepEqual(result, expected[i]) {
+				t.Errorf("Result of %s differs with cache: %v, expected %v", word, result, expected[i])
+			}
+		}
+	}
+
+	stats := varnam.CacheStats()
+	assertEqual(t, stats.Size, 100)
+	assertEqual(t, stats.Hits, uint64(len(words)))
+	assertEqual(t, stats.Misses, uint64(len(words)))
+	assertEqual(t, stats.PrefixHits > 0, true)
+
+	// Cached results are not affected by changes to returned ones
+	sugs := varnam.Transliterate("vayal")
+	sugs[0].Word = "changed"
+	assertEqual(t, varnam.Transliterate("vayal")[0].Word != "changed", true)
+
+	varnam.EnableCache(0)
+	assertEqual(t, varnam.CacheStats(), CacheStats{})
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+	varnam.EnableCache(10)
+
+	assertEqual(t, len(varnam.TransliterateAdvanced("vayal").ExactWords), 0)
+
+	checkError(varnam.Learn("വയൽ", 0))
+	assertEqual(t, varnam.TransliterateAdvanced("vayal").ExactWords[0].Word, "വയൽ")
+
+	checkError(varnam.Unlearn("വയൽ"))
+	assertEqual(t, len(varnam.TransliterateAdvanced("vayal").ExactWords), 0)
+
+	checkError(varnam.Train("vayal", "വയൽ"))
+	assertEqual(t, varnam.TransliterateAdvanced("vayal").ExactWords[0].Word, "വയൽ")
+
+	stats := varnam.CacheStats()
+	assertEqual(t, stats.Hits, uint64(0))
+	assertEqual(t, stats.Misses, uint64(4))
+
+	// Config is part of the key
+	varnam.TransliterateAdvanced("vayal")
+	varnam.DictionarySuggestionsLimit = 1
+	varnam.TransliterateAdvanced("vayal")
+
+	stats = varnam.CacheStats()
+	assertEqual(t, stats.Hits, uint64(1))
+	assertEqual(t, stats.Misses, uint64(5))
+}
+
+// Instances sharing learnings file
+func TestCacheSharedDictionary(t *testing.T) {
+	learningsDir := t.TempDir()
+	varnam := makeTestInstance(t, "ml", learningsDir)
+	other := makeTestInstance(t, "ml", learningsDir)
+	varnam.EnableCache(10)
+
+	assertEqual(t, len(varnam.TransliterateAdvanced("vayal").ExactWords), 0)
+
+	checkError(other.Learn("വയൽ", 0))
+	assertEqual(t, varnam.TransliterateAdvanced("vayal").ExactWords[0].Word, "വയൽ")
+
+	checkError(other.Unlearn("വയൽ"))
+	assertEqual(t, len(varnam.TransliterateAdvanced("vayal").ExactWords), 0)
+
+	// Not changed, from cache
+	varnam.TransliterateAdvanced("vayal")
+	assertEqual(t, varnam.CacheStats().Hits, uint64(1))
+}
+
+func TestLRUCache(t *testing.T) {
+	cache := newLRUCache(2)
+
+	cache.add("a", 1)
+	cache.add("b", 2)
+	cache.get("a")
+	cache.add("c", 3)
+
+	_, found := cache.get("b")
+	assertEqual(t, found, false)
+
+	value, found := cache.get("a")
+	assertEqual(t, found, true)
+	assertEqual(t, value, 1)
+
+	cache.clear()
+	_, found = cache.get("a")
+	assertEqual(t, found, false)
+}
@@ -13,7 +13,7 @@ type channelDictionaryResult struct {
 	suggestions  []Suggestion
 }
 
-func (varnam *Varnam) channelTokenizeWord(ctx context.Context, word string, matchType int, partial bool, channel chan *[]Token) {
+func (varnam *Varnam) channelTokenizeWord(ctx context.Context, cache *transliterationCache, word string, matchType int, partial bool, channel chan *[]Token) {
 	select {
 	case <-ctx.Done():
 		close(channel)
@@ -21,7 +21,7 @@ func (varnam *Varnam) channelTokenizeWord(ctx context.Context, word string, matc
 	default:
 		start := time.Now()
 
-		tokens := varnam.tokenizeWord(ctx, word, matchType, partial)
+		tokens := varnam.tokenizeWord(ctx, cache, word, matchType, partial)
 
 		if LOG_TIME_TAKEN {
 			log.Printf("%s took %v\n", "channelTokenizeWord", time.Since(start))
@@ -32,6 +32,21 @@ func (varnam *Varnam) channelTokenizeWord(ctx context.Context, word string, matc
 	}
 }
 
+func (varnam *Varnam) channelTransliterate(ctx context.Context, word string, channel chan []Suggestion) {
+	select {
+	case <-ctx.Done():
+		close(channel)
+		return
+	default:
+		varnam.mutex.RLock()
+		_, result := varnam.transliterate(ctx, word)
+		varnam.mutex.RUnlock()
+
+		channel <- varnam.rankResult(result)
+		close(channel)
+	}
+}
+
 func (varnam *Varnam) channelTokensToSuggestions(ctx context.Context, tokens *[]Token, limit int, channel chan []Suggestion) {
 	select {
 	case <-ctx.Done():
@@ -70,7 +85,7 @@ func (varnam *Varnam) channelTokensToGreedySuggestions(ctx context.Context, toke
 	}
 }
 
-func (varnam *Varnam) channelGetFromDictionary(ctx context.Context, word string, tokens *[]Token, channel chan channelDictionaryResult) {
+func (varnam *Varnam) channelGetFromDictionary(ctx context.Context, cache *transliterationCache, word string, tokens *[]Token, channel chan channelDictionaryResult) {
 	var (
 		exactWords      []Suggestion
 		exactMatches    []Suggestion
@@ -84,7 +99,7 @@ func (varnam *Varnam) channelGetFromDictionary(ctx context.Context, word string,
 	default:
 		start := time.Now()
 
-		dictResult := varnam.getFromDictionary(ctx, tokens)
+		dictResult := varnam.getFromDictionary(ctx, cache, tokens)
 
 		if varnam.Debug {
 			fmt.Println("Dictionary results:", dictResult)
@@ -134,6 +149,7 @@ func (varnam *Varnam) channelGetFromDictionary(ctx context.Context, word string,
 
 			moreSuggestions = varnam.tokenizeRestOfWord(
 				ctx,
+				cache,
 				restOfWord,
 				dictResult.partialMatches,
 				varnam.DictionarySuggestionsLimit,
@@ -144,6 +160,48 @@ func (varnam *Varnam) channelGetFromDictionary(ctx context.Context, word string,
 			}
 		}
 
+		// Input may have a typo
+		if len(dictResult.exactMatches) == 0 && varnam.FuzzyMatchMaxEdits > 0 {
+			start := time.Now()
+
+			found := make(map[string]bool)
+			for _, sug := range moreSuggestions {
+				found[sug.Word] = true
+			}
+
+			for _, sug := range varnam.getFuzzyFromDictionary(ctx, word, *tokens, dictResult) {
+				if !found[sug.Word] {
+					moreSuggestions = append(moreSuggestions, sug)
+				}
+			}
+
+			if LOG_TIME_TAKEN {
+				log.Printf("%s took %v\n", "getFuzzyFromDictionary", time.Since(start))
+			}
+		}
+
+		// Input may be spelled differently
+		if varnam.PhoneticMatch {
+			start := time.Now()
+
+			found := make(map[string]bool)
+			for _, sugs := range [][]Suggestion{exactWords, exactMatches, moreSuggestions} {
+				for _, sug := range sugs {
+					found[sug.Word] = true
+				}
+			}
+
+			for _, sug := range varnam.getFromPhoneticIndex(ctx, word) {
+				if !found[sug.Word] {
+					moreSuggestions = append(moreSuggestions, sug)
+				}
+			}
+
+			if LOG_TIME_TAKEN {
+				log.Printf("%s took %v\n", "getFromPhoneticIndex", time.Since(start))
+			}
+		}
+
 		if LOG_TIME_TAKEN {
 			log.Printf("%s took %v\n", "channelGetFromDictionary", time.Since(start))
 		}
@@ -157,7 +215,7 @@ func (varnam *Varnam) channelGetFromDictionary(ctx context.Context, word string,
 	}
 }
 
-func (varnam *Varnam) channelGetFromPatternDictionary(ctx context.Context, word string, channel chan channelDictionaryResult) {
+func (varnam *Varnam) channelGetFromPatternDictionary(ctx context.Context, cache *transliterationCache, word string, channel chan channelDictionaryResult) {
 	var (
 		exactWords      []Suggestion
 		moreSuggestions []Suggestion
@@ -186,6 +244,7 @@ func (varnam *Varnam) channelGetFromPatternDictionary(ctx context.Context, word
 					// Increase weight on length matched.
 					// 50 because half of 100%
 					sug.Weight += match.Length * 50
+					varnam.addScore(sug, ScoreBreakdown{PatternLengthBonus: match.Length * 50})
 
 					for _, cb := range varnam.PatternWordPartializers {
 						cb(sug)
@@ -211,6 +270,7 @@ func (varnam *Varnam) channelGetFromPatternDictionary(ctx context.Context, word
 
 				filled := varnam.tokenizeRestOfWord(
 					ctx,
+					cache,
 					restOfWord,
 					[]Suggestion{partialMatches[i].Sug},
 					perMatchLimit,
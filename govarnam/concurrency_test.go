@@ -0,0 +1,134 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+)
+
+// Run with -race to check for data races
+
+var concurrentWords = []string{"വയൽ", "വയലിൽ", "നിത്യം"}
+
+// Transliterate, learn & unlearn from multiple goroutines at once
+func exerciseVarnam(t *testing.T, varnam *Varnam, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ctx := context.Background()
+
+	for _, word := range concurrentWords {
+		err := varnam.Learn(word, 0)
+		if err != nil {
+			t.Error(err)
+		}
+
+		varnam.Transliterate("nithyam")
+		varnam.TransliterateAdvanced("vayal")
+		varnam.GetSuggestions(ctx, "വയ")
+		varnam.PredictNextWords(ctx, []string{word}, 5)
+
+		channel := make(chan []Suggestion, 1)
+		varnam.TransliterateWithContext(ctx, "vayalil", channel)
+		<-channel
+
+		_, err = varnam.TransliterateText(ctx, "nithyam vayal, nithyam")
+		if err != nil {
+			t.Error(err)
+		}
+
+		_, err = varnam.SearchSymbolTable(ctx, NewSearchSymbol())
+		if err != nil {
+			t.Error(err)
+		}
+
+		_, err = varnam.GetRecentlyLearntWords(ctx, 0, 10)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	err := varnam.Unlearn(concurrentWords[0])
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	sharedDir, err := os.MkdirTemp(testTempDir, "shared")
+	checkError(err)
+
+	otherDir, err := os.MkdirTemp(testTempDir, "other")
+	checkError(err)
+
+	// ml & ml-inscript use the same learnings file
+	instances := []*Varnam{
+		makeTestInstance(t, "ml", sharedDir),
+		makeTestInstance(t, "ml-inscript", sharedDir),
+		makeTestInstance(t, "ml", otherDir),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		for _, varnam := range instances {
+			wg.Add(1)
+			go exerciseVarnam(t, varnam, &wg)
+		}
+	}
+	wg.Wait()
+
+	for _, varnam := range instances {
+		sugs := varnam.GetSuggestions(context.Background(), concurrentWords[1])
+		assertEqual(t, len(sugs) > 0, true)
+		assertEqual(t, sugs[0].Word, concurrentWords[1])
+	}
+
+	assertEqual(t, instances[0].DictPath, instances[1].DictPath)
+	assertEqual(t, instances[0].DictPath != instances[2].DictPath, true)
+}
+
+func TestConcurrentInit(t *testing.T) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			varnam := makeTestInstance(t, "ml", testTempDir)
+			varnam.Transliterate("nithyam")
+		}()
+	}
+
+	wg.Wait()
+}
+
+// Changing config while transliterating & learning shouldn't deadlock
+func TestConcurrentConfigChange(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			varnam.RegisterPatternWordPartializer(func(sug *Suggestion) {})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			varnam.Transliterate("nithyam")
+			checkError(varnam.Learn(concurrentWords[0], 0))
+		}
+	}()
+
+	wg.Wait()
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sync"
 )
 
 // Compile-time variables.
@@ -55,9 +56,35 @@ const VARNAM_TOKEN_ACCEPT_IF_STARTS_WITH = 1
 const VARNAM_TOKEN_ACCEPT_IF_IN_BETWEEN = 2
 const VARNAM_TOKEN_ACCEPT_IF_ENDS_WITH = 3
 
+/* Where a suggestion came from, see Suggestion.Source.
+ * 0 if it's not from a transliteration. */
+const VARNAM_SOURCE_EXACT_WORD = 1
+const VARNAM_SOURCE_EXACT_MATCH = 2
+const VARNAM_SOURCE_DICTIONARY = 3
+const VARNAM_SOURCE_PATTERN_DICTIONARY = 4
+const VARNAM_SOURCE_TOKENIZER = 5
+const VARNAM_SOURCE_GREEDY = 6
+
+/* How weights of a word in more than one dictionary
+ * layer are combined, see Varnam.LayerWeightPolicy */
+const VARNAM_LAYER_WEIGHT_OVERRIDE = 0 // Weight in the upper layer
+const VARNAM_LAYER_WEIGHT_MAX = 1
+const VARNAM_LAYER_WEIGHT_SUM = 2
+
+/* How weights of a word in both dictionaries are
+ * combined when merging learnings, see MergeLearnings() */
+const VARNAM_MERGE_WEIGHT_MAX = 0
+const VARNAM_MERGE_WEIGHT_SUM = 1
+
 // VARNAM_LEARNT_WORD_MIN_WEIGHT Minimum weight/confidence for learnt words.
 const VARNAM_LEARNT_WORD_MIN_WEIGHT = 30
 
+// VARNAM_FUZZY_MATCH_COST_LIMIT Default maximum inputs searched with fuzzy matching
+const VARNAM_FUZZY_MATCH_COST_LIMIT = 200
+
+// VARNAM_DEFAULT_PROFILE Name of the learnings profile used by default
+const VARNAM_DEFAULT_PROFILE = "default"
+
 const CHIL_TAG = "chill"
 
 /* VST creation */
@@ -74,24 +101,68 @@ const VARNAM_METADATA_SCHEME_AUTHOR = "scheme-author"
 const VARNAM_METADATA_SCHEME_COMPILED_DATE = "scheme-compiled-date"
 const VARNAM_METADATA_SCHEME_STABLE = "scheme-stable"
 
+/* VST validation */
+const VARNAM_VST_ISSUE_ERROR = 1
+const VARNAM_VST_ISSUE_WARNING = 2
+
+const VARNAM_VST_CHECK_METADATA = "metadata"
+const VARNAM_VST_CHECK_SCHEMA_VERSION = "schema-version"
+const VARNAM_VST_CHECK_VIRAMA = "virama"
+const VARNAM_VST_CHECK_DEAD_CONSONANT = "dead-consonant"
+const VARNAM_VST_CHECK_DUPLICATE = "duplicate"
+const VARNAM_VST_CHECK_ORPHAN_POSSIBILITY = "orphan-possibility"
+const VARNAM_VST_CHECK_LENGTH = "length"
+const VARNAM_VST_CHECK_UNICODE_BLOCK = "unicode-block"
+
+// Default directories of all instances. Initially from
+// environment variables. Instances can have their own
+// directories with InitFromIDWithDirs()
+var defaultDirsMutex sync.RWMutex
+
+// VARNAM_VST_DIR default VST directory.
+//
+// Deprecated: Use SetVSTLookupDir(). Setting this directly
+// isn't safe while instances are being made.
 var VARNAM_VST_DIR = os.Getenv("VARNAM_VST_DIR")
+
+// VARNAM_LEARNINGS_DIR default learnings directory.
+//
+// Deprecated: Use SetLearningsDir(). Setting this directly
+// isn't safe while instances are being made.
 var VARNAM_LEARNINGS_DIR = os.Getenv("VARNAM_LEARNINGS_DIR")
 
-// SetVSTLookupDir This overrides the environment variable
+// SetVSTLookupDir This overrides the environment variable.
+// Affects instances made after this.
 func SetVSTLookupDir(path string) {
+	defaultDirsMutex.Lock()
 	VARNAM_VST_DIR = path
+	defaultDirsMutex.Unlock()
 }
 
-// SetVSTLookupDir This overrides the environment variable
+// SetLearningsDir This overrides the environment variable.
+// Affects instances made after this.
 func SetLearningsDir(path string) {
+	defaultDirsMutex.Lock()
 	VARNAM_LEARNINGS_DIR = path
+	defaultDirsMutex.Unlock()
 }
 
-// VARNAM_VST_DIR VST lookup directories according to priority
-func getVSTLookupDirs() []string {
+func getDefaultDirs() (string, string) {
+	defaultDirsMutex.RLock()
+	defer defaultDirsMutex.RUnlock()
+
+	return VARNAM_VST_DIR, VARNAM_LEARNINGS_DIR
+}
+
+// VST lookup directories according to priority
+func getVSTLookupDirs(vstDir string) []string {
+	if vstDir == "" {
+		vstDir, _ = getDefaultDirs()
+	}
+
 	return []string{
 		// libvarnam used to use "vst" folder
-		VARNAM_VST_DIR,
+		vstDir,
 		"schemes",
 		"/usr/local/share/varnam/schemes",
 		"/usr/share/varnam/schemes",
@@ -100,7 +171,7 @@ func getVSTLookupDirs() []string {
 
 // FindVSTDir Get the VST storing directory
 func FindVSTDir() (string, error) {
-	for _, loc := range getVSTLookupDirs() {
+	for _, loc := range getVSTLookupDirs("") {
 		if dirExists(loc) {
 			return loc, nil
 		}
@@ -108,8 +179,8 @@ func FindVSTDir() (string, error) {
 	return "", fmt.Errorf("Couldn't find VST directory")
 }
 
-func findVSTPath(schemeID string) (string, error) {
-	for _, dir := range getVSTLookupDirs() {
+func findVSTPath(vstDir string, schemeID string) (string, error) {
+	for _, dir := range getVSTLookupDirs(vstDir) {
 		temp := path.Join(dir, schemeID+".vst")
 		if fileExists(temp) {
 			return temp, nil
@@ -118,14 +189,18 @@ func findVSTPath(schemeID string) (string, error) {
 	return "", fmt.Errorf("Couldn't find VST for %q", schemeID)
 }
 
-func findLearningsFilePath(langCode string) string {
+func findLearningsFilePath(learningsDir string, langCode string, profile string) string {
 	var (
 		loc string
 		dir string
 	)
 
-	if VARNAM_LEARNINGS_DIR != "" {
-		dir = VARNAM_LEARNINGS_DIR
+	if learningsDir == "" {
+		_, learningsDir = getDefaultDirs()
+	}
+
+	if learningsDir != "" {
+		dir = learningsDir
 	} else {
 		// libvarnam used to use "suggestions" folder
 		home := os.Getenv("XDG_DATA_HOME")
@@ -137,7 +212,7 @@ func findLearningsFilePath(langCode string) string {
 		}
 	}
 
-	loc = path.Join(dir, langCode+".vst.learnings")
+	loc = path.Join(dir, getLearningsFileName(langCode, profile))
 
 	return loc
 }
@@ -0,0 +1,196 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+/**
+ * Decay of learnt weights. Weight of a word is the number of
+ * times it was learnt, which only grows. With a half-life set,
+ * the part of weight above VARNAM_LEARNT_WORD_MIN_WEIGHT halves
+ * for every half-life since the word was last learnt. Stored
+ * weights are unchanged, decay is applied when searching.
+ *
+ * CompactLearnings() stores the decayed weights. The time it was
+ * run is kept in metadata, weights are decayed from that time on.
+ */
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const learningsCompactedOnKey = "learnings_compacted_on"
+
+// Weight of a word learnt learnedOn, at time now
+func decayWeight(weight int, learnedOn int64, now int64, halfLife int) int {
+	if halfLife <= 0 || learnedOn == 0 || weight <= VARNAM_LEARNT_WORD_MIN_WEIGHT {
+		return weight
+	}
+
+	age := now - learnedOn
+	if age <= 0 {
+		return weight
+	}
+
+	above := float64(weight - VARNAM_LEARNT_WORD_MIN_WEIGHT)
+	return VARNAM_LEARNT_WORD_MIN_WEIGHT + int(math.Round(above*math.Pow(0.5, float64(age)/float64(halfLife))))
+}
+
+// Number of the period now is in. A period is 1/100th of the
+// half-life, decayed weights change by less than 1% in it.
+// Cached results are only reused in the same period.
+func decayPeriod(now int64, halfLife int) int64 {
+	if halfLife <= 0 {
+		return 0
+	}
+
+	length := int64(halfLife / 100)
+	if length < 1 {
+		length = 1
+	}
+	return now / length
+}
+
+// Weight decayed till now. Weights were
+// last decayed when learnings were compacted.
+func (varnam *Varnam) decayedWeight(weight int, learnedOn int) int {
+	if varnam.LearningHalfLife <= 0 {
+		return weight
+	}
+
+	since := int64(learnedOn)
+	if compactedOn := atomic.LoadInt64(&varnam.compactedOn); compactedOn > since {
+		since = compactedOn
+	}
+
+	return decayWeight(weight, since, time.Now().Unix(), varnam.LearningHalfLife)
+}
+
+// Make a suggestion of a word from dictionary
+func (varnam *Varnam) makeLearnedSuggestion(word string, weight int, learnedOn int) Suggestion {
+	sug := Suggestion{
+		Word:      word,
+		Weight:    varnam.decayedWeight(weight, learnedOn),
+		LearnedOn: learnedOn,
+	}
+	varnam.addScore(&sug, ScoreBreakdown{LearnedWeight: weight, Recency: sug.Weight - weight})
+	return sug
+}
+
+// Read the time learnings were last compacted from dictionary
+func (varnam *Varnam) loadCompactedOn() error {
+	var value string
+	err := varnam.dictConn.QueryRow("SELECT value FROM metadata WHERE key = ?", learningsCompactedOnKey).Scan(&value)
+	if err != nil {
+		// Never compacted
+		atomic.StoreInt64(&varnam.compactedOn, 0)
+		return nil
+	}
+
+	compactedOn, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s in dictionary: %s", learningsCompactedOnKey, value)
+	}
+
+	atomic.StoreInt64(&varnam.compactedOn, compactedOn)
+	return nil
+}
+
+// CompactLearnings store the decayed weights of words, so that
+// weights don't grow forever. Needs LearningHalfLife to be set.
+// Returns the number of words whose weight changed.
+func (varnam *Varnam) CompactLearnings() (int, error) {
+	varnam.lockDict()
+	defer varnam.unlockDict()
+
+	if varnam.LearningHalfLife <= 0 {
+		return 0, fmt.Errorf("learning half-life is not set")
+	}
+
+	now := time.Now().Unix()
+	compactedOn := atomic.LoadInt64(&varnam.compactedOn)
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancelFunc()
+
+	tx, err := varnam.dictConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, weight, COALESCE(learned_on, 0) FROM words WHERE weight > ?", VARNAM_LEARNT_WORD_MIN_WEIGHT)
+	if err != nil {
+		return 0, err
+	}
+
+	type wordWeight struct {
+		id     int
+		weight int
+	}
+
+	var changed []wordWeight
+	for rows.Next() {
+		var (
+			item      wordWeight
+			learnedOn int64
+		)
+		err = rows.Scan(&item.id, &item.weight, &learnedOn)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		since := learnedOn
+		if compactedOn > since {
+			since = compactedOn
+		}
+
+		weight := decayWeight(item.weight, since, now, varnam.LearningHalfLife)
+		if weight != item.weight {
+			changed = append(changed, wordWeight{item.id, weight})
+		}
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "UPDATE words SET weight = ? WHERE id = ?")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, item := range changed {
+		_, err = stmt.ExecContext(ctx, item.weight, item.id)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT OR REPLACE INTO metadata(key, value) VALUES (?, ?)", learningsCompactedOnKey, strconv.FormatInt(now, 10))
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	atomic.StoreInt64(&varnam.compactedOn, now)
+
+	if varnam.Debug {
+		fmt.Printf("Compacted weights of %d words\n", len(changed))
+	}
+
+	return len(changed), nil
+}
@@ -0,0 +1,162 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDecayWeight(t *testing.T) {
+	min := VARNAM_LEARNT_WORD_MIN_WEIGHT
+	now := int64(1700000000)
+
+	// Disabled
+	assertEqual(t, decayWeight(min+64, now-1000, now, 0), min+64)
+	// Never learnt
+	assertEqual(t, decayWeight(min+64, 0, now, 1000), min+64)
+	// Learnt now
+	assertEqual(t, decayWeight(min+64, now, now, 1000), min+64)
+	// Minimum weight doesn't decay
+	assertEqual(t, decayWeight(min, now-5000, now, 1000), min)
+	assertEqual(t, decayWeight(min-10, now-5000, now, 1000), min-10)
+
+	assertEqual(t, decayWeight(min+64, now-1000, now, 1000), min+32)
+	assertEqual(t, decayWeight(min+64, now-2000, now, 1000), min+16)
+	assertEqual(t, decayWeight(min+64, now-100000, now, 1000), min)
+}
+
+func TestDecayPeriod(t *testing.T) {
+	now := int64(1700000000)
+
+	assertEqual(t, decayPeriod(now, 0), int64(0))
+	assertEqual(t, decayPeriod(now+1000, 0), int64(0))
+
+	// Periods of 10 seconds
+	assertEqual(t, decayPeriod(now+9, 1000), decayPeriod(now, 1000))
+	assertEqual(t, decayPeriod(now+10, 1000) != decayPeriod(now, 1000), true)
+
+	// At least a second
+	assertEqual(t, decayPeriod(now+1, 50) != decayPeriod(now, 50), true)
+}
+
+// Set when a word was last learnt
+func setLearnedOn(varnam *Varnam, word string, learnedOn int64) {
+	_, err := varnam.dictConn.Exec("UPDATE words SET learned_on = ? WHERE word = ?", learnedOn, word)
+	checkError(err)
+}
+
+func TestLearningDecay(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+	ctx := context.Background()
+
+	// Used a lot a year ago
+	checkError(varnam.Learn("മലയാളം", 100))
+	setLearnedOn(varnam, "മലയാളം", time.Now().Unix()-365*24*60*60)
+
+	checkError(varnam.Learn("മലയാളത്തിൽ", 0))
+	checkError(varnam.Learn("മലയാളത്തിൽ", 0))
+
+	sugs := varnam.GetSuggestions(ctx, "മലയാള")
+	assertEqual(t, sugs[0].Word, "മലയാളം")
+	assertEqual(t, sugs[0].Weight, 101)
+
+	varnam.LearningHalfLife = 30 * 24 * 60 * 60
+	varnam.ExplainSuggestions = true
+
+	sugs = varnam.GetSuggestions(ctx, "മലയാള")
+	sugs = SortSuggestions(sugs)
+	assertEqual(t, sugs[0].Word, "മലയാളത്തിൽ")
+	assertEqual(t, sugs[0].Weight, VARNAM_LEARNT_WORD_MIN_WEIGHT+1)
+	assertEqual(t, sugs[0].Score.Recency, 0)
+
+	assertEqual(t, sugs[1].Word, "മലയാളം")
+	assertEqual(t, sugs[1].Weight, VARNAM_LEARNT_WORD_MIN_WEIGHT)
+	assertEqual(t, sugs[1].Score.LearnedWeight, 101)
+	assertEqual(t, sugs[1].Score.Recency, VARNAM_LEARNT_WORD_MIN_WEIGHT-101)
+
+	// Stored weight is unchanged
+	info, err := varnam.getWordInfo("മലയാളം")
+	checkError(err)
+	assertEqual(t, info.weight, 101)
+}
+
+func TestCompactLearnings(t *testing.T) {
+	dir := t.TempDir()
+	varnam := makeTestInstance(t, "ml", dir)
+	ctx := context.Background()
+
+	_, err := varnam.CompactLearnings()
+	assertEqual(t, err != nil, true)
+
+	halfLife := 1000
+	varnam.LearningHalfLife = halfLife
+
+	checkError(varnam.Learn("മലയാളം", VARNAM_LEARNT_WORD_MIN_WEIGHT+63))
+	setLearnedOn(varnam, "മലയാളം", time.Now().Unix()-int64(halfLife))
+
+	checkError(varnam.Learn("മലയാളത്തിൽ", 0))
+
+	changed, err := varnam.CompactLearnings()
+	checkError(err)
+	assertEqual(t, changed, 1)
+
+	info, err := varnam.getWordInfo("മലയാളം")
+	checkError(err)
+	assertEqual(t, info.weight, VARNAM_LEARNT_WORD_MIN_WEIGHT+32)
+
+	info, err = varnam.getWordInfo("മലയാളത്തിൽ")
+	checkError(err)
+	assertEqual(t, info.weight, VARNAM_LEARNT_WORD_MIN_WEIGHT)
+
+	// Not decayed again
+	sugs := varnam.GetSuggestions(ctx, "മലയാള")
+	assertEqual(t, sugs[0].Word, "മലയാളം")
+	assertEqual(t, sugs[0].Weight, VARNAM_LEARNT_WORD_MIN_WEIGHT+32)
+
+	// Time of compaction is kept in dictionary
+	compactedOn := atomic.LoadInt64(&varnam.compactedOn)
+	assertEqual(t, compactedOn != 0, true)
+
+	varnam2 := makeTestInstance(t, "ml", dir)
+	assertEqual(t, atomic.LoadInt64(&varnam2.compactedOn), compactedOn)
+}
+
+func TestLearnAgainDecays(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+
+	halfLife := 100000
+	varnam.LearningHalfLife = halfLife
+
+	// Used a lot a half-life ago, learnt again now
+	checkError(varnam.Learn("മലയാളം", VARNAM_LEARNT_WORD_MIN_WEIGHT+63))
+	setLearnedOn(varnam, "മലയാളം", time.Now().Unix()-int64(halfLife))
+	checkError(varnam.Learn("മലയാളം", 0))
+
+	info, err := varnam.getWordInfo("മലയാളം")
+	checkError(err)
+	assertEqual(t, info.weight, VARNAM_LEARNT_WORD_MIN_WEIGHT+33)
+
+	setLearnedOn(varnam, "മലയാളം", time.Now().Unix()-2*int64(halfLife))
+	_, err = varnam.LearnMany([]WordInfo{{word: "മലയാളം"}})
+	checkError(err)
+
+	info, err = varnam.getWordInfo("മലയാളം")
+	checkError(err)
+	assertEqual(t, info.weight, VARNAM_LEARNT_WORD_MIN_WEIGHT+9)
+
+	// Without half-life, weight only grows
+	varnam.LearningHalfLife = 0
+	setLearnedOn(varnam, "മലയാളം", time.Now().Unix()-int64(halfLife))
+	checkError(varnam.Learn("മലയാളം", 0))
+
+	info, err = varnam.getWordInfo("മലയാളം")
+	checkError(err)
+	assertEqual(t, info.weight, VARNAM_LEARNT_WORD_MIN_WEIGHT+10)
+}
@@ -8,13 +8,16 @@ package govarnam
 
 import (
 	"context"
+	"database/sql"
 	"embed"
 	"fmt"
 	"io/fs"
 	"log"
 	"os"
 	"path"
+	"sort"
 	"time"
+	"unicode/utf8"
 )
 
 //go:embed migrations/*.sql
@@ -51,10 +54,23 @@ type searchDictionaryResult struct {
 	word      string
 	weight    int
 	learnedOn int
+
+	// Dictionary layer it's from, see layers.go
+	layer int
+}
+
+// Open a dictionary. Transactions take the write lock when they
+// begin, so that another instance or process using the file
+// can't make them fail midway with "database is locked".
+func openDictionary(dictPath string, layers ...string) (*sql.DB, error) {
+	return openDB(dictPath+"?_txlock=immediate", layers...)
 }
 
 // InitDict open connection to dictionary
 func (varnam *Varnam) InitDict(dictPath string) error {
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
 	var err error
 
 	if !fileExists(dictPath) {
@@ -65,20 +81,36 @@ func (varnam *Varnam) InitDict(dictPath string) error {
 		}
 	}
 
-	varnam.dictConn, err = openDB(dictPath)
+	varnam.closeDictVersionConn()
+	varnam.dictConn, err = openDictionary(dictPath, varnam.layers...)
 	if err != nil {
 		return err
 	}
 
 	varnam.DictPath = dictPath
 
+	err = migrateDictionary(varnam.dictConn)
+	if err == nil {
+		err = varnam.loadCompactedOn()
+	}
+
+	// Since SQLite v3.12.0, default page size is 4096
+	varnam.dictConn.Exec("PRAGMA page_size=4096;")
+	// WAL makes writes & reads happen concurrently => significantly fast
+	varnam.dictConn.Exec("PRAGMA journal_mode=wal;")
+
+	return err
+}
+
+// Make or update tables of a dictionary
+func migrateDictionary(conn *sql.DB) error {
 	// cd into migrations directory
 	migrationsFS, err := fs.Sub(embedFS, "migrations")
 	if err != nil {
 		return err
 	}
 
-	mg, err := InitMigrate(varnam.dictConn, migrationsFS)
+	mg, err := InitMigrate(conn, migrationsFS)
 	if err != nil {
 		return err
 	}
@@ -88,18 +120,20 @@ func (varnam *Varnam) InitDict(dictPath string) error {
 		log.Printf("ran %d migrations", ranMigrations)
 	}
 
-	// Since SQLite v3.12.0, default page size is 4096
-	varnam.dictConn.Exec("PRAGMA page_size=4096;")
-	// WAL makes writes & reads happen concurrently => significantly fast
-	varnam.dictConn.Exec("PRAGMA journal_mode=wal;")
-
 	return err
 }
 
 // ReIndexDictionary re-indexes dictionary
 func (varnam *Varnam) ReIndexDictionary() error {
+	varnam.lockDict()
+	defer varnam.unlockDict()
+
 	_, err := varnam.dictConn.Exec("INSERT INTO words_fts(words_fts) VALUES('rebuild');")
-	return err
+	if err != nil {
+		return err
+	}
+
+	return varnam.reIndexPhoneticKeys(context.Background())
 }
 
 type searchDictionaryType int32
@@ -158,7 +192,7 @@ func (varnam *Varnam) searchDictionary(ctx context.Context, words []string, sear
 					w.word AS word,
 					MAX(w.weight),
 					MAX(w.learned_on)
-				FROM words_fts w
+				FROM {layer}.words_fts w
 				INNER JOIN cte c
 					ON w.word MATCH c.match || '*'
 				GROUP BY c.match
@@ -169,7 +203,7 @@ func (varnam *Varnam) searchDictionary(ctx context.Context, words []string, sear
 				SELECT
 					SUBSTR(c.match, 2, LENGTH(c.match) - 1) AS match,
 					w.*
-				FROM words_fts w
+				FROM {layer}.words_fts w
 				INNER JOIN cte c
 					ON w.word MATCH c.match || '*'
 					AND w.word != c.match
@@ -177,10 +211,10 @@ func (varnam *Varnam) searchDictionary(ctx context.Context, words []string, sear
 				`
 			vals = append(vals, varnam.DictionarySuggestionsLimit)
 		} else if searchType == searchExactWords {
-			query = "SELECT * FROM words WHERE word IN ((?) " + likes + ")"
+			query = "SELECT word AS match, word, weight, learned_on FROM {layer}.words WHERE word IN ((?) " + likes + ")"
 		}
 
-		rows, err := varnam.dictConn.QueryContext(ctx, query, vals...)
+		rows, err := varnam.queryLayers(ctx, query, vals...)
 
 		if err != nil {
 			log.Print(err)
@@ -191,7 +225,7 @@ func (varnam *Varnam) searchDictionary(ctx context.Context, words []string, sear
 
 		for rows.Next() {
 			var item searchDictionaryResult
-			rows.Scan(&item.match, &item.word, &item.weight, &item.learnedOn)
+			rows.Scan(&item.match, &item.word, &item.weight, &item.learnedOn, &item.layer)
 			results = append(results, item)
 		}
 
@@ -201,11 +235,56 @@ func (varnam *Varnam) searchDictionary(ctx context.Context, words []string, sear
 			return results
 		}
 
+		if len(varnam.layers) > 0 {
+			results = varnam.combineLayerResults(results)
+
+			if searchType == searchMatches {
+				results = getBestLayerMatches(results)
+			} else if searchType == searchStartingWith {
+				results = sortLayerResults(results, varnam.DictionarySuggestionsLimit)
+			}
+		}
+
 		return results
 	}
 }
 
-func (varnam *Varnam) getFromDictionary(ctx context.Context, tokensPointer *[]Token) DictionaryResult {
+// State of searching dictionary token by token.
+// See getFromDictionary()
+type dictionaryWalk struct {
+	// This is a temporary storage for words made from tokens,
+	// which will be searched in dictionary.
+	// Similar to 'result' usage in tokenizeWord
+	tokenizedWords []searchDictionaryResult
+
+	// We search in dictionary by going through each token,
+	// these would store the last found results
+	lastFoundDictWords []searchDictionaryResult
+	lastFoundPosition  int
+}
+
+// tokenizedWords is modified while searching, so cached walks
+// are copied. After the first token, lastFoundDictWords shares
+// the array of tokenizedWords and sees its changes. The copy
+// keeps that sharing & the capacity, so that a walk continued
+// from cache is same as a walk from the beginning.
+func (walk dictionaryWalk) copy() dictionaryWalk {
+	shared := len(walk.lastFoundDictWords) > 0 &&
+		len(walk.tokenizedWords) > 0 &&
+		&walk.lastFoundDictWords[0] == &walk.tokenizedWords[0]
+
+	tokenizedWords := make([]searchDictionaryResult, len(walk.tokenizedWords), cap(walk.tokenizedWords))
+	copy(tokenizedWords, walk.tokenizedWords)
+	walk.tokenizedWords = tokenizedWords
+
+	if shared {
+		walk.lastFoundDictWords = tokenizedWords[:len(walk.lastFoundDictWords)]
+	}
+
+	return walk
+}
+
+func (varnam *Varnam) getFromDictionary(ctx context.Context, cache *transliterationCache, tokensPointer *[]Token) DictionaryResult {
 	var result DictionaryResult
 	tokens := *tokensPointer
 
@@ -213,17 +292,14 @@ func (varnam *Varnam) getFromDictionary(ctx context.Context, tokensPointer *[]To
 	case <-ctx.Done():
 		return result
 	default:
-		// This is a temporary storage for words made from tokens,
-		// which will be searched in dictionary.
-		// Similar to 'result' usage in tokenizeWord
-		var tokenizedWords []searchDictionaryResult
+		generation := cache.getGeneration()
+
+		// Continue from the search of a prefix of tokens
+		walk, walkedTokens := cache.getDictionaryWalk(tokens)
 
-		// We search in dictionary by going through each token,
-		// these vars would store the last found results
-		var lastFoundDictWords []searchDictionaryResult
-		var lastFoundPosition = 0
+		for i := walkedTokens; i < len(tokens); i++ {
+			t := tokens[i]
 
-		for i, t := range tokens {
 			var tempFoundDictWords []searchDictionaryResult
 			if t.tokenType == VARNAM_TOKEN_SYMBOL {
 				if i == 0 {
@@ -241,7 +317,7 @@ func (varnam *Varnam) getFromDictionary(ctx context.Context, tokensPointer *[]To
 					)
 
 					tempFoundDictWords = searchResults
-					tokenizedWords = searchResults
+					walk.tokenizedWords = searchResults
 
 					if LOG_TIME_TAKEN {
 						log.Printf(
@@ -252,12 +328,12 @@ func (varnam *Varnam) getFromDictionary(ctx context.Context, tokensPointer *[]To
 					}
 				} else {
 					start := time.Now()
-					for j := range tokenizedWords {
-						if tokenizedWords[j].weight == -1 {
+					for j := range walk.tokenizedWords {
+						if walk.tokenizedWords[j].weight == -1 {
 							continue
 						}
 
-						till := tokenizedWords[j].match
+						till := walk.tokenizedWords[j].match
 
 						var toSearch []string
 
@@ -277,7 +353,7 @@ func (varnam *Varnam) getFromDictionary(ctx context.Context, tokensPointer *[]To
 
 							for k := range searchResults {
 								if k == 0 {
-									tokenizedWords[j].match = searchResults[k].match
+									walk.tokenizedWords[j].match = searchResults[k].match
 									continue
 								}
 
@@ -286,13 +362,14 @@ func (varnam *Varnam) getFromDictionary(ctx context.Context, tokensPointer *[]To
 									searchResults[k].word,
 									0,
 									0,
+									0,
 								}
-								tokenizedWords = append(tokenizedWords, sug)
+								walk.tokenizedWords = append(walk.tokenizedWords, sug)
 							}
 						} else {
 							// No need of processing this word anymore, we found no match in dictionary.
 							// weight is used as a flag here to skip processing this further.
-							tokenizedWords[j].weight = -1
+							walk.tokenizedWords[j].weight = -1
 						}
 					}
 					if LOG_TIME_TAKEN {
@@ -301,18 +378,25 @@ func (varnam *Varnam) getFromDictionary(ctx context.Context, tokensPointer *[]To
 				}
 			}
 			if len(tempFoundDictWords) > 0 {
-				lastFoundDictWords = tempFoundDictWords
-				lastFoundPosition = t.position
+				walk.lastFoundDictWords = tempFoundDictWords
+				walk.lastFoundPosition = t.position
 			}
+
+			// A cancelled search has incomplete results
+			if ctx.Err() != nil {
+				return result
+			}
+
+			cache.addDictionaryWalk(tokens[:i+1], walk, generation)
 		}
 
-		if lastFoundPosition == tokens[len(tokens)-1].position {
-			result.exactMatches = convertSearchDictResultToSuggestion(lastFoundDictWords, false)
+		if walk.lastFoundPosition == tokens[len(tokens)-1].position {
+			result.exactMatches = varnam.convertSearchDictResultToSuggestion(walk.lastFoundDictWords, false)
 		} else {
-			result.partialMatches = convertSearchDictResultToSuggestion(lastFoundDictWords, false)
+			result.partialMatches = varnam.convertSearchDictResultToSuggestion(walk.lastFoundDictWords, false)
 		}
 
-		result.longestMatchPosition = lastFoundPosition
+		result.longestMatchPosition = walk.lastFoundPosition
 
 		return result
 	}
@@ -333,14 +417,14 @@ func (varnam *Varnam) getMoreFromDictionary(ctx context.Context, words []Suggest
 			search := []string{words[i].Word}
 			result.moreSuggestions = append(
 				result.moreSuggestions,
-				convertSearchDictResultToSuggestion(
+				varnam.convertSearchDictResultToSuggestion(
 					varnam.searchDictionary(ctx, search, searchStartingWith),
 					true,
 				),
 			)
 		}
 
-		result.exactWords = convertSearchDictResultToSuggestion(
+		result.exactWords = varnam.convertSearchDictResultToSuggestion(
 			varnam.searchDictionary(ctx, wordsToSearch, searchExactWords),
 			true,
 		)
@@ -358,7 +442,7 @@ func (varnam *Varnam) getFromPatternDictionary(ctx context.Context, pattern stri
 	case <-ctx.Done():
 		return results
 	default:
-		rows, err := varnam.dictConn.QueryContext(ctx, "SELECT LENGTH(pts.pattern), w.word, w.weight, w.learned_on FROM `patterns` pts LEFT JOIN words w ON w.id = pts.word_id WHERE ? LIKE (pts.pattern || '%') OR pattern LIKE ? ORDER BY LENGTH(pts.pattern) DESC LIMIT ?", pattern, pattern+"%", varnam.PatternDictionarySuggestionsLimit)
+		rows, err := varnam.queryLayers(ctx, "SELECT pts.pattern, w.word, w.weight, w.learned_on FROM {layer}.`patterns` pts LEFT JOIN {layer}.words w ON w.id = pts.word_id WHERE ? LIKE (pts.pattern || '%') OR pattern LIKE ? ORDER BY LENGTH(pts.pattern) DESC LIMIT ?", pattern, pattern+"%", varnam.PatternDictionarySuggestionsLimit)
 
 		if err != nil {
 			log.Print(err)
@@ -367,11 +451,12 @@ func (varnam *Varnam) getFromPatternDictionary(ctx context.Context, pattern stri
 
 		defer rows.Close()
 
+		// Pattern is the match
+		var items []searchDictionaryResult
 		for rows.Next() {
-			var item PatternDictionarySuggestion
-			rows.Scan(&item.Length, &item.Sug.Word, &item.Sug.Weight, &item.Sug.LearnedOn)
-			item.Sug.Weight += VARNAM_LEARNT_WORD_MIN_WEIGHT
-			results = append(results, item)
+			var item searchDictionaryResult
+			rows.Scan(&item.match, &item.word, &item.weight, &item.learnedOn, &item.layer)
+			items = append(items, item)
 		}
 
 		err = rows.Err()
@@ -379,12 +464,36 @@ func (varnam *Varnam) getFromPatternDictionary(ctx context.Context, pattern stri
 			log.Print(err)
 		}
 
+		if len(varnam.layers) > 0 {
+			items = varnam.combineLayerResults(items)
+
+			sort.SliceStable(items, func(i, j int) bool {
+				return utf8.RuneCountInString(items[i].match) > utf8.RuneCountInString(items[j].match)
+			})
+			if len(items) > varnam.PatternDictionarySuggestionsLimit {
+				items = items[:varnam.PatternDictionarySuggestionsLimit]
+			}
+		}
+
+		for _, item := range items {
+			var result PatternDictionarySuggestion
+			result.Length = utf8.RuneCountInString(item.match)
+
+			result.Sug = varnam.makeLearnedSuggestion(item.word, item.weight, item.learnedOn)
+			result.Sug.Weight += VARNAM_LEARNT_WORD_MIN_WEIGHT
+			varnam.addScore(&result.Sug, ScoreBreakdown{LearnedWeight: VARNAM_LEARNT_WORD_MIN_WEIGHT})
+			results = append(results, result)
+		}
+
 		return results
 	}
 }
 
 // GetRecentlyLearntWords get recently learnt words
 func (varnam *Varnam) GetRecentlyLearntWords(ctx context.Context, offset int, limit int) ([]Suggestion, error) {
+	varnam.mutex.RLock()
+	defer varnam.mutex.RUnlock()
+
 	var result []Suggestion
 
 	select {
@@ -416,27 +525,30 @@ func (varnam *Varnam) GetRecentlyLearntWords(ctx context.Context, offset int, li
 
 // GetSuggestions get word suggestions from dictionary
 func (varnam *Varnam) GetSuggestions(ctx context.Context, word string) []Suggestion {
+	varnam.mutex.RLock()
+	defer varnam.mutex.RUnlock()
+
 	var sugs []Suggestion
 
 	select {
 	case <-ctx.Done():
 		return sugs
 	default:
-		return convertSearchDictResultToSuggestion(
+		return varnam.convertSearchDictResultToSuggestion(
 			varnam.searchDictionary(ctx, []string{word}, searchStartingWith),
 			true,
 		)
 	}
 }
 
-func convertSearchDictResultToSuggestion(searchResults []searchDictionaryResult, word bool) []Suggestion {
+func (varnam *Varnam) convertSearchDictResultToSuggestion(searchResults []searchDictionaryResult, word bool) []Suggestion {
 	var sugs []Suggestion
 	for i := range searchResults {
-		sug := Suggestion{
+		sug := varnam.makeLearnedSuggestion(
 			searchResults[i].match,
 			searchResults[i].weight,
 			searchResults[i].learnedOn,
-		}
+		)
 		if word {
 			sug.Word = searchResults[i].word
 		}
@@ -0,0 +1,128 @@
+/*
+Package govarnam is an Indian language transliteration library.
+
+# Concurrency
+
+A *Varnam is safe for concurrent use by multiple goroutines, and
+different instances can be used in parallel, even when they share
+the same learnings file (like "ml" and "ml-inscript" schemes).
+
+Methods that only read can run parallelly:
+
+	Transliterate, TransliterateAdvanced, TransliterateWithContext,
+	TransliterateAdvancedWithContext, TransliterateWithPreviousWords,
+	TransliterateAdvancedWithPreviousWords, TransliterateText,
+	TransliterateGreedyTokenized, ReverseTransliterate,
+	GetSuggestions, GetRecentlyLearntWords, PredictNextWords,
+	SearchSymbolTable, Export, ExportWithProgress, CacheStats,
+	Session.Suggestions, ListProfiles, CreateProfile, DeleteProfile,
+	GetAttachedDictionaries
+
+Methods that write to the dictionary run one at a time on an
+instance, but don't block the above:
+
+	Learn, Unlearn, LearnMany, LearnFromFile, Train, TrainFromFile,
+	Import, ImportWithProgress, MergeLearnings, ReIndexDictionary,
+	CompactLearnings
+
+Methods that change VST or connections wait for all other
+methods to finish and block them till done:
+
+	InitVST, InitDict, RegisterPatternWordPartializer, EnableCache,
+	SetBaseProfile, AttachDictionary, DetachDictionary, Close,
+	VMCreateToken, VMDeleteToken, VMSetSchemeDetails, VMFlushBuffer,
+	VMCreateStemRule, VMDeleteStemRule, VMCreateStemException,
+	VMDeleteStemException
+
+The exported config fields of Varnam (Debug, LangRules,
+DictionarySuggestionsLimit etc.) are not guarded. Set them right
+after Init and before using the instance from multiple goroutines.
+
+Package level functions are safe for concurrent use. SetVSTLookupDir
+and SetLearningsDir change the defaults for instances made after the
+call, use InitFromIDWithDirs to set them for one instance.
+
+# Cache
+
+With EnableCache, transliteration results are cached. Tokens and
+dictionary search results of a word are reused when transliterating
+a longer word starting with it, like when typing in an IME. Results
+are dropped when the dictionary changes with Learn, Unlearn, Train,
+Import etc., also when it's done by another instance or process using
+the same learnings file. With Varnam.LearningHalfLife set, results are
+kept for at most a 100th of the half-life as weights decay with time.
+CacheStats gives the hit & miss counters.
+
+# Ranking
+
+Transliterate orders the lists of TransliterateAdvanced's result into
+one list with Varnam.Ranker. DefaultRanker keeps the order by source,
+WeightedRanker orders by weight, counting a percentage of it for each
+source, with a bonus for recently learnt words. A word is given only
+once, from the list where it ranked first.
+
+Weight of a learnt word grows by one every time it's learnt. With
+Varnam.LearningHalfLife set, the weight above the minimum halves for
+every half-life since the word was last learnt, so words used a lot
+long ago don't stay above words used now. Decay is applied when
+searching, CompactLearnings stores the decayed weights.
+
+When no dictionary word is found for an input, it may have a typo.
+With Varnam.FuzzyMatchMaxEdits set, inputs with a letter inserted,
+deleted, replaced or swapped are searched too, at most
+FuzzyMatchCostLimit of them. Words found are in DictionarySuggestions,
+their weight divided by the number of edits + 1.
+
+With Varnam.PhoneticMatch set, learnt words that sound like the
+input are suggested too, in DictionarySuggestions. A phonetic key,
+the latin spelling of a word with aspiration, vowel lengths and
+letters of the same sound made one, is kept for every learnt word.
+Words learnt before it was added get their keys with
+ReIndexDictionary.
+
+# Profiles
+
+A language can have more than one learnings dictionary, called
+profiles. InitFromIDWithProfile uses a profile, InitFromID uses
+VARNAM_DEFAULT_PROFILE. ListProfiles, CreateProfile & DeleteProfile
+manage the profiles of a language. With SetBaseProfile, words of
+another profile are suggested too. It's attached read only like
+AttachDictionary below, words are learnt to the profile in use.
+
+# Dictionary Layers
+
+AttachDictionary attaches a read only dictionary, like a curated
+word list shared by a team, to the one in use. Dictionary and
+pattern dictionary searches go through all of them, the one in use
+first and then the attached ones in order. A word in more than one
+is suggested once, its weight combined by Varnam.LayerWeightPolicy:
+the weight in the first one (VARNAM_LAYER_WEIGHT_OVERRIDE, default),
+the maximum or the sum. Words are learnt only to the dictionary in
+use. Unlearning a word hides it in attached dictionaries too, till
+it's learnt again.
+
+# Sharing Learnings
+
+Export writes learnings to .vlf JSON files and Import adds words of
+them that aren't there, in one transaction. Both go through files an
+item at a time, the WithProgress variants take a function called
+with counts of words & patterns done.
+
+MergeLearnings merges another dictionary or a .vlf export word by
+word: a word in both gets the later learnt time and the maximum or
+sum of weights (VARNAM_MERGE_WEIGHT_*). Unlearning a word or pattern
+leaves a tombstone with the time, and merging removes it from the
+other side if it was learnt there before that time. Exports carry
+tombstones. With dryRun set, a MergeReport of what would change is
+given and nothing is changed.
+
+# Session
+
+NewSession starts a typing session for an IME. Characters are given
+one at a time with Append & Backspace, and Suggestions transliterates
+what's typed so far, continuing from the tokens & dictionary search
+of the previous input. A session has its own cache and works even
+without EnableCache. Session methods are safe for concurrent use, and
+many sessions of an instance can be used in parallel.
+*/
+package govarnam
@@ -0,0 +1,310 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+/**
+ * Learnings export files (.vlf). A file is a JSON object:
+ *
+ * {"words": [{"w": word, "c": weight, "l": learned_on}, ...],
+ *  "patterns": [{"p": pattern, "w": word}, ...],
+ *  "unlearned_words": [{"w": word, "u": unlearned_on}, ...],
+ *  "unlearned_patterns": [{"p": pattern, "u": unlearned_on}, ...]}
+ *
+ * Tombstones (unlearned_*) are only in the first file of an export.
+ * Files are read & written an item at a time, so that big
+ * dictionaries don't have to be in memory.
+ */
+
+import (
+	"bufio"
+	"context"
+	sql "database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type exportWord struct {
+	Word      string `json:"w"`
+	Weight    int    `json:"c"`
+	LearnedOn *int64 `json:"l"`
+}
+
+type exportPattern struct {
+	Pattern string `json:"p"`
+	Word    string `json:"w"`
+}
+
+type exportUnlearnedWord struct {
+	Word        string `json:"w"`
+	UnlearnedOn int    `json:"u"`
+}
+
+type exportUnlearnedPattern struct {
+	Pattern     string `json:"p"`
+	UnlearnedOn int    `json:"u"`
+}
+
+// Functions called for each item read from an export
+// file. Items of a nil function are skipped.
+type exportFileReader struct {
+	onWord             func(item exportWord) error
+	onPattern          func(item exportPattern) error
+	onUnlearnedWord    func(item exportUnlearnedWord) error
+	onUnlearnedPattern func(item exportUnlearnedPattern) error
+}
+
+func exportParseError(err error) error {
+	return fmt.Errorf("Parsing JSON failed, err: %s", err.Error())
+}
+
+// Read items of an array one by one. null is an empty array.
+func readExportArray(decoder *json.Decoder, readItem func() error) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return exportParseError(err)
+	}
+	if token == nil {
+		return nil
+	}
+	if token != json.Delim('[') {
+		return exportParseError(fmt.Errorf("expected an array, got %v", token))
+	}
+
+	for decoder.More() {
+		err = readItem()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Closing ]
+	if _, err = decoder.Token(); err != nil {
+		return exportParseError(err)
+	}
+	return nil
+}
+
+func (reader exportFileReader) readArray(decoder *json.Decoder, key string) error {
+	switch {
+	case key == "words" && reader.onWord != nil:
+		return readExportArray(decoder, func() error {
+			var item exportWord
+			if err := decoder.Decode(&item); err != nil {
+				return exportParseError(err)
+			}
+			return reader.onWord(item)
+		})
+	case key == "patterns" && reader.onPattern != nil:
+		return readExportArray(decoder, func() error {
+			var item exportPattern
+			if err := decoder.Decode(&item); err != nil {
+				return exportParseError(err)
+			}
+			return reader.onPattern(item)
+		})
+	case key == "unlearned_words" && reader.onUnlearnedWord != nil:
+		return readExportArray(decoder, func() error {
+			var item exportUnlearnedWord
+			if err := decoder.Decode(&item); err != nil {
+				return exportParseError(err)
+			}
+			return reader.onUnlearnedWord(item)
+		})
+	case key == "unlearned_patterns" && reader.onUnlearnedPattern != nil:
+		return readExportArray(decoder, func() error {
+			var item exportUnlearnedPattern
+			if err := decoder.Decode(&item); err != nil {
+				return exportParseError(err)
+			}
+			return reader.onUnlearnedPattern(item)
+		})
+	}
+
+	return readExportArray(decoder, func() error {
+		var item json.RawMessage
+		if err := decoder.Decode(&item); err != nil {
+			return exportParseError(err)
+		}
+		return nil
+	})
+}
+
+// Read an export file. Items are given in the order they're in
+// file, words before patterns in files made by Export().
+func (reader exportFileReader) read(filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+
+	token, err := decoder.Token()
+	if err != nil {
+		return exportParseError(err)
+	}
+	if token != json.Delim('{') {
+		return exportParseError(fmt.Errorf("expected an object, got %v", token))
+	}
+
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return exportParseError(err)
+		}
+
+		key, _ := token.(string)
+		if err = reader.readArray(decoder, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Writes an export file an item at a time
+type exportFileWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+
+	arrays int // Arrays started
+	items  int // Items in the array being written
+}
+
+func createExportFile(filePath string) (*exportFileWriter, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &exportFileWriter{
+		file:   file,
+		writer: bufio.NewWriter(file),
+	}, nil
+}
+
+func (w *exportFileWriter) beginArray(key string) error {
+	separator := ","
+	if w.arrays == 0 {
+		separator = "{"
+	}
+	w.arrays++
+	w.items = 0
+
+	keyJSON, _ := json.Marshal(key)
+	_, err := w.writer.WriteString(separator + string(keyJSON) + ":[")
+	return err
+}
+
+func (w *exportFileWriter) writeItem(item interface{}) error {
+	itemJSON, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	if w.items > 0 {
+		if err = w.writer.WriteByte(','); err != nil {
+			return err
+		}
+	}
+	w.items++
+
+	_, err = w.writer.Write(itemJSON)
+	return err
+}
+
+func (w *exportFileWriter) endArray() error {
+	return w.writer.WriteByte(']')
+}
+
+// Write a whole array, item(i) gives the ith item
+func (w *exportFileWriter) writeArray(key string, count int, item func(i int) interface{}) error {
+	err := w.beginArray(key)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < count; i++ {
+		if err = w.writeItem(item(i)); err != nil {
+			return err
+		}
+	}
+
+	return w.endArray()
+}
+
+// Finish the file. The writer can't be used after this.
+func (w *exportFileWriter) close() error {
+	if w.arrays == 0 {
+		w.writer.WriteByte('{')
+	}
+	w.writer.WriteByte('}')
+
+	err := w.writer.Flush()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Inserts rows in batches, a multi-row INSERT for each batch
+type insertBatch struct {
+	tx    *sql.Tx
+	query string // With %s in place of VALUES
+	value string // VALUES of a row
+	size  int    // Rows in a batch
+
+	// Called with number of rows after inserting a batch
+	flushed func(count int)
+
+	// Rows of this are inserted before a batch, for
+	// when a batch refers to rows of it
+	dependsOn *insertBatch
+
+	values []string
+	args   []interface{}
+}
+
+func (batch *insertBatch) add(ctx context.Context, args ...interface{}) error {
+	batch.values = append(batch.values, batch.value)
+	batch.args = append(batch.args, args...)
+
+	if len(batch.values) >= batch.size {
+		return batch.flush(ctx)
+	}
+	return nil
+}
+
+// Insert rows added till now
+func (batch *insertBatch) flush(ctx context.Context) error {
+	count := len(batch.values)
+	if count == 0 {
+		return nil
+	}
+
+	if batch.dependsOn != nil {
+		if err := batch.dependsOn.flush(ctx); err != nil {
+			return err
+		}
+	}
+
+	_, err := batch.tx.ExecContext(ctx, fmt.Sprintf(batch.query, strings.Join(batch.values, ", ")), batch.args...)
+	if err != nil {
+		return err
+	}
+
+	batch.values = nil
+	batch.args = nil
+
+	if batch.flushed != nil {
+		batch.flushed(count)
+	}
+	return nil
+}
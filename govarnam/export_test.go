@@ -0,0 +1,80 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestExportImportProgress(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+
+	checkError(varnam.Learn("വയൽ", 0))
+	checkError(varnam.Learn("തത്ത", 0))
+
+	// More patterns than words
+	checkError(varnam.Train("field", "മലയാളം"))
+	checkError(varnam.Train("paddy", "മലയാളം"))
+	checkError(varnam.Train("meadow", "മലയാളം"))
+
+	exportPath := path.Join(t.TempDir(), "export")
+
+	assertEqual(t, varnam.Export(exportPath, 0) != nil, true)
+
+	var exported []LearningsProgress
+	checkError(varnam.ExportWithProgress(exportPath, 2, func(progress LearningsProgress) {
+		exported = append(exported, progress)
+	}))
+
+	assertEqual(t, len(exported), 2)
+	assertEqual(t, exported[1], LearningsProgress{Words: 3, Patterns: 3})
+	assertEqual(t, fileExists(exportPath+"-2.vlf"), true)
+	assertEqual(t, fileExists(exportPath+"-3.vlf"), false)
+
+	fresh := makeTestInstance(t, "ml", t.TempDir())
+
+	var imported LearningsProgress
+	for _, file := range []string{exportPath + "-1.vlf", exportPath + "-2.vlf"} {
+		var last LearningsProgress
+		checkError(fresh.ImportWithProgress(file, func(progress LearningsProgress) {
+			last = progress
+		}))
+
+		imported.Words += last.Words
+		imported.Patterns += last.Patterns
+	}
+	assertEqual(t, imported, LearningsProgress{Words: 3, Patterns: 3})
+
+	for _, word := range []string{"വയൽ", "തത്ത", "മലയാളം"} {
+		assertEqual(t, getLayerWeight(t, fresh, word), getLayerWeight(t, varnam, word))
+	}
+
+	// Phonetic keys are made in the same transaction
+	countPhoneticKeys := func(varnam *Varnam) int {
+		var count int
+		checkError(varnam.dictConn.QueryRow("SELECT COUNT(*) FROM word_phonetic_keys").Scan(&count))
+		return count
+	}
+	assertEqual(t, countPhoneticKeys(fresh) > 0, true)
+	assertEqual(t, countPhoneticKeys(fresh), countPhoneticKeys(varnam))
+
+	for _, pattern := range []string{"field", "paddy", "meadow"} {
+		sugs := fresh.getFromPatternDictionary(context.Background(), pattern)
+		assertEqual(t, len(sugs), 1)
+		assertEqual(t, sugs[0].Sug.Word, "മലയാളം")
+	}
+
+	// Nothing is imported from a broken file
+	brokenPath := path.Join(t.TempDir(), "broken.vlf")
+	checkError(os.WriteFile(brokenPath, []byte(`{"words": [{"w": "ഇല", "c": 1, "l": 1}, {"w": `), 0644))
+
+	assertEqual(t, fresh.Import(brokenPath) != nil, true)
+	assertEqual(t, len(fresh.searchDictionary(context.Background(), []string{"ഇല"}, searchExactWords)), 0)
+}
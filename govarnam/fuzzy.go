@@ -0,0 +1,259 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+/**
+ * Fuzzy dictionary matching. When no dictionary word has the
+ * tokens of input, the input may have a typo. Inputs made with
+ * an edit (insertion, deletion, substitution or transposition of
+ * a letter) are searched in dictionary, level by level till
+ * Varnam.FuzzyMatchMaxEdits edits.
+ *
+ * Dictionary search walks tokens from the start, and stops at the
+ * first token with no words. An edit after that token can't make
+ * it match, so only edits till there are tried. A typo is usually
+ * at or a little before the failed token, edits are tried from
+ * there to the start of input first.
+ */
+
+import (
+	"context"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Maximum edits tried with fuzzy matching
+const fuzzyMatchMaxEdits = 2
+
+// An input made by editing the typed input
+type fuzzyInput struct {
+	runes []rune
+
+	// Dictionary search result of input
+	tokens     []Token
+	dictResult DictionaryResult
+}
+
+// Letters of VST patterns that can be used in edits
+func (varnam *Varnam) getFuzzyAlphabet() []rune {
+	var alphabet []rune
+	for r := range varnam.patternRunes {
+		if r < utf8.RuneSelf && unicode.IsLetter(r) {
+			alphabet = append(alphabet, r)
+		}
+	}
+	sort.Slice(alphabet, func(i, j int) bool {
+		return alphabet[i] < alphabet[j]
+	})
+	return alphabet
+}
+
+// Typos are looked for only in latin input
+func isLatinWord(word string) bool {
+	for _, r := range word {
+		if r >= utf8.RuneSelf || !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return word != ""
+}
+
+// Get the position in input where the dictionary search failed,
+// and the position till which an edit can make the search go
+// further. Tokenizer looks ahead PatternLongestLength characters,
+// so an edit till that far from the failed token can change it.
+func (varnam *Varnam) getFuzzyEditRange(input fuzzyInput) (int, int) {
+	failed := len(input.runes)
+
+	for _, token := range input.tokens {
+		if token.tokenType != VARNAM_TOKEN_SYMBOL {
+			continue
+		}
+		if len(input.dictResult.partialMatches) == 0 || token.position > input.dictResult.longestMatchPosition {
+			failed = token.position
+			break
+		}
+	}
+
+	limit := failed + varnam.LangRules.PatternLongestLength
+	if limit > len(input.runes) {
+		limit = len(input.runes)
+	}
+
+	return failed, limit
+}
+
+// Inputs made with one edit on input. Edits from failed to the
+// start come first, then the ones after failed till limit.
+func getFuzzyEdits(runes []rune, failed int, limit int, alphabet []rune) [][]rune {
+	var (
+		results [][]rune
+		length  = len(runes)
+	)
+
+	var positions []int
+	for i := failed; i >= 0; i-- {
+		positions = append(positions, i)
+	}
+	for i := failed + 1; i <= limit; i++ {
+		positions = append(positions, i)
+	}
+
+	edit := func(before []rune, middle []rune, after []rune) {
+		result := make([]rune, 0, len(before)+len(middle)+len(after))
+		result = append(result, before...)
+		result = append(result, middle...)
+		result = append(result, after...)
+		results = append(results, result)
+	}
+
+	for _, i := range positions {
+		if i+1 < length && runes[i] != runes[i+1] {
+			edit(runes[:i], []rune{runes[i+1], runes[i]}, runes[i+2:])
+		}
+
+		if i < length && length > 1 {
+			edit(runes[:i], nil, runes[i+1:])
+		}
+
+		if i < length {
+			for _, r := range alphabet {
+				if r != runes[i] {
+					edit(runes[:i], []rune{r}, runes[i+1:])
+				}
+			}
+		}
+
+		if i <= length {
+			for _, r := range alphabet {
+				edit(runes[:i], []rune{r}, runes[i:])
+			}
+		}
+	}
+
+	return results
+}
+
+// Search dictionary with the tokens of input
+func (varnam *Varnam) searchFuzzyInput(ctx context.Context, cache *transliterationCache, input *fuzzyInput) {
+	tokens := *varnam.tokenizeWord(ctx, cache, string(input.runes), VARNAM_MATCH_ALL, false)
+
+	if varnam.DictionaryMatchExact {
+		exactTokens := make([]Token, len(tokens))
+		copy(exactTokens, tokens)
+		tokens = removeNonExactTokens(exactTokens)
+	}
+
+	input.tokens = tokens
+	if len(tokens) > 0 {
+		input.dictResult = varnam.getFromDictionary(ctx, cache, &tokens)
+	}
+}
+
+// Lower weight of a suggestion by the number of edits made to find it
+func (varnam *Varnam) addEditPenalty(sug *Suggestion, edits int) {
+	weight := sug.Weight / (edits + 1)
+	varnam.addScore(sug, ScoreBreakdown{EditPenalty: weight - sug.Weight})
+	sug.Weight = weight
+}
+
+// Get dictionary words of inputs made by editing the input.
+// tokens & dictResult are of the input. Searches at most
+// Varnam.FuzzyMatchCostLimit inputs.
+func (varnam *Varnam) getFuzzyFromDictionary(ctx context.Context, word string, tokens []Token, dictResult DictionaryResult) []Suggestion {
+	var results []Suggestion
+
+	maxEdits := varnam.FuzzyMatchMaxEdits
+	if maxEdits > fuzzyMatchMaxEdits {
+		maxEdits = fuzzyMatchMaxEdits
+	}
+
+	if maxEdits <= 0 || varnam.FuzzyMatchCostLimit <= 0 || !isLatinWord(word) {
+		return results
+	}
+
+	alphabet := varnam.getFuzzyAlphabet()
+
+	// Edited inputs share prefixes, their
+	// tokens & dictionary walks are reused
+	cache := newTransliterationCache(varnam.FuzzyMatchCostLimit)
+
+	original := fuzzyInput{
+		runes:      []rune(word),
+		tokens:     tokens,
+		dictResult: dictResult,
+	}
+
+	searched := map[string]bool{word: true}
+	cost := 0
+
+	found := map[string]bool{}
+	addResults := func(sugs []Suggestion, edits int) {
+		for _, sug := range sugs {
+			if found[sug.Word] {
+				continue
+			}
+			varnam.addEditPenalty(&sug, edits)
+			found[sug.Word] = true
+			results = append(results, sug)
+		}
+	}
+
+	level := []fuzzyInput{original}
+
+	for edits := 1; edits <= maxEdits && len(level) > 0; edits++ {
+		var nextLevel []fuzzyInput
+
+		for _, input := range level {
+			failed, limit := varnam.getFuzzyEditRange(input)
+
+			for _, runes := range getFuzzyEdits(input.runes, failed, limit, alphabet) {
+				if cost >= varnam.FuzzyMatchCostLimit || ctx.Err() != nil {
+					break
+				}
+
+				key := string(runes)
+				if searched[key] {
+					continue
+				}
+				searched[key] = true
+
+				edited := fuzzyInput{runes: runes}
+				varnam.searchFuzzyInput(ctx, cache, &edited)
+				cost++
+
+				if len(edited.dictResult.exactMatches) > 0 {
+					more := varnam.getMoreFromDictionary(ctx, edited.dictResult.exactMatches)
+
+					addResults(more.exactWords, edits)
+					for _, sugs := range more.moreSuggestions {
+						addResults(sugs, edits)
+					}
+				} else if len(edited.dictResult.partialMatches) > 0 &&
+					(len(input.dictResult.partialMatches) == 0 || edited.dictResult.longestMatchPosition > input.dictResult.longestMatchPosition) {
+					// Search went further, another edit may complete it
+					nextLevel = append(nextLevel, edited)
+				}
+			}
+		}
+
+		// Inputs with less edits are better
+		if len(results) > 0 {
+			break
+		}
+
+		level = nextLevel
+	}
+
+	results = SortSuggestions(results)
+	if len(results) > varnam.DictionarySuggestionsLimit {
+		results = results[:varnam.DictionarySuggestionsLimit]
+	}
+
+	return results
+}
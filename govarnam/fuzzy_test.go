@@ -0,0 +1,92 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetFuzzyEdits(t *testing.T) {
+	var edits []string
+	for _, runes := range getFuzzyEdits([]rune("ab"), 0, 1, []rune("ab")) {
+		edits = append(edits, string(runes))
+	}
+
+	expected := []string{
+		// At 0: transposition, deletion, substitution, insertion
+		"ba", "b", "bb", "aab", "bab",
+		// At 1
+		"a", "aa", "aab", "abb",
+	}
+	assertEqual(t, reflect.DeepEqual(edits, expected), true)
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+	checkError(varnam.Learn("വയൽ", 0))
+
+	hasWord := func(sugs []Suggestion, word string) bool {
+		for _, sug := range sugs {
+			if sug.Word == word {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Disabled by default
+	assertEqual(t, hasWord(varnam.Transliterate("vsyal"), "വയൽ"), false)
+
+	varnam.FuzzyMatchMaxEdits = 1
+	varnam.ExplainSuggestions = true
+
+	// Substitution & transposition
+	for _, input := range []string{"vsyal", "vaayl"} {
+		sugs := varnam.TransliterateAdvanced(input).DictionarySuggestions
+		assertEqual(t, sugs[0].Word, "വയൽ")
+		assertEqual(t, sugs[0].Weight, VARNAM_LEARNT_WORD_MIN_WEIGHT/2)
+		assertEqual(t, sugs[0].Score.EditPenalty, sugs[0].Weight-VARNAM_LEARNT_WORD_MIN_WEIGHT)
+		assertEqual(t, sugs[0].Score.LearnedWeight+sugs[0].Score.EditPenalty, sugs[0].Weight)
+	}
+
+	// Correct input is not penalized
+	sugs := varnam.TransliterateAdvanced("vayal").ExactWords
+	assertEqual(t, sugs[0].Word, "വയൽ")
+	assertEqual(t, sugs[0].Weight, VARNAM_LEARNT_WORD_MIN_WEIGHT)
+
+	// Needs 2 edits
+	assertEqual(t, hasWord(varnam.Transliterate("vsyl"), "വയൽ"), false)
+
+	// Inputs with 2 edits are many more
+	varnam.FuzzyMatchMaxEdits = 2
+	varnam.FuzzyMatchCostLimit = 1000
+	sugs = varnam.TransliterateAdvanced("vsyl").DictionarySuggestions
+	assertEqual(t, sugs[0].Word, "വയൽ")
+	assertEqual(t, sugs[0].Weight, VARNAM_LEARNT_WORD_MIN_WEIGHT/3)
+
+	// Not enough searches allowed
+	varnam.FuzzyMatchCostLimit = 1
+	assertEqual(t, hasWord(varnam.Transliterate("vsyl"), "വയൽ"), false)
+}
+
+// A word found by tokenizing the rest of input
+// after a partial match is not given again
+func TestFuzzyMatchNoDuplicates(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+	checkError(varnam.Learn("അമ്മ", 0))
+	checkError(varnam.Learn("അമമാ", 0))
+
+	varnam.FuzzyMatchMaxEdits = 1
+
+	found := map[string]bool{}
+	for _, sug := range varnam.TransliterateAdvanced("ammaa").DictionarySuggestions {
+		assertEqual(t, found[sug.Word], false)
+		found[sug.Word] = true
+	}
+	assertEqual(t, found["അമമാ"], true)
+}
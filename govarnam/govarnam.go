@@ -13,6 +13,8 @@ import (
 	"log"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 	"unicode/utf8"
@@ -31,12 +33,12 @@ type LangRules struct {
 
 // SchemeDetails of VST
 type SchemeDetails struct {
-	Identifier   string
-	LangCode     string
-	DisplayName  string
-	Author       string
-	CompiledDate string
-	IsStable     bool
+	Identifier   string `json:"identifier"`
+	LangCode     string `json:"lang_code"`
+	DisplayName  string `json:"display_name"`
+	Author       string `json:"author"`
+	CompiledDate string `json:"compiled_date"`
+	IsStable     bool   `json:"is_stable"`
 }
 
 type VSTMakerConfig struct {
@@ -49,12 +51,39 @@ type VSTMakerConfig struct {
 
 // Varnam config
 type Varnam struct {
+	// Number of times dictionary or VST changed. Sessions use it
+	// to know when their state is outdated. Accessed atomically,
+	// kept first for 64-bit alignment on 32-bit platforms.
+	changes uint64
+
+	// Unix time learnings were last compacted, 0 if never.
+	// Accessed atomically. See CompactLearnings()
+	compactedOn int64
+
 	VSTPath  string
 	DictPath string
 
+	// Profile searched along with DictPath, empty
+	// if there's none. See SetBaseProfile()
+	BaseProfile string
+
 	vstConn  *sql.DB
 	dictConn *sql.DB
 
+	// Read only dictionaries attached to dictConn. Searched
+	// after it, in this order. See layers.go
+	layers []string
+
+	// Characters used in VST patterns. See setPatternRunes()
+	patternRunes map[rune]bool
+
+	// VST symbols in memory. See setSymbolTries()
+	patternTrie *symbolTrie
+	valueTrie   *symbolTrie
+
+	// Stem rules in VST. See setStemRules()
+	stemRules []StemRule
+
 	LangRules     LangRules
 	SchemeDetails SchemeDetails
 	Debug         bool
@@ -78,43 +107,157 @@ type Varnam struct {
 	// for dictionary search and discard possibility matches
 	DictionaryMatchExact bool
 
+	// Words learnt with Learn() one after the other within
+	// this many seconds are stored as a sequence (bigram, trigram).
+	// Used for context-aware ranking & next word prediction.
+	// 0 disables it.
+	WordSequenceTimeout int
+
+	// Fill Suggestion.Score of suggestions
+	ExplainSuggestions bool
+
+	// Learnt weight of a word halves every this many seconds
+	// since it was last learnt, so that current words win over
+	// words used a lot long ago. 0 disables it. See decay.go
+	LearningHalfLife int
+
+	// Number of typos (letter inserted, deleted, replaced or
+	// swapped) tolerated in input when no dictionary word is
+	// found for it. At most 2, 0 disables it. See fuzzy.go
+	FuzzyMatchMaxEdits int
+
+	// Maximum inputs with typos fixed searched in dictionary
+	// for a transliteration. Keeps fuzzy matching fast. The
+	// default is enough for 1 edit, 2 edits need ~1000
+	FuzzyMatchCostLimit int
+
+	// Suggest learnt words that sound like the input, though
+	// spelled differently ("th", "t" & "d" for example). See
+	// phonetic.go
+	PhoneticMatch bool
+
+	// How weights of a word in more than one dictionary layer
+	// are combined, one of VARNAM_LAYER_WEIGHT_*. See layers.go
+	LayerWeightPolicy int
+
+	// Orders the results of Transliterate into one list.
+	// nil is DefaultRanker
+	Ranker Ranker
+
 	VSTMakerConfig VSTMakerConfig
 
 	// See setDefaultConfig() for the default values
+
+	// Last words learnt with Learn(). For word sequences
+	lastLearnedWords []string
+	lastLearnedAt    time.Time
+
+	// Read locked by every method, write locked by methods
+	// that change VST or connections. See doc.go
+	mutex sync.RWMutex
+
+	// Methods writing to dictionary are run one at a time.
+	// Always taken after mutex, see lockDict()
+	learnMutex sync.Mutex
+
+	// nil if disabled. See EnableCache()
+	cache *transliterationCache
+
+	// Connection of dictConn kept for reading SQLite
+	// data_version. See getDictVersion()
+	dictVersionMutex sync.Mutex
+	dictVersionConn  *sql.Conn
+}
+
+// Lock for methods writing to dictionary. Transliteration
+// can still happen parallelly, SQLite handles that.
+func (varnam *Varnam) lockDict() {
+	varnam.mutex.RLock()
+	varnam.learnMutex.Lock()
+}
+
+// Cached results are outdated after dictionary changes
+func (varnam *Varnam) unlockDict() {
+	varnam.cache.clearResults()
+	atomic.AddUint64(&varnam.changes, 1)
+
+	varnam.learnMutex.Unlock()
+	varnam.mutex.RUnlock()
+}
+
+// Lock for methods changing VST, config or connections
+func (varnam *Varnam) lockVST() {
+	varnam.mutex.Lock()
+}
+
+func (varnam *Varnam) unlockVST() {
+	varnam.cache.clear()
+	atomic.AddUint64(&varnam.changes, 1)
+
+	varnam.mutex.Unlock()
 }
 
 // Suggestion suggestion
 type Suggestion struct {
-	Word      string
-	Weight    int
-	LearnedOn int
+	Word      string `json:"word"`
+	Weight    int    `json:"weight"`
+	LearnedOn int    `json:"learned_on"`
+
+	// Result list it came from, one of VARNAM_SOURCE_*
+	Source int `json:"source"`
+
+	// How Weight was made. Only set if
+	// Varnam.ExplainSuggestions is true
+	Score *ScoreBreakdown `json:"score,omitempty"`
+}
+
+// ScoreBreakdown parts of the weight of a suggestion.
+// Weight is the sum of these.
+type ScoreBreakdown struct {
+	// Weights of VST symbols used to make the word
+	SymbolWeight int `json:"symbol_weight"`
+
+	// Weight of the word in dictionary
+	LearnedWeight int `json:"learned_weight"`
+
+	// Weight lost to decay since the word was last
+	// learnt. 0 or negative, see Varnam.LearningHalfLife
+	Recency int `json:"recency"`
+
+	// Weight added for the length of input
+	// matched with pattern dictionary
+	PatternLengthBonus int `json:"pattern_length_bonus"`
+
+	// Weight lost for the typos fixed in input to find
+	// the word. 0 or negative, see Varnam.FuzzyMatchMaxEdits
+	EditPenalty int `json:"edit_penalty"`
 }
 
 // TransliterationResult result
 type TransliterationResult struct {
 	// Exactly found words in dictionary if there is any.
 	// From both patterns and normal dict
-	ExactWords []Suggestion
+	ExactWords []Suggestion `json:"exact_words"`
 
 	// Exactly starting word matches in dictionary if there is any.
 	// Not applicable for patterns dictionary.
-	ExactMatches []Suggestion
+	ExactMatches []Suggestion `json:"exact_matches"`
 
 	// Possible word suggestions from dictionary
-	DictionarySuggestions []Suggestion
+	DictionarySuggestions []Suggestion `json:"dictionary_suggestions"`
 
 	// Possible words matching from patterns dictionary
-	PatternDictionarySuggestions []Suggestion
+	PatternDictionarySuggestions []Suggestion `json:"pattern_dictionary_suggestions"`
 
 	// All possible matches from tokenizer (VARNAM_MATCH_ALL)
 	// Has a limit. The first few results will be VARNAM_MATCH_EXACT.
 	// This will only be filled if there are no exact matches.
 	// Related: See Config.TokenizerSuggestionsAlways
-	TokenizerSuggestions []Suggestion
+	TokenizerSuggestions []Suggestion `json:"tokenizer_suggestions"`
 
 	// VARNAM_MATCH_EXACT results from tokenizer.
 	// No limit, mostly gives 1 or less than 3 outputs
-	GreedyTokenized []Suggestion
+	GreedyTokenized []Suggestion `json:"greedy_tokenized"`
 }
 
 func (varnam *Varnam) log(msg string) {
@@ -141,7 +284,11 @@ func (varnam *Varnam) tokensToSuggestions(ctx context.Context, tokensPointer *[]
 		addWord := func(word []string, weight int) {
 			// TODO avoid division, performance improvement ?
 			weight = weight / 100
-			results = append(results, Suggestion{strings.Join(word, ""), weight, 0})
+
+			sug := Suggestion{Word: strings.Join(word, ""), Weight: weight}
+			varnam.addScore(&sug, ScoreBreakdown{SymbolWeight: weight})
+
+			results = append(results, sug)
 		}
 
 		// Tracks index of each token possibilities
@@ -248,6 +395,15 @@ func (varnam *Varnam) setDefaultConfig() {
 
 	varnam.DictionaryMatchExact = false
 
+	varnam.WordSequenceTimeout = 60
+
+	varnam.FuzzyMatchMaxEdits = 0
+	varnam.FuzzyMatchCostLimit = VARNAM_FUZZY_MATCH_COST_LIMIT
+
+	varnam.PhoneticMatch = false
+
+	varnam.LayerWeightPolicy = VARNAM_LAYER_WEIGHT_OVERRIDE
+
 	varnam.LangRules.IndicDigits = false
 	varnam.LangRules.Virama, _ = varnam.getVirama()
 	varnam.LangRules.UnicodeBlock = varnam.getUnicodeBlock()
@@ -257,6 +413,40 @@ func (varnam *Varnam) setDefaultConfig() {
 	}
 }
 
+// Add to score breakdown of suggestion if explaining. A new
+// breakdown is made, so copies of the suggestion don't change.
+func (varnam *Varnam) addScore(sug *Suggestion, score ScoreBreakdown) {
+	if !varnam.ExplainSuggestions {
+		return
+	}
+
+	if sug.Score != nil {
+		score.SymbolWeight += sug.Score.SymbolWeight
+		score.LearnedWeight += sug.Score.LearnedWeight
+		score.Recency += sug.Score.Recency
+		score.PatternLengthBonus += sug.Score.PatternLengthBonus
+		score.EditPenalty += sug.Score.EditPenalty
+	}
+
+	sug.Score = &score
+}
+
+func setSuggestionsSource(sugs []Suggestion, source int) {
+	for i := range sugs {
+		sugs[i].Source = source
+	}
+}
+
+// Mark each suggestion with the list it is in
+func (result *TransliterationResult) setSources() {
+	setSuggestionsSource(result.ExactWords, VARNAM_SOURCE_EXACT_WORD)
+	setSuggestionsSource(result.ExactMatches, VARNAM_SOURCE_EXACT_MATCH)
+	setSuggestionsSource(result.DictionarySuggestions, VARNAM_SOURCE_DICTIONARY)
+	setSuggestionsSource(result.PatternDictionarySuggestions, VARNAM_SOURCE_PATTERN_DICTIONARY)
+	setSuggestionsSource(result.TokenizerSuggestions, VARNAM_SOURCE_TOKENIZER)
+	setSuggestionsSource(result.GreedyTokenized, VARNAM_SOURCE_GREEDY)
+}
+
 // SortSuggestions by weight and learned on time
 func SortSuggestions(sugs []Suggestion) []Suggestion {
 	// TODO write tests
@@ -269,8 +459,45 @@ func SortSuggestions(sugs []Suggestion) []Suggestion {
 	return sugs
 }
 
-// Returns tokens and all found suggestions
+// Returns tokens and all found suggestions.
+// Uses cache if it's enabled.
 func (varnam *Varnam) transliterate(ctx context.Context, word string) (
+	*[]Token,
+	TransliterationResult) {
+	return varnam.transliterateWithCache(ctx, varnam.cache, word)
+}
+
+// Transliterate using the results in cache. Sessions
+// have their own cache, see NewSession()
+func (varnam *Varnam) transliterateWithCache(ctx context.Context, cache *transliterationCache, word string) (
+	*[]Token,
+	TransliterationResult) {
+	if cache == nil {
+		return varnam.transliterateWord(ctx, cache, word)
+	}
+
+	// Dictionary could've been changed by another instance
+	// sharing the learnings file
+	cache.checkDictVersion(varnam.getDictVersion(ctx))
+
+	key := varnam.getResultCacheKey(word)
+	generation := cache.getGeneration()
+
+	if tokens, result, found := cache.getResult(key); found {
+		return &tokens, result
+	}
+
+	tokensPointer, result := varnam.transliterateWord(ctx, cache, word)
+
+	// A cancelled transliteration has incomplete results
+	if tokensPointer != nil && ctx.Err() == nil {
+		cache.addResult(key, *tokensPointer, result, generation)
+	}
+
+	return tokensPointer, result
+}
+
+func (varnam *Varnam) transliterateWord(ctx context.Context, cache *transliterationCache, word string) (
 	*[]Token,
 	TransliterationResult) {
 	var (
@@ -280,7 +507,7 @@ func (varnam *Varnam) transliterate(ctx context.Context, word string) (
 	start := time.Now()
 
 	tokensPointerChan := make(chan *[]Token)
-	go varnam.channelTokenizeWord(ctx, word, VARNAM_MATCH_ALL, false, tokensPointerChan)
+	go varnam.channelTokenizeWord(ctx, cache, word, VARNAM_MATCH_ALL, false, tokensPointerChan)
 
 	select {
 	case <-ctx.Done():
@@ -308,12 +535,12 @@ func (varnam *Varnam) transliterate(ctx context.Context, word string) (
 		exactTokens = removeNonExactTokens(exactTokens)
 
 		if varnam.DictionaryMatchExact {
-			go varnam.channelGetFromDictionary(ctx, word, &exactTokens, dictSugsChan)
+			go varnam.channelGetFromDictionary(ctx, cache, word, &exactTokens, dictSugsChan)
 		} else {
-			go varnam.channelGetFromDictionary(ctx, word, tokensPointer, dictSugsChan)
+			go varnam.channelGetFromDictionary(ctx, cache, word, tokensPointer, dictSugsChan)
 		}
 
-		go varnam.channelGetFromPatternDictionary(ctx, word, patternDictSugsChan)
+		go varnam.channelGetFromPatternDictionary(ctx, cache, word, patternDictSugsChan)
 		go varnam.channelTokensToGreedySuggestions(ctx, &exactTokens, greedyTokenizedChan)
 
 		tokenizerSugsChan := make(chan []Suggestion)
@@ -365,6 +592,9 @@ func (varnam *Varnam) transliterate(ctx context.Context, word string) (
 						case tokenizerSugs := <-tokenizerSugsChan:
 							result.TokenizerSuggestions = SortSuggestions(tokenizerSugs)
 
+							varnam.addStemSuggestions(ctx, &result)
+							result.setSources()
+
 							if LOG_TIME_TAKEN {
 								log.Printf("%s took %v\n", "transliteration", time.Since(start))
 							}
@@ -373,6 +603,9 @@ func (varnam *Varnam) transliterate(ctx context.Context, word string) (
 						}
 
 					} else {
+						varnam.addStemSuggestions(ctx, &result)
+						result.setSources()
+
 						if LOG_TIME_TAKEN {
 							log.Printf("%s took %v\n", "transliteration", time.Since(start))
 						}
@@ -387,6 +620,9 @@ func (varnam *Varnam) transliterate(ctx context.Context, word string) (
 
 // TransliterateAdvanced transliterate with a detailed structure as result
 func (varnam *Varnam) TransliterateAdvanced(word string) TransliterationResult {
+	varnam.mutex.RLock()
+	defer varnam.mutex.RUnlock()
+
 	ctx := context.Background()
 	_, result := varnam.transliterate(ctx, word)
 	return result
@@ -399,7 +635,9 @@ func (varnam *Varnam) TransliterateAdvancedWithContext(ctx context.Context, word
 		return
 
 	default:
+		varnam.mutex.RLock()
 		_, result := varnam.transliterate(ctx, word)
+		varnam.mutex.RUnlock()
 		resultChannel <- result
 		close(resultChannel)
 	}
@@ -447,13 +685,17 @@ func flattenTR(result TransliterationResult) []Suggestion {
 	}
 
 	combined = append(combined, result.TokenizerSuggestions...)
-	combined = append(combined, result.GreedyTokenized...)
-	return combined
+
+	return deduplicateSuggestions(combined)
 }
 
 // Transliterate transliterate with output array
 func (varnam *Varnam) Transliterate(word string) []Suggestion {
-	return flattenTR(varnam.TransliterateAdvanced(word))
+	varnam.mutex.RLock()
+	defer varnam.mutex.RUnlock()
+
+	_, result := varnam.transliterate(context.Background(), word)
+	return varnam.rankResult(result)
 }
 
 // TransliterateWithContext Transliterate but with Go context
@@ -462,22 +704,34 @@ func (varnam *Varnam) TransliterateWithContext(ctx context.Context, word string,
 	case <-ctx.Done():
 		return
 	default:
+		varnam.mutex.RLock()
 		_, result := varnam.transliterate(ctx, word)
-		resultChannel <- flattenTR(result)
+		varnam.mutex.RUnlock()
+		resultChannel <- varnam.rankResult(result)
 		close(resultChannel)
 	}
 }
 
 // TransliterateGreedyTokenized transliterate word, only tokenizer results
 func (varnam *Varnam) TransliterateGreedyTokenized(word string) []Suggestion {
+	varnam.mutex.RLock()
+	defer varnam.mutex.RUnlock()
+
 	ctx := context.Background()
 
-	tokens := varnam.tokenizeWord(ctx, word, VARNAM_MATCH_EXACT, false)
-	return varnam.tokensToSuggestions(ctx, tokens, false, varnam.TokenizerSuggestionsLimit)
+	tokens := varnam.tokenizeWord(ctx, varnam.cache, word, VARNAM_MATCH_EXACT, false)
+
+	sugs := varnam.tokensToSuggestions(ctx, tokens, false, varnam.TokenizerSuggestionsLimit)
+	setSuggestionsSource(sugs, VARNAM_SOURCE_GREEDY)
+
+	return sugs
 }
 
 // ReverseTransliterate do a reverse transliteration
 func (varnam *Varnam) ReverseTransliterate(word string) ([]Suggestion, error) {
+	varnam.mutex.RLock()
+	defer varnam.mutex.RUnlock()
+
 	var results []Suggestion
 	ctx := context.Background()
 
@@ -503,6 +757,9 @@ func (varnam *Varnam) ReverseTransliterate(word string) ([]Suggestion, error) {
 // with proper alternative so that the word can be tokenized further.
 // Useful for malayalam to replace last chil letter with its root
 func (varnam *Varnam) RegisterPatternWordPartializer(cb func(*Suggestion)) {
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
 	varnam.PatternWordPartializers = append(varnam.PatternWordPartializers, cb)
 }
 
@@ -526,12 +783,34 @@ func Init(vstPath string, dictPath string) (*Varnam, error) {
 
 // InitFromID Init from ID. Scheme ID doesn't necessarily be a language code
 func InitFromID(schemeID string) (*Varnam, error) {
+	return InitFromIDWithDirs(schemeID, "", "")
+}
+
+// InitFromIDWithDirs Init from ID with VST looked up first in vstDir
+// and learnings stored in learningsDir. Empty values use the
+// defaults, see SetVSTLookupDir() & SetLearningsDir()
+func InitFromIDWithDirs(schemeID string, vstDir string, learningsDir string) (*Varnam, error) {
+	return initFromID(schemeID, vstDir, learningsDir, VARNAM_DEFAULT_PROFILE)
+}
+
+// InitFromIDWithProfile Init from ID with learnings stored in
+// the profile. Profile is made if it doesn't exist. See profile.go
+func InitFromIDWithProfile(schemeID string, profile string) (*Varnam, error) {
+	return initFromID(schemeID, "", "", profile)
+}
+
+func initFromID(schemeID string, vstDir string, learningsDir string, profile string) (*Varnam, error) {
 	var (
 		vstPath  string
 		dictPath string
 	)
 
-	vstPath, err := findVSTPath(schemeID)
+	err := validateProfileName(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	vstPath, err = findVSTPath(vstDir, schemeID)
 	if err != nil {
 		return nil, err
 	}
@@ -544,7 +823,7 @@ func InitFromID(schemeID string) (*Varnam, error) {
 	}
 
 	// One dictionary for one language, not for different scheme
-	dictPath = findLearningsFilePath(varnam.SchemeDetails.LangCode)
+	dictPath = findLearningsFilePath(learningsDir, varnam.SchemeDetails.LangCode, profile)
 
 	err = varnam.InitDict(dictPath)
 	if err != nil {
@@ -558,10 +837,14 @@ func InitFromID(schemeID string) (*Varnam, error) {
 
 // Close close db connections
 func (varnam *Varnam) Close() error {
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
 	if varnam.vstConn != nil {
 		varnam.vstConn.Close()
 	}
 	if varnam.dictConn != nil {
+		varnam.closeDictVersionConn()
 		varnam.dictConn.Close()
 	}
 	return nil
@@ -67,7 +67,7 @@ func TestMLInscriptTokenizer(t *testing.T) {
 // 	// varnam.Debug(true)
 // 	sugs := varnam.TransliterateAdvanced("malayala").DictionarySuggestions
 
-// 	assertEqual(t, sugs[0], Suggestion{"മലയാളം", VARNAM_LEARNT_WORD_MIN_WEIGHT, sugs[0].LearnedOn})
+// 	assertEqual(t, sugs[0], Suggestion{Word: "മലയാളം", Weight: VARNAM_LEARNT_WORD_MIN_WEIGHT, LearnedOn: sugs[0].LearnedOn, Source: VARNAM_SOURCE_DICTIONARY})
 
 // 	// Check the time learnt is right (UTC) ?
 // 	learnedOn := time.Unix(int64(sugs[1].LearnedOn), 0)
@@ -76,7 +76,7 @@ func TestMLInscriptTokenizer(t *testing.T) {
 // 		t.Errorf("Learn time %v (%v) not in between %v and %v", learnedOn, sugs[1].LearnedOn, start1SecondBefore, end1SecondAfter)
 // 	}
 
-// 	assertEqual(t, sugs[1], Suggestion{"മലയാളത്തിൽ", VARNAM_LEARNT_WORD_MIN_WEIGHT, sugs[1].LearnedOn})
+// 	assertEqual(t, sugs[1], Suggestion{Word: "മലയാളത്തിൽ", Weight: VARNAM_LEARNT_WORD_MIN_WEIGHT, LearnedOn: sugs[1].LearnedOn, Source: VARNAM_SOURCE_DICTIONARY})
 
 // 	// Learn the word again
 // 	// This word will now be at the top
@@ -85,7 +85,7 @@ func TestMLInscriptTokenizer(t *testing.T) {
 // 	checkError(err)
 
 // 	sug := varnam.TransliterateAdvanced("malayala").DictionarySuggestions[0]
-// 	assertEqual(t, sug, Suggestion{"മലയാളത്തിൽ", VARNAM_LEARNT_WORD_MIN_WEIGHT + 1, sug.LearnedOn})
+// 	assertEqual(t, sug, Suggestion{Word: "മലയാളത്തിൽ", Weight: VARNAM_LEARNT_WORD_MIN_WEIGHT + 1, LearnedOn: sug.LearnedOn, Source: VARNAM_SOURCE_DICTIONARY})
 
 // 	// Subsequent pattern can be smaller now (no need of "thth")
 // 	assertEqual(t, varnam.TransliterateAdvanced("malayalathil").ExactMatches[0].Word, "മലയാളത്തിൽ")
@@ -103,7 +103,7 @@ func TestMLLearn(t *testing.T) {
 	// varnam.Debug(true)
 	sugs := varnam.TransliterateAdvanced("malayala").DictionarySuggestions
 
-	assertEqual(t, sugs[0], Suggestion{"മലയാളം", VARNAM_LEARNT_WORD_MIN_WEIGHT, sugs[0].LearnedOn})
+	assertEqual(t, sugs[0], Suggestion{Word: "മലയാളം", Weight: VARNAM_LEARNT_WORD_MIN_WEIGHT, LearnedOn: sugs[0].LearnedOn, Source: VARNAM_SOURCE_DICTIONARY})
 
 	// Check the time learnt is right (UTC) ?
 	learnedOn := time.Unix(int64(sugs[1].LearnedOn), 0)
@@ -112,7 +112,7 @@ func TestMLLearn(t *testing.T) {
 		t.Errorf("Learn time %v (%v) not in between %v and %v", learnedOn, sugs[1].LearnedOn, start1SecondBefore, end1SecondAfter)
 	}
 
-	assertEqual(t, sugs[1], Suggestion{"മലയാളത്തിൽ", VARNAM_LEARNT_WORD_MIN_WEIGHT, sugs[1].LearnedOn})
+	assertEqual(t, sugs[1], Suggestion{Word: "മലയാളത്തിൽ", Weight: VARNAM_LEARNT_WORD_MIN_WEIGHT, LearnedOn: sugs[1].LearnedOn, Source: VARNAM_SOURCE_DICTIONARY})
 
 	// Learn the word again
 	// This word will now be at the top
@@ -121,7 +121,7 @@ func TestMLLearn(t *testing.T) {
 	checkError(err)
 
 	sug := varnam.TransliterateAdvanced("malayala").DictionarySuggestions[0]
-	assertEqual(t, sug, Suggestion{"മലയാളത്തിൽ", VARNAM_LEARNT_WORD_MIN_WEIGHT + 1, sug.LearnedOn})
+	assertEqual(t, sug, Suggestion{Word: "മലയാളത്തിൽ", Weight: VARNAM_LEARNT_WORD_MIN_WEIGHT + 1, LearnedOn: sug.LearnedOn, Source: VARNAM_SOURCE_DICTIONARY})
 
 	// Subsequent pattern can be smaller now (no need of "thth")
 	assertEqual(t, varnam.TransliterateAdvanced("malayalathil").ExactWords[0].Word, "മലയാളത്തിൽ")
@@ -422,6 +422,7 @@ func TestMLExportAndImport(t *testing.T) {
 		Word:      "അൾജീരിയ",
 		Weight:    VARNAM_LEARNT_WORD_MIN_WEIGHT + 25,
 		LearnedOn: 1531131220,
+		Source:    VARNAM_SOURCE_EXACT_WORD,
 	})
 }
 
@@ -509,3 +510,277 @@ func TestMLNativePartialWordsInInput(t *testing.T) {
 	assertEqual(t, varnam.TransliterateAdvanced("puസ്ത").DictionarySuggestions[0].Word, "പുസ്തകം")
 	assertEqual(t, varnam.TransliterateAdvanced("ആലippazham").DictionarySuggestions[0].Word, "ആലിപ്പഴം")
 }
+
+func TestMLTransliterateText(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+
+	segments, err := varnam.TransliterateText(context.Background(), "namaskaaram, malayalam 2021!")
+	checkError(err)
+
+	expected := []TextSegment{
+		{Start: 0, End: 11, Text: "namaskaaram", IsWord: true},
+		{Start: 11, End: 13, Text: ", ", IsWord: false},
+		{Start: 13, End: 22, Text: "malayalam", IsWord: true},
+		{Start: 22, End: 28, Text: " 2021!", IsWord: false},
+	}
+
+	assertEqual(t, len(segments), len(expected))
+	for i, segment := range segments {
+		assertEqual(t, segment.Start, expected[i].Start)
+		assertEqual(t, segment.End, expected[i].End)
+		assertEqual(t, segment.Text, expected[i].Text)
+		assertEqual(t, segment.IsWord, expected[i].IsWord)
+
+		if segment.IsWord {
+			assertEqual(t, len(segment.Suggestions) > 0, true)
+		} else {
+			assertEqual(t, len(segment.Suggestions), 0)
+		}
+	}
+
+	assertEqual(t, segments[0].Suggestions[0].Word, varnam.Transliterate("namaskaaram")[0].Word)
+
+	// Cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = varnam.TransliterateText(ctx, "namaskaaram")
+	assertEqual(t, err, context.Canceled)
+}
+
+func TestMLTransliterateWithPreviousWords(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+
+	// പനി is learnt more, so it comes first without context
+	varnam.Learn("പനി", 100)
+
+	filePath := makeFile("sequence.txt", "നല്ല പണി ചെയ്തു. നല്ല പണി")
+	_, err := varnam.LearnFromFile(filePath)
+	checkError(err)
+
+	result := varnam.TransliterateAdvanced("pani")
+	assertEqual(t, result.ExactWords[0].Word, "പനി")
+
+	channel := make(chan TransliterationResult)
+	go varnam.TransliterateAdvancedWithPreviousWords(context.Background(), []string{"നല്ല"}, "pani", channel)
+	result = <-channel
+	assertEqual(t, result.ExactWords[0].Word, "പണി")
+	assertEqual(t, result.ExactWords[1].Word, "പനി")
+
+	// Sequence breaks at end of a sentence
+	assertEqual(t, len(varnam.getWordBigrams(context.Background(), "ചെയ്തു")), 0)
+	assertEqual(t, varnam.getWordBigrams(context.Background(), "നല്ല")["പണി"], 2)
+
+	// Learn() calls one after the other make a sequence
+	varnam.Learn("വെറും", 0)
+	varnam.Learn("പനി", 0)
+	assertEqual(t, varnam.getWordBigrams(context.Background(), "വെറും")["പനി"], 1)
+
+	sugChannel := make(chan []Suggestion)
+	go varnam.TransliterateWithPreviousWords(context.Background(), []string{"എനിക്ക്", "വെറും"}, "pani", sugChannel)
+	sugs := <-sugChannel
+	assertEqual(t, sugs[0].Word, "പനി")
+
+	// Unlearning removes sequences too
+	varnam.Unlearn("പണി")
+	assertEqual(t, len(varnam.getWordBigrams(context.Background(), "നല്ല")), 0)
+}
+
+func TestMLPredictNextWords(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+
+	filePath := makeFile("predict.txt", "അവൾ ഇന്ന് വന്നു. അവൻ ഇന്ന് നിന്നു. അവൻ ഇന്ന് നിന്നു. അവൾ ഇന്ന് വന്നു. അവൾ ഇന്ന് വന്നു")
+	_, err := varnam.LearnFromFile(filePath)
+	checkError(err)
+
+	ctx := context.Background()
+
+	// Bigram: വന്നു came 3 times after ഇന്ന്, നിന്നു 2 times
+	sugs := varnam.PredictNextWords(ctx, []string{"ഇന്ന്"}, 5)
+	assertEqual(t, len(sugs), 2)
+	assertEqual(t, sugs[0].Word, "വന്നു")
+	assertEqual(t, sugs[0].Weight, 3)
+	assertEqual(t, sugs[1].Word, "നിന്നു")
+
+	// Trigram takes precedence, bigram fills the rest
+	sugs = varnam.PredictNextWords(ctx, []string{"അവൻ", "ഇന്ന്"}, 5)
+	assertEqual(t, len(sugs), 2)
+	assertEqual(t, sugs[0].Word, "നിന്നു")
+	assertEqual(t, sugs[0].Weight, 2)
+	assertEqual(t, sugs[1].Word, "വന്നു")
+
+	assertEqual(t, len(varnam.PredictNextWords(ctx, []string{"അവൻ", "ഇന്ന്"}, 1)), 1)
+
+	// Nothing comes after end of a sentence
+	assertEqual(t, len(varnam.PredictNextWords(ctx, []string{"വന്നു."}, 5)), 0)
+	assertEqual(t, len(varnam.PredictNextWords(ctx, []string{}, 5)), 0)
+
+	// Learn() calls one after the other make a sequence
+	varnam.Learn("നാളെ", 0)
+	varnam.Learn("ഇന്ന്", 0)
+	varnam.Learn("നിന്നു", 0)
+	sugs = varnam.PredictNextWords(ctx, []string{"നാളെ", "ഇന്ന്"}, 1)
+	assertEqual(t, sugs[0].Word, "നിന്നു")
+	assertEqual(t, sugs[0].Weight, 1)
+}
+
+func TestMLSymbolTrie(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+	ctx := context.Background()
+
+	querySymbols := func(query string, args ...interface{}) []Symbol {
+		var results []Symbol
+
+		rows, err := varnam.vstConn.Query(query, args...)
+		checkError(err)
+		defer rows.Close()
+
+		for rows.Next() {
+			var item Symbol
+			rows.Scan(&item.Identifier, &item.Type, &item.Pattern, &item.Value1, &item.Value2, &item.Value3, &item.Tag, &item.MatchType, &item.Priority, &item.AcceptCondition, &item.Flags, &item.Weight)
+			results = append(results, item)
+		}
+		return results
+	}
+
+	// Flags are left out. Prefix flags of VSTs made without
+	// them are set when loading, see setSymbolPrefixFlags()
+	assertSymbols := func(symbols []Symbol, expected []Symbol) {
+		assertEqual(t, len(symbols), len(expected))
+		for i := 0; i < len(symbols) && i < len(expected); i++ {
+			symbol, expectedSymbol := symbols[i], expected[i]
+			symbol.Flags, expectedSymbol.Flags = 0, 0
+			assertEqual(t, symbol, expectedSymbol)
+		}
+	}
+
+	acceptConditions := []int{
+		VARNAM_TOKEN_ACCEPT_IF_STARTS_WITH,
+		VARNAM_TOKEN_ACCEPT_IF_IN_BETWEEN,
+		VARNAM_TOKEN_ACCEPT_IF_ENDS_WITH,
+	}
+
+	// Same result as querying the VST
+	for _, input := range []string{"nithyaharitha", "kaaRRu", "~", "aa", "r", "zzz"} {
+		runes := []rune(input)
+		for _, acceptCondition := range acceptConditions {
+			var patterns []interface{}
+			for i := range runes {
+				patterns = append(patterns, string(runes[:i+1]))
+			}
+
+			expected := querySymbols(
+				"SELECT * FROM symbols WHERE (accept_condition = 0 OR accept_condition = ?) AND pattern IN (?"+strings.Repeat(", ?", len(patterns)-1)+") ORDER BY LENGTH(pattern) DESC, match_type ASC, weight DESC, priority DESC, id ASC",
+				append([]interface{}{acceptCondition}, patterns...)...,
+			)
+			assertSymbols(varnam.findLongestPatternMatchSymbols(ctx, runes, VARNAM_MATCH_ALL, acceptCondition), expected)
+		}
+	}
+
+	for _, value := range []string{"ക", "ാ", "്", "ത്യ", "x"} {
+		for _, acceptCondition := range acceptConditions {
+			expected := querySymbols(
+				"SELECT * FROM symbols WHERE (value1 = ? OR value2 = ?) AND (accept_condition = 0 OR accept_condition = ?) ORDER BY match_type ASC, weight DESC, priority DESC, id ASC",
+				value, value, acceptCondition,
+			)
+			assertSymbols(varnam.searchPattern(ctx, value, VARNAM_MATCH_ALL, acceptCondition), expected)
+		}
+	}
+
+	for _, symbol := range varnam.searchPattern(ctx, "ക", VARNAM_MATCH_EXACT, VARNAM_TOKEN_ACCEPT_IF_IN_BETWEEN) {
+		assertEqual(t, symbol.MatchType, VARNAM_MATCH_EXACT)
+	}
+}
+
+func TestMLStemRules(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+
+	varnam.stemRules = []StemRule{
+		{"ലിൽ", "ൽ", nil},
+		{"ിൽ", "്", []string{"ടിൽ"}},
+	}
+	defer func() {
+		varnam.stemRules = nil
+	}()
+
+	assertEqual(t, len(varnam.getStems("വയലിൽ")), 1)
+	assertEqual(t, varnam.getStems("വയലിൽ")[0], "വയൽ")
+	assertEqual(t, varnam.getStems("വീട്ടിൽ") == nil, true)
+
+	// Stem is learnt too
+	err := varnam.Learn("വയലിൽ", 0)
+	checkError(err)
+	_, err = varnam.getWordInfo("വയൽ")
+	checkError(err)
+
+	checkError(varnam.Unlearn("വയലിൽ"))
+
+	// Word made by tokenizer is suggested from dictionary
+	// if its stem is in dictionary
+	stemSugs := varnam.getFromStemDictionary(context.Background(), []Suggestion{
+		{Word: "വയലിൽ", Weight: 8},
+		{Word: "വയലില്", Weight: 8},
+	})
+	assertEqual(t, len(stemSugs), 1)
+	assertEqual(t, stemSugs[0].Word, "വയലിൽ")
+	assertEqual(t, stemSugs[0].LearnedOn != 0, true)
+
+	result := varnam.TransliterateAdvanced("vayalil")
+	found := false
+	for _, sug := range result.DictionarySuggestions {
+		if sug.Word == "വയലിൽ" {
+			found = true
+		}
+	}
+	assertEqual(t, found, true)
+
+	checkError(varnam.Unlearn("വയൽ"))
+}
+
+func TestSuggestionSource(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+	checkError(varnam.Learn("വയൽ", 0))
+
+	result := varnam.TransliterateAdvanced("vayal")
+	assertEqual(t, result.ExactWords[0].Source, VARNAM_SOURCE_EXACT_WORD)
+	assertEqual(t, result.DictionarySuggestions[0].Source, VARNAM_SOURCE_DICTIONARY)
+	assertEqual(t, result.TokenizerSuggestions[0].Source, VARNAM_SOURCE_TOKENIZER)
+	assertEqual(t, result.GreedyTokenized[0].Source, VARNAM_SOURCE_GREEDY)
+	assertEqual(t, result.ExactWords[0].Score == nil, true)
+
+	assertEqual(t, varnam.TransliterateGreedyTokenized("vayal")[0].Source, VARNAM_SOURCE_GREEDY)
+
+	// Same word from multiple lists comes only once
+	sugs := varnam.Transliterate("vayal")
+	added := map[string]bool{}
+	for _, sug := range sugs {
+		assertEqual(t, added[sug.Word], false)
+		added[sug.Word] = true
+	}
+	assertEqual(t, sugs[0].Word, "വയൽ")
+	assertEqual(t, sugs[0].Source, VARNAM_SOURCE_EXACT_WORD)
+}
+
+func TestExplainSuggestions(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+	checkError(varnam.Learn("വയൽ", 0))
+	checkError(varnam.Train("vayal", "വയൽ"))
+
+	varnam.ExplainSuggestions = true
+
+	result := varnam.TransliterateAdvanced("vayalil")
+
+	// Parts add up to weight
+	for _, sug := range flattenTR(result) {
+		score := sug.Score
+		assertEqual(t, score.SymbolWeight+score.LearnedWeight+score.Recency+score.PatternLengthBonus, sug.Weight)
+	}
+
+	sug := result.PatternDictionarySuggestions[0]
+	assertEqual(t, sug.Score.PatternLengthBonus, len("vayal")*50)
+	assertEqual(t, sug.Score.LearnedWeight > 0, true)
+	assertEqual(t, sug.Score.SymbolWeight > 0, true)
+
+	sug = result.TokenizerSuggestions[0]
+	assertEqual(t, sug.Score.SymbolWeight, sug.Weight)
+}
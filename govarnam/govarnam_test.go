@@ -70,6 +70,19 @@ func getVarnamInstance(schemeID string) *Varnam {
 	return nil
 }
 
+// A separate instance with its own learnings, closed when
+// the test ends
+func makeTestInstance(t *testing.T, schemeID string, learningsDir string) *Varnam {
+	varnam, err := InitFromIDWithDirs(schemeID, "", learningsDir)
+	checkError(err)
+
+	t.Cleanup(func() {
+		varnam.Close()
+	})
+
+	return varnam
+}
+
 func tearDownVarnam(schemeID string) {
 	getVarnamInstance(schemeID).Close()
 }
@@ -85,6 +98,8 @@ func TestEnv(t *testing.T) {
 	prevEnvValue := os.Getenv("VARNAM_VST_DIR")
 
 	SetVSTLookupDir(testTempDir)
+	assertEqual(t, VARNAM_VST_DIR, testTempDir)
+
 	_, err := InitFromID("ml")
 	assertEqual(t, err != nil, true)
 
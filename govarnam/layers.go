@@ -0,0 +1,288 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+/**
+ * Dictionary layers. Read only dictionaries, a curated word list
+ * shipped to everyone for example, can be attached to the
+ * dictionary in use with AttachDictionary(). They're attached to
+ * every connection of dictConn with SQLite ATTACH, so that one
+ * query searches all layers. The dictionary in use is layer 0
+ * ("main" schema), attached ones are layer 1, 2... in the order
+ * they were attached.
+ *
+ * Words are learnt only to layer 0. A word in more than one layer
+ * is suggested once, with its weights combined by
+ * Varnam.LayerWeightPolicy. Unlearning a word records it in
+ * unlearned_words of layer 0, which hides it in other layers too.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema name of a dictionary layer in queries
+func getLayerSchema(layer int) string {
+	if layer == 0 {
+		return "main"
+	}
+	return "layer_" + strconv.Itoa(layer)
+}
+
+// Check if a dictionary can be attached as a layer
+func checkDictionaryLayer(dictPath string) error {
+	if !fileExists(dictPath) {
+		return fmt.Errorf("dictionary %q doesn't exist", dictPath)
+	}
+
+	conn, err := openDB(getReadOnlyURI(dictPath))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var tables int
+	err = conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type IN ('table', 'view') AND name IN ('words', 'words_fts', 'patterns')").Scan(&tables)
+	if err != nil {
+		return err
+	}
+	if tables != 3 {
+		return fmt.Errorf("%q is not a varnam dictionary", dictPath)
+	}
+
+	return nil
+}
+
+// Open dictionary again with the layers. Connections in
+// the pool have the old layers attached, they're closed.
+func (varnam *Varnam) setLayers(layers []string) error {
+	conn, err := openDictionary(varnam.DictPath, layers...)
+	if err != nil {
+		return err
+	}
+
+	// Connections are made lazily, check if layers attach
+	err = conn.Ping()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	varnam.closeDictVersionConn()
+	varnam.dictConn.Close()
+
+	varnam.dictConn = conn
+	varnam.layers = layers
+
+	return nil
+}
+
+func (varnam *Varnam) attachDictionary(dictPath string) error {
+	if dictPath == varnam.DictPath {
+		return fmt.Errorf("dictionary %q is in use, it can't be attached", dictPath)
+	}
+	for _, layer := range varnam.layers {
+		if layer == dictPath {
+			return fmt.Errorf("dictionary %q is already attached", dictPath)
+		}
+	}
+
+	err := checkDictionaryLayer(dictPath)
+	if err != nil {
+		return err
+	}
+
+	layers := make([]string, len(varnam.layers), len(varnam.layers)+1)
+	copy(layers, varnam.layers)
+
+	return varnam.setLayers(append(layers, dictPath))
+}
+
+func (varnam *Varnam) detachDictionary(dictPath string) error {
+	var layers []string
+	for _, layer := range varnam.layers {
+		if layer != dictPath {
+			layers = append(layers, layer)
+		}
+	}
+
+	if len(layers) == len(varnam.layers) {
+		return fmt.Errorf("dictionary %q is not attached", dictPath)
+	}
+
+	return varnam.setLayers(layers)
+}
+
+// AttachDictionary search the words of a read only dictionary
+// along with the dictionary in use. It's searched after the
+// dictionary in use and the ones attached before it.
+func (varnam *Varnam) AttachDictionary(dictPath string) error {
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
+	return varnam.attachDictionary(dictPath)
+}
+
+// DetachDictionary stop searching a dictionary attached
+// with AttachDictionary()
+func (varnam *Varnam) DetachDictionary(dictPath string) error {
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
+	if varnam.BaseProfile != "" && dictPath == varnam.getProfilePath(varnam.BaseProfile) {
+		return fmt.Errorf("dictionary %q is the base profile, use SetBaseProfile()", dictPath)
+	}
+
+	return varnam.detachDictionary(dictPath)
+}
+
+// GetAttachedDictionaries get paths of attached
+// dictionaries in the order they're searched
+func (varnam *Varnam) GetAttachedDictionaries() []string {
+	varnam.mutex.RLock()
+	defer varnam.mutex.RUnlock()
+
+	layers := make([]string, len(varnam.layers))
+	copy(layers, varnam.layers)
+
+	return layers
+}
+
+// Query all dictionary layers. query is for one layer, with
+// "{layer}" in place of the schema name and a "word" column in
+// result. Each row has its layer as the last column. Rows of
+// words unlearnt from layer 0 are left out from other layers.
+func (varnam *Varnam) queryLayers(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var (
+		layerQueries []string
+		layerArgs    []interface{}
+	)
+
+	for layer := 0; layer <= len(varnam.layers); layer++ {
+		layerQuery := "SELECT *, " + strconv.Itoa(layer) + " FROM (" + strings.ReplaceAll(query, "{layer}", getLayerSchema(layer)) + ")"
+		if layer > 0 {
+			layerQuery += " WHERE word NOT IN (SELECT word FROM main.unlearned_words)"
+		}
+
+		layerQueries = append(layerQueries, layerQuery)
+		layerArgs = append(layerArgs, args...)
+	}
+
+	return varnam.dictConn.QueryContext(ctx, strings.Join(layerQueries, " UNION ALL "), layerArgs...)
+}
+
+// Check if a word is in any attached layer
+func (varnam *Varnam) isWordInLayers(ctx context.Context, word string) (bool, error) {
+	for layer := 1; layer <= len(varnam.layers); layer++ {
+		var count int
+		err := varnam.dictConn.QueryRowContext(
+			ctx,
+			"SELECT COUNT(*) FROM "+getLayerSchema(layer)+".words WHERE word = ?",
+			word,
+		).Scan(&count)
+		if err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Combine weights of a word found in two layers
+func (varnam *Varnam) combineLayerWeights(a searchDictionaryResult, b searchDictionaryResult) searchDictionaryResult {
+	if b.layer < a.layer {
+		a, b = b, a
+	}
+
+	switch varnam.LayerWeightPolicy {
+	case VARNAM_LAYER_WEIGHT_MAX:
+		if b.weight > a.weight {
+			a.weight = b.weight
+		}
+	case VARNAM_LAYER_WEIGHT_SUM:
+		a.weight += b.weight
+	default:
+		// Upper layer overrides
+		return a
+	}
+
+	if b.learnedOn > a.learnedOn {
+		a.learnedOn = b.learnedOn
+	}
+
+	return a
+}
+
+// Combine results from layers so that a word is there once
+// for each match. Order of results is kept.
+func (varnam *Varnam) combineLayerResults(results []searchDictionaryResult) []searchDictionaryResult {
+	var (
+		combined []searchDictionaryResult
+		index    = make(map[[2]string]int)
+	)
+
+	for _, result := range results {
+		key := [2]string{result.match, result.word}
+		if i, found := index[key]; found {
+			combined[i] = varnam.combineLayerWeights(combined[i], result)
+			continue
+		}
+
+		index[key] = len(combined)
+		combined = append(combined, result)
+	}
+
+	return combined
+}
+
+// Keep one word with the most weight for each match,
+// like the GROUP BY of searchMatches in one layer
+func getBestLayerMatches(results []searchDictionaryResult) []searchDictionaryResult {
+	var (
+		best  []searchDictionaryResult
+		index = make(map[string]int)
+	)
+
+	for _, result := range results {
+		i, found := index[result.match]
+		if !found {
+			index[result.match] = len(best)
+			best = append(best, result)
+			continue
+		}
+
+		learnedOn := best[i].learnedOn
+		if result.learnedOn > learnedOn {
+			learnedOn = result.learnedOn
+		}
+
+		if result.weight > best[i].weight {
+			best[i] = result
+		}
+		best[i].learnedOn = learnedOn
+	}
+
+	return best
+}
+
+// Sort results by weight and keep limit of them
+func sortLayerResults(results []searchDictionaryResult, limit int) []searchDictionaryResult {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].weight > results[j].weight
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
@@ -0,0 +1,116 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"context"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func getLayerWeight(t *testing.T, varnam *Varnam, word string) int {
+	results := varnam.searchDictionary(context.Background(), []string{word}, searchExactWords)
+	assertEqual(t, len(results), 1)
+	return results[0].weight
+}
+
+func TestDictionaryLayers(t *testing.T) {
+	base := makeTestInstance(t, "ml", t.TempDir())
+	checkError(base.Learn("വയൽ", 0))
+	checkError(base.Learn("തത്ത", 0))
+	checkError(base.Learn("തത്ത", 0))
+	checkError(base.Train("field", "വയൽ"))
+
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+	checkError(varnam.Learn("തത്ത", 0))
+
+	notDict := path.Join(t.TempDir(), "words.txt")
+	checkError(os.WriteFile(notDict, []byte("വയൽ"), 0644))
+
+	assertEqual(t, varnam.AttachDictionary(varnam.DictPath) != nil, true)
+	assertEqual(t, varnam.AttachDictionary(path.Join(t.TempDir(), "nothing")) != nil, true)
+	assertEqual(t, varnam.AttachDictionary(notDict) != nil, true)
+	assertEqual(t, len(varnam.GetAttachedDictionaries()), 0)
+
+	assertEqual(t, hasSuggestion(varnam.TransliterateAdvanced("vayal").ExactWords, "വയൽ"), false)
+
+	checkError(varnam.AttachDictionary(base.DictPath))
+	assertEqual(t, varnam.AttachDictionary(base.DictPath) != nil, true)
+	assertEqual(t, reflect.DeepEqual(varnam.GetAttachedDictionaries(), []string{base.DictPath}), true)
+
+	assertEqual(t, hasSuggestion(varnam.TransliterateAdvanced("vayal").ExactWords, "വയൽ"), true)
+
+	sugs := varnam.getFromPatternDictionary(context.Background(), "field")
+	assertEqual(t, len(sugs), 1)
+	assertEqual(t, sugs[0].Sug.Word, "വയൽ")
+	assertEqual(t, sugs[0].Length, len("field"))
+
+	// Weights of a word in both layers
+	userWeight := getLayerWeight(t, varnam, "തത്ത")
+	baseWeight := getLayerWeight(t, base, "തത്ത")
+	assertEqual(t, userWeight != baseWeight, true)
+
+	assertEqual(t, len(varnam.TransliterateAdvanced("thaththa").ExactWords), 1)
+
+	varnam.LayerWeightPolicy = VARNAM_LAYER_WEIGHT_MAX
+	assertEqual(t, getLayerWeight(t, varnam, "തത്ത"), baseWeight)
+
+	varnam.LayerWeightPolicy = VARNAM_LAYER_WEIGHT_SUM
+	assertEqual(t, getLayerWeight(t, varnam, "തത്ത"), userWeight+baseWeight)
+
+	varnam.LayerWeightPolicy = VARNAM_LAYER_WEIGHT_OVERRIDE
+
+	// Learnt only to the dictionary in use
+	checkError(varnam.Learn("മലയാളം", 0))
+	assertEqual(t, hasSuggestion(base.TransliterateAdvanced("malayaaLam").ExactWords, "മലയാളം"), false)
+
+	// Unlearning hides words of attached dictionaries
+	checkError(varnam.Unlearn("വയൽ"))
+	assertEqual(t, hasSuggestion(varnam.TransliterateAdvanced("vayal").ExactWords, "വയൽ"), false)
+	assertEqual(t, len(varnam.searchDictionary(context.Background(), []string{"വയൽ"}, searchExactWords)), 0)
+	assertEqual(t, len(varnam.getFromPatternDictionary(context.Background(), "field")), 0)
+
+	// till it's learnt again
+	checkError(varnam.Learn("വയൽ", 0))
+	assertEqual(t, hasSuggestion(varnam.TransliterateAdvanced("vayal").ExactWords, "വയൽ"), true)
+
+	checkError(varnam.DetachDictionary(base.DictPath))
+	assertEqual(t, varnam.DetachDictionary(base.DictPath) != nil, true)
+	assertEqual(t, len(varnam.GetAttachedDictionaries()), 0)
+
+	assertEqual(t, getLayerWeight(t, varnam, "തത്ത"), userWeight)
+	assertEqual(t, len(varnam.getFromPatternDictionary(context.Background(), "field")), 0)
+}
+
+// Path is given to SQLite as a URI
+func TestDictionaryLayerPath(t *testing.T) {
+	base := makeTestInstance(t, "ml", t.TempDir())
+	checkError(base.Learn("വയൽ", 0))
+
+	// Copy of it without WAL
+	_, err := base.dictConn.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	checkError(err)
+
+	dir := path.Join(t.TempDir(), "a?b#c %41")
+	checkError(os.Mkdir(dir, 0750))
+
+	data, err := os.ReadFile(base.DictPath)
+	checkError(err)
+
+	dictPath := path.Join(dir, "base.learnings")
+	checkError(os.WriteFile(dictPath, data, 0644))
+
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+	checkError(varnam.AttachDictionary(dictPath))
+	assertEqual(t, hasSuggestion(varnam.TransliterateAdvanced("vayal").ExactWords, "വയൽ"), true)
+
+	report, err := varnam.MergeLearnings(dictPath, VARNAM_MERGE_WEIGHT_MAX, true)
+	checkError(err)
+	assertEqual(t, reflect.DeepEqual(report.AddedWords, []string{"വയൽ"}), true)
+}
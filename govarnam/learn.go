@@ -10,16 +10,14 @@ import (
 	"bufio"
 	"context"
 	sql "database/sql"
-	"encoding/json"
 	"fmt"
 	"log"
 	"math"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
-
-	"github.com/mattn/go-sqlite3"
 )
 
 // WordInfo represent a item in words table
@@ -36,10 +34,11 @@ type LearnStatus struct {
 	FailedWords int
 }
 
-// Learnings file export format
-type exportFormat struct {
-	WordsDict    []map[string]interface{} `json:"words"`
-	PatternsDict []map[string]interface{} `json:"patterns"`
+// LearningsProgress words & patterns done so
+// far by ImportWithProgress or ExportWithProgress
+type LearningsProgress struct {
+	Words    int
+	Patterns int
 }
 
 func (varnam *Varnam) languageSpecificSanitization(word string) string {
@@ -82,64 +81,119 @@ func (varnam *Varnam) sanitizeWord(word string) string {
 	return word
 }
 
-// Learn a word. If already exist, increases weight
-func (varnam *Varnam) Learn(word string, weight int) error {
+// Make the word in the form it's stored in dictionary
+func (varnam *Varnam) getLearnableWord(word string) (string, error) {
 	word = varnam.sanitizeWord(word)
 	conjuncts := varnam.splitWordByConjunct(word)
 
 	if len(conjuncts) == 0 {
-		return fmt.Errorf("Nothing to learn")
+		return "", fmt.Errorf("Nothing to learn")
 	}
 
 	if len(conjuncts) == 1 {
-		return fmt.Errorf("Can't learn a single conjunct")
+		return "", fmt.Errorf("Can't learn a single conjunct")
 	}
 
 	// reconstruct word
-	word = strings.Join(conjuncts, "")
+	return strings.Join(conjuncts, ""), nil
+}
 
-	if weight == 0 {
-		weight = VARNAM_LEARNT_WORD_MIN_WEIGHT - 1
+// Learn a word. If already exist, increases weight
+func (varnam *Varnam) Learn(word string, weight int) error {
+	varnam.lockDict()
+	defer varnam.unlockDict()
+
+	learntWord, err := varnam.learnWord(word, weight)
+	if err != nil {
+		varnam.lastLearnedWords = nil
+		return err
+	}
+
+	// Stems are learnt so that the stem with
+	// other endings can be suggested too
+	err = varnam.learnStems(learntWord, weight)
+	if err != nil {
+		varnam.lastLearnedWords = nil
+		return err
+	}
+
+	// Words learnt one after the other make a sequence
+	if varnam.WordSequenceTimeout <= 0 ||
+		time.Since(varnam.lastLearnedAt) > time.Duration(varnam.WordSequenceTimeout)*time.Second {
+		varnam.lastLearnedWords = nil
+	}
+
+	varnam.lastLearnedWords = append(varnam.lastLearnedWords, learntWord)
+	if len(varnam.lastLearnedWords) > 3 {
+		varnam.lastLearnedWords = varnam.lastLearnedWords[len(varnam.lastLearnedWords)-3:]
+	}
+	varnam.lastLearnedAt = time.Now()
+
+	return varnam.learnWordNGrams(getNGramsEndingWith(varnam.lastLearnedWords))
+}
+
+// Updates weight of a word learnt again. Stored weight is decayed
+// till now first, as learned_on moves to now. Args are given by
+// learnAgainArgs(). decay_weight() is decayWeight(), see openDB()
+const learnAgainSetClause = "weight = decay_weight(weight, max(COALESCE(learned_on, 0), ?), ?, ?) + 1, learned_on = strftime('%s', 'now')"
+
+func (varnam *Varnam) learnAgainArgs() []interface{} {
+	return []interface{}{
+		atomic.LoadInt64(&varnam.compactedOn),
+		time.Now().Unix(),
+		varnam.LearningHalfLife,
 	}
+}
 
-	query := "INSERT OR IGNORE INTO words(word, weight, learned_on) VALUES (trim(?), ?, strftime('%s', 'now'))"
+// Learn a word and return the word as it was stored
+func (varnam *Varnam) learnWord(word string, weight int) (string, error) {
+	word, err := varnam.getLearnableWord(word)
+	if err != nil {
+		return "", err
+	}
 
-	bgContext := context.Background()
+	if weight == 0 {
+		weight = VARNAM_LEARNT_WORD_MIN_WEIGHT - 1
+	}
 
-	ctx, cancelFunc := context.WithTimeout(bgContext, 5*time.Second)
+	// Word & its phonetic key are stored together
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelFunc()
 
-	stmt, err := varnam.dictConn.PrepareContext(ctx, query)
+	tx, err := varnam.dictConn.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return "", err
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	_, err = stmt.ExecContext(ctx, word, weight)
+	_, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO words(word, weight, learned_on) VALUES (trim(?), ?, strftime('%s', 'now'))", word, weight)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	query = "UPDATE words SET weight = weight + 1, learned_on = strftime('%s', 'now') WHERE word = ?"
-	ctx, cancelFunc = context.WithTimeout(bgContext, 5*time.Second)
-	defer cancelFunc()
+	_, err = tx.ExecContext(ctx, "UPDATE words SET "+learnAgainSetClause+" WHERE word = ?", append(varnam.learnAgainArgs(), word)...)
+	if err != nil {
+		return "", err
+	}
 
-	stmt, err = varnam.dictConn.PrepareContext(ctx, query)
+	err = varnam.learnPhoneticKeys(ctx, tx, []string{word})
 	if err != nil {
-		return err
+		return "", err
 	}
-	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, word)
+	err = tx.Commit()
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	return word, nil
 }
 
 // Unlearn a word, remove from words DB and pattern if there is
 func (varnam *Varnam) Unlearn(word string) error {
+	varnam.lockDict()
+	defer varnam.unlockDict()
+
 	conjuncts := varnam.splitWordByConjunct(strings.TrimSpace(word))
 
 	if len(conjuncts) == 0 {
@@ -163,35 +217,69 @@ func (varnam *Varnam) Unlearn(word string) error {
 		if affected == 0 {
 			return fmt.Errorf("nothing to unlearn")
 		}
-		return nil
+
+		// Tombstone for merging learnings, see merge.go
+		_, err = varnam.dictConn.Exec("INSERT OR REPLACE INTO unlearned_patterns(pattern, unlearned_on) VALUES (?, strftime('%s', 'now'))", word)
+		return err
 	}
 
-	varnam.dictConn.Exec("PRAGMA foreign_keys = ON")
+	ctx := context.Background()
 
-	query := "DELETE FROM words WHERE word = ?"
-	stmt, err := varnam.dictConn.Prepare(query)
+	// The pragma is per connection, DELETE should run on the same one
+	conn, err := varnam.dictConn.Conn(ctx)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
+	defer conn.Close()
 
-	_, err = stmt.Exec(word)
+	_, err = conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	if err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF")
+
+	result, err := conn.ExecContext(ctx, "DELETE FROM words WHERE word = ?", word)
 	if err != nil {
 		return err
 	}
 
 	// No need to remove from `patterns` since FOREIGN KEY ON DELETE CASCADE will work
 
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	inLayers, err := varnam.isWordInLayers(ctx, word)
+	if err != nil {
+		return err
+	}
+
+	// Hides the word in attached dictionaries (see layers.go)
+	// and is a tombstone for merging learnings (see merge.go)
+	if affected > 0 || inLayers {
+		_, err = conn.ExecContext(ctx, "INSERT OR REPLACE INTO unlearned_words(word, unlearned_on) VALUES (?, strftime('%s', 'now'))", word)
+		if err != nil {
+			return err
+		}
+	}
+
 	if varnam.Debug {
 		fmt.Printf("Removed %s\n", word)
 	}
 
-	varnam.dictConn.Exec("PRAGMA foreign_keys = OFF")
 	return nil
 }
 
 // LearnMany words in bulk. Faster learning
 func (varnam *Varnam) LearnMany(words []WordInfo) (LearnStatus, error) {
+	varnam.lockDict()
+	defer varnam.unlockDict()
+
+	return varnam.learnMany(words)
+}
+
+func (varnam *Varnam) learnMany(words []WordInfo) (LearnStatus, error) {
 	var (
 		insertionValues []string
 		insertionArgs   []interface{}
@@ -199,6 +287,8 @@ func (varnam *Varnam) LearnMany(words []WordInfo) (LearnStatus, error) {
 		updationValues []string
 		updationArgs   []interface{}
 
+		learntWords []string
+
 		learnStatus LearnStatus = LearnStatus{len(words), 0}
 	)
 
@@ -229,48 +319,59 @@ func (varnam *Varnam) LearnMany(words []WordInfo) (LearnStatus, error) {
 			weight--
 		}
 
-		insertionValues = append(insertionValues, "(trim(?), ?, strftime('%s', 'now'))")
-		insertionArgs = append(insertionArgs, word, weight)
+		learnableWords := []string{word}
+		for _, stem := range varnam.getStems(word) {
+			if len(varnam.splitWordByConjunct(stem)) > 1 {
+				learnableWords = append(learnableWords, stem)
+			}
+		}
+
+		learntWords = append(learntWords, learnableWords...)
+
+		for _, learnableWord := range learnableWords {
+			insertionValues = append(insertionValues, "(trim(?), ?, strftime('%s', 'now'))")
+			insertionArgs = append(insertionArgs, learnableWord, weight)
 
-		updationValues = append(updationValues, "word = ?")
-		updationArgs = append(updationArgs, word)
+			updationValues = append(updationValues, "word = ?")
+			updationArgs = append(updationArgs, learnableWord)
+		}
 	}
 
 	if len(insertionArgs) == 0 {
 		return learnStatus, nil
 	}
 
-	query := fmt.Sprintf(
-		"INSERT OR IGNORE INTO words(word, weight, learned_on) VALUES %s",
-		strings.Join(insertionValues, ", "),
-	)
-
-	stmt, err := varnam.dictConn.Prepare(query)
+	// There is a limit on number of OR that can be done
+	// Reference: https://stackoverflow.com/questions/9570197/sqlite-expression-maximum-depth-limit
+	limits, err := getDBLimits(varnam.dictConn)
 	if err != nil {
 		return learnStatus, err
 	}
+	depthLimit := limits.exprDepth - 1
 
-	_, err = stmt.Exec(insertionArgs...)
+	// Words & their phonetic keys are stored together
+	tx, err := varnam.dictConn.Begin()
 	if err != nil {
 		return learnStatus, err
 	}
+	defer tx.Rollback()
 
-	// There is a limit on number of OR that can be done
-	// Reference: https://stackoverflow.com/questions/9570197/sqlite-expression-maximum-depth-limit
-	depthLimit := sqlite3Conn.GetLimit(sqlite3.SQLITE_LIMIT_EXPR_DEPTH) - 1
+	query := fmt.Sprintf(
+		"INSERT OR IGNORE INTO words(word, weight, learned_on) VALUES %s",
+		strings.Join(insertionValues, ", "),
+	)
+
+	_, err = tx.Exec(query, insertionArgs...)
+	if err != nil {
+		return learnStatus, err
+	}
 
 	for len(updationValues) > 0 {
 		lastIndex := int(math.Min(float64(depthLimit), float64(len(updationValues))))
 
-		query = "UPDATE words SET weight = weight + 1, learned_on = strftime('%s', 'now') WHERE " + strings.Join(updationValues[0:lastIndex], " OR ")
-
-		stmt, err = varnam.dictConn.Prepare(query)
-		if err != nil {
-			return learnStatus, err
-		}
-		defer stmt.Close()
+		query = "UPDATE words SET " + learnAgainSetClause + " WHERE " + strings.Join(updationValues[0:lastIndex], " OR ")
 
-		_, err = stmt.Exec(updationArgs[0:lastIndex]...)
+		_, err = tx.Exec(query, append(varnam.learnAgainArgs(), updationArgs[0:lastIndex]...)...)
 		if err != nil {
 			return learnStatus, err
 		}
@@ -279,14 +380,32 @@ func (varnam *Varnam) LearnMany(words []WordInfo) (LearnStatus, error) {
 		updationArgs = updationArgs[lastIndex:]
 	}
 
+	err = varnam.learnPhoneticKeys(context.Background(), tx, learntWords)
+	if err != nil {
+		return learnStatus, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return learnStatus, err
+	}
+
 	return learnStatus, nil
 }
 
 // Train a word with a particular pattern. Pattern => word
 func (varnam *Varnam) Train(pattern string, word string) error {
+	varnam.lockDict()
+	defer varnam.unlockDict()
+
+	return varnam.train(pattern, word)
+}
+
+func (varnam *Varnam) train(pattern string, word string) error {
 	word = varnam.sanitizeWord(word)
 
-	err := varnam.Learn(word, 0)
+	// Trained words are not part of a sentence, so not Learn()
+	_, err := varnam.learnWord(word, 0)
 	if err != nil {
 		return err
 	}
@@ -340,6 +459,9 @@ func (varnam *Varnam) getWordInfo(word string) (*WordInfo, error) {
 
 // LearnFromFile Learn all words in a file
 func (varnam *Varnam) LearnFromFile(filePath string) (LearnStatus, error) {
+	varnam.lockDict()
+	defer varnam.unlockDict()
+
 	learnStatus := LearnStatus{0, 0}
 
 	file, err := os.Open(filePath)
@@ -348,7 +470,11 @@ func (varnam *Varnam) LearnFromFile(filePath string) (LearnStatus, error) {
 	}
 	defer file.Close()
 
-	limitVariableNumber := sqlite3Conn.GetLimit(sqlite3.SQLITE_LIMIT_VARIABLE_NUMBER)
+	limits, err := getDBLimits(varnam.dictConn)
+	if err != nil {
+		return learnStatus, err
+	}
+	limitVariableNumber := limits.variableNumber
 	log.Printf("default SQLITE_LIMIT_VARIABLE_NUMBER: %d", limitVariableNumber)
 
 	// We have 2 fields per item, word and weight
@@ -368,6 +494,10 @@ func (varnam *Varnam) LearnFromFile(filePath string) (LearnStatus, error) {
 
 	var words []WordInfo
 
+	// Words in the order they appear in text.
+	// Not applicable for frequency report.
+	var sequence []string
+
 	word := ""
 	insertions := 0
 	count := 0
@@ -400,6 +530,7 @@ func (varnam *Varnam) LearnFromFile(filePath string) (LearnStatus, error) {
 				// Not a frequency report, so attempt to learn those 2 words
 				words = append(words, WordInfo{0, word, 0, 0})
 				words = append(words, WordInfo{0, curWord, 0, 0})
+				sequence = append(sequence, word, curWord)
 
 				count++
 			}
@@ -427,16 +558,27 @@ func (varnam *Varnam) LearnFromFile(filePath string) (LearnStatus, error) {
 			}
 		} else {
 			words = append(words, WordInfo{0, curWord, 0, 0})
+			sequence = append(sequence, curWord)
 			count++
 		}
 
 		if count == insertsPerTransaction {
-			learnStatusBatch, err := varnam.LearnMany(words)
+			learnStatusBatch, err := varnam.learnMany(words)
 
 			if err != nil {
 				return learnStatus, err
 			}
 
+			err = varnam.learnWordSequence(sequence)
+			if err != nil {
+				return learnStatus, err
+			}
+
+			// Last word continues the sequence to next batch
+			if len(sequence) > 0 {
+				sequence = sequence[len(sequence)-1:]
+			}
+
 			learnStatus.TotalWords += learnStatusBatch.TotalWords
 			learnStatus.FailedWords += learnStatusBatch.FailedWords
 			insertions += learnStatusBatch.TotalWords
@@ -449,12 +591,17 @@ func (varnam *Varnam) LearnFromFile(filePath string) (LearnStatus, error) {
 	}
 
 	if len(words) != 0 {
-		learnStatusBatch, err := varnam.LearnMany(words)
+		learnStatusBatch, err := varnam.learnMany(words)
 
 		if err != nil {
 			return learnStatus, err
 		}
 
+		err = varnam.learnWordSequence(sequence)
+		if err != nil {
+			return learnStatus, err
+		}
+
 		learnStatus.TotalWords += learnStatusBatch.TotalWords
 		learnStatus.FailedWords += learnStatusBatch.FailedWords
 
@@ -471,6 +618,9 @@ func (varnam *Varnam) LearnFromFile(filePath string) (LearnStatus, error) {
 
 // TrainFromFile Train words with a particular pattern in bulk
 func (varnam *Varnam) TrainFromFile(filePath string) (LearnStatus, error) {
+	varnam.lockDict()
+	defer varnam.unlockDict()
+
 	// The file should have the format :
 	//    pattern word
 	// The separation between pattern and word should just be a single whitespace
@@ -493,7 +643,7 @@ func (varnam *Varnam) TrainFromFile(filePath string) (LearnStatus, error) {
 		if len(wordsInLine) == 2 {
 			learnStatus.TotalWords++
 
-			err := varnam.Train(wordsInLine[0], wordsInLine[1])
+			err := varnam.train(wordsInLine[0], wordsInLine[1])
 			if err != nil {
 				learnStatus.FailedWords++
 				fmt.Printf("Couldn't train %s => %s (%s) \n", wordsInLine[0], wordsInLine[1], err.Error())
@@ -514,121 +664,195 @@ func (varnam *Varnam) TrainFromFile(filePath string) (LearnStatus, error) {
 	return learnStatus, nil
 }
 
-// Get full data from DB
-func rowsToJSON(rows *sql.Rows) ([]map[string]interface{}, error) {
-	// Dumping rows from SQL to JSON
-	// Thanks lucidquiet https://stackoverflow.com/a/17885636/1372424
-	// Thanks turkenh https://stackoverflow.com/a/29164115/1372424
-	// Licensed CC-BY-SA 4.0
-
-	columns, err := rows.Columns()
-	if err != nil {
-		return nil, err
-	}
+// Export learnings as JSON to a file
+func (varnam *Varnam) Export(filePath string, wordsPerFile int) error {
+	return varnam.ExportWithProgress(filePath, wordsPerFile, nil)
+}
 
-	count := len(columns)
-	tableData := make([]map[string]interface{}, 0)
-	values := make([]interface{}, count)
-	valuePtrs := make([]interface{}, count)
+// ExportWithProgress export learnings as JSON to files of
+// wordsPerFile words each, filePath-1.vlf, filePath-2.vlf etc.
+// If progress is not nil, it's called after writing each file.
+func (varnam *Varnam) ExportWithProgress(filePath string, wordsPerFile int, progress func(LearningsProgress)) error {
+	varnam.mutex.RLock()
+	defer varnam.mutex.RUnlock()
 
-	for rows.Next() {
-		for i := 0; i < count; i++ {
-			valuePtrs[i] = &values[i]
-		}
-		rows.Scan(valuePtrs...)
-		entry := make(map[string]interface{})
-		for i, col := range columns {
-			var v interface{}
-			val := values[i]
-			b, ok := val.([]byte)
-			if ok {
-				v = string(b)
-			} else {
-				v = val
-			}
-			entry[col] = v
-		}
-		tableData = append(tableData, entry)
+	if fileExists(filePath) {
+		return fmt.Errorf("Output file already exists")
+	}
+	if wordsPerFile <= 0 {
+		return fmt.Errorf("Words per file should be more than 0")
 	}
 
-	return tableData, nil
-}
+	ctx := context.Background()
 
-// Export learnings as JSON to a file
-func (varnam *Varnam) Export(filePath string, wordsPerFile int) error {
-	if fileExists(filePath) {
-		return fmt.Errorf("Output file already exists")
+	var (
+		unlearnedWords    []exportUnlearnedWord
+		unlearnedPatterns []exportUnlearnedPattern
+	)
+
+	err := queryEach(ctx, varnam.dictConn, "SELECT word, unlearned_on FROM unlearned_words", func(rows *sql.Rows) error {
+		var item exportUnlearnedWord
+		err := rows.Scan(&item.Word, &item.UnlearnedOn)
+		unlearnedWords = append(unlearnedWords, item)
+		return err
+	})
+	if err != nil {
+		return err
 	}
 
-	patternsCount := -1
-	wordsCount := -1
+	err = queryEach(ctx, varnam.dictConn, "SELECT pattern, unlearned_on FROM unlearned_patterns", func(rows *sql.Rows) error {
+		var item exportUnlearnedPattern
+		err := rows.Scan(&item.Pattern, &item.UnlearnedOn)
+		unlearnedPatterns = append(unlearnedPatterns, item)
+		return err
+	})
+	if err != nil {
+		return err
+	}
 
-	countRows, err := varnam.dictConn.Query("SELECT COUNT(*) AS patternsCount FROM patterns UNION SELECT COUNT(*) AS wordsCount FROM words")
+	// One pass through words with their patterns. Rows
+	// of a word are together, heaviest words first.
+	rows, err := varnam.dictConn.QueryContext(ctx, `
+		SELECT w.id, w.word, w.weight, w.learned_on, p.pattern
+		FROM words w
+		LEFT JOIN patterns p ON p.word_id = w.id
+		ORDER BY w.weight DESC, w.id
+	`)
 	if err != nil {
 		return err
 	}
-	defer countRows.Close()
+	defer rows.Close()
 
-	for countRows.Next() {
-		if patternsCount == -1 {
-			countRows.Scan(&patternsCount)
-		} else {
-			countRows.Scan(&wordsCount)
+	var (
+		file      *exportFileWriter
+		page      = 0
+		pageWords = 0
+		done      LearningsProgress
+
+		// Patterns of words in the page are written after them
+		patterns []exportPattern
+	)
+
+	defer func() {
+		if file != nil {
+			file.file.Close()
 		}
-	}
+	}()
 
-	totalPages := int(math.Ceil(float64(wordsCount) / float64(wordsPerFile)))
+	startPage := func() error {
+		page++
 
-	if varnam.Debug {
-		log.Printf("Words: %d. Patterns: %d", wordsCount, patternsCount)
-		log.Printf("Pages: %d", totalPages)
+		var err error
+		file, err = createExportFile(filePath + "-" + fmt.Sprint(page) + ".vlf")
+		if err != nil {
+			return err
+		}
+		return file.beginArray("words")
 	}
 
-	page := 1
-	for page <= totalPages {
-		wordsTableQuery := fmt.Sprintf("SELECT word AS w, weight AS c, learned_on AS l FROM words ORDER BY c DESC LIMIT %d OFFSET %d", wordsPerFile, (page-1)*wordsPerFile)
+	finishPage := func() error {
+		err := file.endArray()
+		if err != nil {
+			return err
+		}
 
-		wordsRows, err := varnam.dictConn.Query(wordsTableQuery)
+		err = file.writeArray("patterns", len(patterns), func(i int) interface{} {
+			return patterns[i]
+		})
 		if err != nil {
 			return err
 		}
-		defer wordsRows.Close()
-
-		wordsData, err := rowsToJSON(wordsRows)
-
-		patternsRows, err := varnam.dictConn.Query(
-			`
-			SELECT pattern AS p, (
-				SELECT word FROM words WHERE words.id = patterns.word_id
-			) AS w
-			FROM patterns
-			WHERE patterns.word_id IN (
-				SELECT id FROM words WHERE word IN (
-					SELECT w FROM (
-						` + wordsTableQuery + `
-					)
-				)
-			)
-			`,
+
+		// Tombstones, in the first file only
+		if page == 1 && len(unlearnedWords) > 0 {
+			err = file.writeArray("unlearned_words", len(unlearnedWords), func(i int) interface{} {
+				return unlearnedWords[i]
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if page == 1 && len(unlearnedPatterns) > 0 {
+			err = file.writeArray("unlearned_patterns", len(unlearnedPatterns), func(i int) interface{} {
+				return unlearnedPatterns[i]
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err = file.close()
+		file = nil
+		if err != nil {
+			return err
+		}
+
+		done.Words += pageWords
+		done.Patterns += len(patterns)
+		pageWords = 0
+		patterns = nil
+
+		if progress != nil {
+			progress(done)
+		}
+		return nil
+	}
+
+	lastID := int64(-1)
+	for rows.Next() {
+		var (
+			id      int64
+			word    exportWord
+			pattern sql.NullString
 		)
+		err = rows.Scan(&id, &word.Word, &word.Weight, &word.LearnedOn, &pattern)
 		if err != nil {
 			return err
 		}
-		defer patternsRows.Close()
 
-		patternsData, err := rowsToJSON(patternsRows)
+		if id != lastID {
+			lastID = id
 
-		output := exportFormat{wordsData, patternsData}
+			if file != nil && pageWords == wordsPerFile {
+				if err = finishPage(); err != nil {
+					return err
+				}
+			}
+			if file == nil {
+				if err = startPage(); err != nil {
+					return err
+				}
+			}
 
-		jsonData, err := json.Marshal(output)
+			if err = file.writeItem(word); err != nil {
+				return err
+			}
+			pageWords++
+		}
 
-		filePathWithPageNumber := filePath + "-" + fmt.Sprint(page) + ".vlf"
-		err = os.WriteFile(filePathWithPageNumber, jsonData, 0644)
-		if err != nil {
+		if pattern.Valid {
+			patterns = append(patterns, exportPattern{pattern.String, word.Word})
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	// Tombstones are exported even if there are no words
+	if file == nil && len(unlearnedWords)+len(unlearnedPatterns) > 0 {
+		if err = startPage(); err != nil {
+			return err
+		}
+	}
+	if file != nil {
+		if err = finishPage(); err != nil {
 			return err
 		}
+	}
 
-		page++
+	if varnam.Debug {
+		log.Printf("Words: %d. Patterns: %d", done.Words, done.Patterns)
+		log.Printf("Pages: %d", page)
 	}
 
 	return nil
@@ -636,105 +860,107 @@ func (varnam *Varnam) Export(filePath string, wordsPerFile int) error {
 
 // Import learnings from file
 func (varnam *Varnam) Import(filePath string) error {
+	return varnam.ImportWithProgress(filePath, nil)
+}
+
+// ImportWithProgress import learnings from a file made by
+// Export(). Words already in dictionary are left as they are,
+// MergeLearnings() updates them. If progress is not nil, it's
+// called after every batch of words or patterns inserted.
+func (varnam *Varnam) ImportWithProgress(filePath string, progress func(LearningsProgress)) error {
+	varnam.lockDict()
+	defer varnam.unlockDict()
+
 	if !fileExists(filePath) {
 		return fmt.Errorf("Import file not found")
 	}
 
-	// TODO better reading of JSON. This loads entire file into memory
-	fileContent, _ := os.ReadFile(filePath)
-
-	var dbData exportFormat
+	limits, err := getDBLimits(varnam.dictConn)
+	if err != nil {
+		return err
+	}
+	limitVariableNumber := limits.variableNumber
 
-	if err := json.Unmarshal(fileContent, &dbData); err != nil {
-		return fmt.Errorf("Parsing JSON failed, err: %s", err.Error())
+	if varnam.Debug {
+		log.Printf("default SQLITE_LIMIT_VARIABLE_NUMBER: %d", limitVariableNumber)
 	}
 
-	limitVariableNumber := sqlite3Conn.GetLimit(sqlite3.SQLITE_LIMIT_VARIABLE_NUMBER)
-	log.Printf("default SQLITE_LIMIT_VARIABLE_NUMBER: %d", limitVariableNumber)
+	ctx := context.Background()
 
-	insertsPerTransaction := int(math.Min(
-		float64(limitVariableNumber)/4, // We have 4 fields per item
-		float64(len(dbData.WordsDict)),
-	))
+	tx, err := varnam.dictConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	var (
-		args   []interface{}
-		values []string
-	)
+	var done LearningsProgress
 
-	insertions := 0
-	count := 0
-	for i, item := range dbData.WordsDict {
-		values = append(values, "(trim(?), ?, ?)")
-		args = append(args, item["w"], item["c"], item["l"])
-
-		count++
-		if count == insertsPerTransaction || i == len(dbData.WordsDict)-1 {
-			query := fmt.Sprintf(
-				"INSERT OR IGNORE INTO words(word, weight, learned_on) VALUES %s",
-				strings.Join(values, ", "),
-			)
-
-			stmt, err := varnam.dictConn.Prepare(query)
-			if err != nil {
-				return err
+	words := &insertBatch{
+		tx:    tx,
+		query: "INSERT OR IGNORE INTO words(word, weight, learned_on) VALUES %s",
+		value: "(trim(?), ?, ?)",
+		size:  limitVariableNumber / 3, // We have 3 fields per item
+		flushed: func(count int) {
+			done.Words += count
+			if progress != nil {
+				progress(done)
 			}
-
-			_, err = stmt.Exec(args...)
-			if err != nil {
-				return err
+		},
+	}
+
+	patterns := &insertBatch{
+		tx:    tx,
+		query: "INSERT OR IGNORE INTO patterns(pattern, word_id) VALUES %s",
+		value: "(?, (SELECT id FROM words WHERE word = ?))",
+		size:  limitVariableNumber / 2, // We have 2 fields per item
+		flushed: func(count int) {
+			done.Patterns += count
+			if progress != nil {
+				progress(done)
 			}
-
-			args = nil
-			values = nil
-
-			insertions += count
-			count = 0
-
-			fmt.Printf("Inserted %d words\n", insertions)
-		}
+		},
+		dependsOn: words,
 	}
 
-	args = nil
-	values = nil
-
-	insertsPerTransaction = int(math.Min(
-		float64(limitVariableNumber)/2, // We have 2 fields per item
-		float64(len(dbData.PatternsDict)),
-	))
-
-	insertions = 0
-	count = 0
-	for i, item := range dbData.PatternsDict {
-		values = append(values, "(?, (SELECT id FROM words WHERE word = ?))")
-		args = append(args, item["p"], item["w"])
-
-		count++
-		if count == insertsPerTransaction || i == len(dbData.WordsDict)-1 {
-			query := fmt.Sprintf(
-				"INSERT OR IGNORE INTO patterns(pattern, word_id) VALUES %s",
-				strings.Join(values, ", "),
-			)
+	phoneticKeys := &insertBatch{
+		tx:        tx,
+		query:     "INSERT OR IGNORE INTO word_phonetic_keys(scheme_id, key, word_id) VALUES %s",
+		value:     "(?, ?, (SELECT id FROM words WHERE word = ?))",
+		size:      limitVariableNumber / 3,
+		dependsOn: words,
+	}
 
-			stmt, err := varnam.dictConn.Prepare(query)
+	err = exportFileReader{
+		onWord: func(item exportWord) error {
+			err := words.add(ctx, item.Word, item.Weight, item.LearnedOn)
 			if err != nil {
 				return err
 			}
 
-			_, err = stmt.Exec(args...)
-			if err != nil {
-				return err
+			word := strings.TrimSpace(item.Word)
+			key := varnam.getWordPhoneticKey(ctx, word)
+			if key == "" {
+				return nil
 			}
+			return phoneticKeys.add(ctx, varnam.SchemeDetails.Identifier, key, word)
+		},
+		onPattern: func(item exportPattern) error {
+			return patterns.add(ctx, item.Pattern, item.Word)
+		},
+	}.read(filePath)
+	if err != nil {
+		return err
+	}
 
-			args = nil
-			values = nil
-
-			insertions += count
-			count = 0
-
-			fmt.Printf("Inserted %d patterns\n", insertions)
-		}
+	if err = words.flush(ctx); err != nil {
+		return err
+	}
+	if err = patterns.flush(ctx); err != nil {
+		return err
+	}
+	if err = phoneticKeys.flush(ctx); err != nil {
+		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
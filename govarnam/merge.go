@@ -0,0 +1,478 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+/**
+ * Merging learnings of another machine. Import only adds words
+ * that aren't there, so weights, learnt times & unlearning are not
+ * synced with it. MergeLearnings reconciles the dictionary in use
+ * with another dictionary or a .vlf export, word by word:
+ *
+ * - A word in both gets the later learned_on, and the maximum or
+ *   the sum of weights (VARNAM_MERGE_WEIGHT_*).
+ * - Unlearning leaves a tombstone with the time in unlearned_words
+ *   or unlearned_patterns. A word learnt till the tombstone's time
+ *   is removed, a word learnt after it stays. Last writer wins.
+ *
+ * Summing counts the weights of both again on every merge, it's
+ * for merging different learnings once. Max can be repeated, and
+ * merging both ways makes two dictionaries the same.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// MergeReport changes made by MergeLearnings,
+// or to be made in a dry run
+type MergeReport struct {
+	AddedWords   []string
+	UpdatedWords []string // Weight or learnt time changed
+	RemovedWords []string
+
+	AddedPatterns int
+	// Including the patterns of removed words
+	RemovedPatterns int
+}
+
+type mergeWord struct {
+	weight    int
+	learnedOn int
+}
+
+type mergePattern struct {
+	pattern string
+	word    string
+}
+
+// Patterns are case insensitive
+func (pattern mergePattern) key() mergePattern {
+	return mergePattern{strings.ToLower(pattern.pattern), pattern.word}
+}
+
+// Learnings of a dictionary needed for merging
+type mergeLearnings struct {
+	words    map[string]mergeWord
+	patterns []mergePattern
+
+	// Tombstones, unlearnt time of words & patterns.
+	// Patterns are in lowercase.
+	unlearnedWords    map[string]int
+	unlearnedPatterns map[string]int
+}
+
+func newMergeLearnings() mergeLearnings {
+	return mergeLearnings{
+		words:             make(map[string]mergeWord),
+		unlearnedWords:    make(map[string]int),
+		unlearnedPatterns: make(map[string]int),
+	}
+}
+
+func isSQLiteFile(filePath string) (bool, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	header := make([]byte, 16)
+	_, err = io.ReadFull(file, header)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return false, nil
+	}
+
+	return string(header) == "SQLite format 3\x00", err
+}
+
+func tableExists(ctx context.Context, conn *sql.DB, table string) (bool, error) {
+	var count int
+	err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count)
+	return count > 0, err
+}
+
+// Run query and call scan for each row
+func queryEach(ctx context.Context, conn *sql.DB, query string, scan func(rows *sql.Rows) error) error {
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = scan(rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func loadMergeLearningsFromDB(ctx context.Context, conn *sql.DB) (mergeLearnings, error) {
+	learnings := newMergeLearnings()
+
+	err := queryEach(ctx, conn, "SELECT word, weight, COALESCE(learned_on, 0) FROM words", func(rows *sql.Rows) error {
+		var (
+			word string
+			item mergeWord
+		)
+		err := rows.Scan(&word, &item.weight, &item.learnedOn)
+		learnings.words[word] = item
+		return err
+	})
+	if err != nil {
+		return learnings, err
+	}
+
+	err = queryEach(ctx, conn, "SELECT p.pattern, w.word FROM patterns p JOIN words w ON w.id = p.word_id", func(rows *sql.Rows) error {
+		var item mergePattern
+		err := rows.Scan(&item.pattern, &item.word)
+		learnings.patterns = append(learnings.patterns, item)
+		return err
+	})
+	if err != nil {
+		return learnings, err
+	}
+
+	// Dictionaries made before tombstones don't have them
+	for table, tombstones := range map[string]map[string]int{
+		"unlearned_words":    learnings.unlearnedWords,
+		"unlearned_patterns": learnings.unlearnedPatterns,
+	} {
+		exists, err := tableExists(ctx, conn, table)
+		if err != nil {
+			return learnings, err
+		}
+		if !exists {
+			continue
+		}
+
+		column := "word"
+		if table == "unlearned_patterns" {
+			column = "LOWER(pattern)"
+		}
+
+		err = queryEach(ctx, conn, "SELECT "+column+", unlearned_on FROM "+table, func(rows *sql.Rows) error {
+			var (
+				key         string
+				unlearnedOn int
+			)
+			err := rows.Scan(&key, &unlearnedOn)
+			tombstones[key] = unlearnedOn
+			return err
+		})
+		if err != nil {
+			return learnings, err
+		}
+	}
+
+	return learnings, nil
+}
+
+func loadMergeLearningsFromFile(ctx context.Context, filePath string) (mergeLearnings, error) {
+	learnings := newMergeLearnings()
+
+	isSQLite, err := isSQLiteFile(filePath)
+	if err != nil {
+		return learnings, err
+	}
+
+	if isSQLite {
+		conn, err := openDB(getReadOnlyURI(filePath))
+		if err != nil {
+			return learnings, err
+		}
+		defer conn.Close()
+
+		return loadMergeLearningsFromDB(ctx, conn)
+	}
+
+	err = exportFileReader{
+		onWord: func(item exportWord) error {
+			word := mergeWord{weight: item.Weight}
+			if item.LearnedOn != nil {
+				word.learnedOn = int(*item.LearnedOn)
+			}
+			learnings.words[strings.TrimSpace(item.Word)] = word
+			return nil
+		},
+		onPattern: func(item exportPattern) error {
+			learnings.patterns = append(learnings.patterns, mergePattern{item.Pattern, strings.TrimSpace(item.Word)})
+			return nil
+		},
+		onUnlearnedWord: func(item exportUnlearnedWord) error {
+			learnings.unlearnedWords[item.Word] = item.UnlearnedOn
+			return nil
+		},
+		onUnlearnedPattern: func(item exportUnlearnedPattern) error {
+			learnings.unlearnedPatterns[strings.ToLower(item.Pattern)] = item.UnlearnedOn
+			return nil
+		},
+	}.read(filePath)
+	if err != nil {
+		return learnings, err
+	}
+
+	return learnings, nil
+}
+
+// Changes to make for merging learnings
+type mergePlan struct {
+	report MergeReport
+
+	// Words after merge
+	words map[string]mergeWord
+
+	unlearnedWords    map[string]int
+	addPatterns       []mergePattern
+	removePatterns    map[string]int
+	unlearnedPatterns map[string]int
+}
+
+// Find what changes when theirs is merged into ours
+func planMerge(ours mergeLearnings, theirs mergeLearnings, strategy int) mergePlan {
+	plan := mergePlan{
+		words:             make(map[string]mergeWord, len(ours.words)),
+		unlearnedWords:    make(map[string]int),
+		removePatterns:    make(map[string]int),
+		unlearnedPatterns: make(map[string]int),
+	}
+
+	for word, item := range ours.words {
+		plan.words[word] = item
+	}
+
+	for word, item := range theirs.words {
+		ourItem, found := ours.words[word]
+		if !found {
+			if unlearnedOn, unlearned := ours.unlearnedWords[word]; unlearned && unlearnedOn >= item.learnedOn {
+				continue
+			}
+			plan.words[word] = item
+			plan.report.AddedWords = append(plan.report.AddedWords, word)
+			continue
+		}
+
+		merged := ourItem
+		if item.learnedOn > merged.learnedOn {
+			merged.learnedOn = item.learnedOn
+		}
+		switch strategy {
+		case VARNAM_MERGE_WEIGHT_SUM:
+			merged.weight += item.weight
+		case VARNAM_MERGE_WEIGHT_MAX:
+			if item.weight > merged.weight {
+				merged.weight = item.weight
+			}
+		}
+
+		if merged != ourItem {
+			plan.words[word] = merged
+			plan.report.UpdatedWords = append(plan.report.UpdatedWords, word)
+		}
+	}
+
+	for word, unlearnedOn := range theirs.unlearnedWords {
+		if item, found := plan.words[word]; found {
+			if item.learnedOn > unlearnedOn {
+				continue
+			}
+			delete(plan.words, word)
+			plan.report.RemovedWords = append(plan.report.RemovedWords, word)
+		}
+
+		if ourUnlearnedOn, found := ours.unlearnedWords[word]; !found || unlearnedOn > ourUnlearnedOn {
+			plan.unlearnedWords[word] = unlearnedOn
+		}
+	}
+
+	// Patterns left after removing words & unlearnt patterns
+	patterns := make(map[mergePattern]bool)
+	remainingPatterns := make(map[string]bool)
+	for _, item := range ours.patterns {
+		key := item.key()
+		patterns[key] = true
+
+		wordItem, found := plan.words[item.word]
+		unlearnedOn, unlearned := theirs.unlearnedPatterns[key.pattern]
+
+		if !found || (unlearned && wordItem.learnedOn <= unlearnedOn) {
+			plan.report.RemovedPatterns++
+			continue
+		}
+		remainingPatterns[key.pattern] = true
+	}
+
+	for _, item := range theirs.patterns {
+		key := item.key()
+		if patterns[key] {
+			continue
+		}
+		patterns[key] = true
+
+		wordItem, found := plan.words[item.word]
+		if !found {
+			continue
+		}
+		if unlearnedOn, unlearned := ours.unlearnedPatterns[key.pattern]; unlearned && unlearnedOn >= wordItem.learnedOn {
+			continue
+		}
+
+		plan.addPatterns = append(plan.addPatterns, item)
+		remainingPatterns[key.pattern] = true
+	}
+	plan.report.AddedPatterns = len(plan.addPatterns)
+
+	for pattern, unlearnedOn := range theirs.unlearnedPatterns {
+		plan.removePatterns[pattern] = unlearnedOn
+
+		// A pattern learnt after the tombstone stays
+		if remainingPatterns[pattern] {
+			continue
+		}
+		if ourUnlearnedOn, found := ours.unlearnedPatterns[pattern]; !found || unlearnedOn > ourUnlearnedOn {
+			plan.unlearnedPatterns[pattern] = unlearnedOn
+		}
+	}
+
+	sort.Strings(plan.report.AddedWords)
+	sort.Strings(plan.report.UpdatedWords)
+	sort.Strings(plan.report.RemovedWords)
+
+	return plan
+}
+
+// Make the changes of plan in dictionary
+func (varnam *Varnam) applyMerge(ctx context.Context, plan mergePlan) error {
+	conn, err := varnam.dictConn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Patterns & phonetic keys of removed words go with them.
+	// Can't be changed inside a transaction.
+	_, err = conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	if err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	exec := func(query string, args ...interface{}) error {
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	}
+
+	for _, word := range plan.report.AddedWords {
+		item := plan.words[word]
+		if err = exec("INSERT INTO words(word, weight, learned_on) VALUES (?, ?, ?)", word, item.weight, item.learnedOn); err != nil {
+			return err
+		}
+	}
+
+	for _, word := range plan.report.UpdatedWords {
+		item := plan.words[word]
+		if err = exec("UPDATE words SET weight = ?, learned_on = ? WHERE word = ?", item.weight, item.learnedOn, word); err != nil {
+			return err
+		}
+	}
+
+	for _, word := range plan.report.RemovedWords {
+		if err = exec("DELETE FROM words WHERE word = ?", word); err != nil {
+			return err
+		}
+	}
+
+	for word, unlearnedOn := range plan.unlearnedWords {
+		if err = exec("INSERT OR REPLACE INTO unlearned_words(word, unlearned_on) VALUES (?, ?)", word, unlearnedOn); err != nil {
+			return err
+		}
+	}
+
+	for pattern, unlearnedOn := range plan.removePatterns {
+		if err = exec("DELETE FROM patterns WHERE pattern = ? AND word_id IN (SELECT id FROM words WHERE COALESCE(learned_on, 0) <= ?)", pattern, unlearnedOn); err != nil {
+			return err
+		}
+	}
+
+	for pattern, unlearnedOn := range plan.unlearnedPatterns {
+		if err = exec("INSERT OR REPLACE INTO unlearned_patterns(pattern, unlearned_on) VALUES (?, ?)", pattern, unlearnedOn); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range plan.addPatterns {
+		if err = exec("INSERT OR IGNORE INTO patterns(pattern, word_id) SELECT ?, id FROM words WHERE word = ?", item.pattern, item.word); err != nil {
+			return err
+		}
+	}
+
+	err = varnam.learnPhoneticKeys(ctx, tx, plan.report.AddedWords)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MergeLearnings merge learnings of another dictionary or a .vlf
+// file made by Export() into the dictionary in use. Weights of
+// words in both are combined by strategy, one of
+// VARNAM_MERGE_WEIGHT_*. With dryRun, nothing is changed and the
+// report tells what would change. See merge.go
+func (varnam *Varnam) MergeLearnings(filePath string, strategy int, dryRun bool) (MergeReport, error) {
+	if dryRun {
+		varnam.mutex.RLock()
+		defer varnam.mutex.RUnlock()
+	} else {
+		varnam.lockDict()
+		defer varnam.unlockDict()
+	}
+
+	ctx := context.Background()
+
+	if strategy != VARNAM_MERGE_WEIGHT_MAX && strategy != VARNAM_MERGE_WEIGHT_SUM {
+		return MergeReport{}, fmt.Errorf("unknown merge strategy %d", strategy)
+	}
+	if !fileExists(filePath) {
+		return MergeReport{}, fmt.Errorf("Merge file not found")
+	}
+	if filePath == varnam.DictPath {
+		return MergeReport{}, fmt.Errorf("can't merge the dictionary in use with itself")
+	}
+
+	theirs, err := loadMergeLearningsFromFile(ctx, filePath)
+	if err != nil {
+		return MergeReport{}, err
+	}
+
+	ours, err := loadMergeLearningsFromDB(ctx, varnam.dictConn)
+	if err != nil {
+		return MergeReport{}, err
+	}
+
+	plan := planMerge(ours, theirs, strategy)
+
+	if dryRun {
+		return plan.report, nil
+	}
+
+	return plan.report, varnam.applyMerge(ctx, plan)
+}
@@ -0,0 +1,136 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func getMergeWord(t *testing.T, varnam *Varnam, word string) (mergeWord, bool) {
+	learnings, err := loadMergeLearningsFromDB(context.Background(), varnam.dictConn)
+	checkError(err)
+
+	item, found := learnings.words[word]
+	return item, found
+}
+
+func TestMergeLearnings(t *testing.T) {
+	ours := makeTestInstance(t, "ml", t.TempDir())
+	theirs := makeTestInstance(t, "ml", t.TempDir())
+
+	checkError(ours.Learn("വയൽ", 0))
+	checkError(ours.Learn("തത്ത", 0))
+	setLearnedOn(ours, "വയൽ", 100)
+	setLearnedOn(ours, "തത്ത", 100)
+
+	checkError(theirs.Learn("തത്ത", 0))
+	checkError(theirs.Learn("തത്ത", 0))
+	checkError(theirs.Train("field", "മലയാളം"))
+	setLearnedOn(theirs, "തത്ത", 200)
+	setLearnedOn(theirs, "മലയാളം", 200)
+
+	// Unlearnt by them after we learnt it
+	checkError(theirs.Learn("വയൽ", 0))
+	checkError(theirs.Unlearn("വയൽ"))
+	_, err := theirs.dictConn.Exec("UPDATE unlearned_words SET unlearned_on = 150")
+	checkError(err)
+
+	ourWeight := getLayerWeight(t, ours, "തത്ത")
+	theirWeight := getLayerWeight(t, theirs, "തത്ത")
+	assertEqual(t, ourWeight < theirWeight, true)
+
+	_, err = ours.MergeLearnings(ours.DictPath, VARNAM_MERGE_WEIGHT_MAX, true)
+	assertEqual(t, err != nil, true)
+
+	_, err = ours.MergeLearnings(theirs.DictPath, 42, true)
+	assertEqual(t, err != nil, true)
+
+	expected := MergeReport{
+		AddedWords:    []string{"മലയാളം"},
+		UpdatedWords:  []string{"തത്ത"},
+		RemovedWords:  []string{"വയൽ"},
+		AddedPatterns: 1,
+	}
+
+	report, err := ours.MergeLearnings(theirs.DictPath, VARNAM_MERGE_WEIGHT_MAX, true)
+	checkError(err)
+	assertEqual(t, reflect.DeepEqual(report, expected), true)
+
+	// Dry run changes nothing
+	_, found := getMergeWord(t, ours, "വയൽ")
+	assertEqual(t, found, true)
+
+	report, err = ours.MergeLearnings(theirs.DictPath, VARNAM_MERGE_WEIGHT_MAX, false)
+	checkError(err)
+	assertEqual(t, reflect.DeepEqual(report, expected), true)
+
+	item, _ := getMergeWord(t, ours, "തത്ത")
+	assertEqual(t, item, mergeWord{theirWeight, 200})
+
+	_, found = getMergeWord(t, ours, "വയൽ")
+	assertEqual(t, found, false)
+
+	sugs := ours.getFromPatternDictionary(context.Background(), "field")
+	assertEqual(t, len(sugs), 1)
+	assertEqual(t, sugs[0].Sug.Word, "മലയാളം")
+
+	// Merging again with max changes nothing
+	report, err = ours.MergeLearnings(theirs.DictPath, VARNAM_MERGE_WEIGHT_MAX, false)
+	checkError(err)
+	assertEqual(t, reflect.DeepEqual(report, MergeReport{}), true)
+
+	report, err = ours.MergeLearnings(theirs.DictPath, VARNAM_MERGE_WEIGHT_SUM, false)
+	checkError(err)
+	assertEqual(t, reflect.DeepEqual(report.UpdatedWords, []string{"തത്ത", "മലയാളം"}), true)
+	assertEqual(t, getLayerWeight(t, ours, "തത്ത"), theirWeight*2)
+
+	// Learnt after their tombstone, it stays
+	checkError(ours.Learn("വയൽ", 0))
+	report, err = ours.MergeLearnings(theirs.DictPath, VARNAM_MERGE_WEIGHT_MAX, false)
+	checkError(err)
+	assertEqual(t, len(report.RemovedWords), 0)
+
+	// Their words unlearnt by us later don't come back
+	checkError(ours.Unlearn("field"))
+	checkError(ours.Unlearn("മലയാളം"))
+
+	report, err = ours.MergeLearnings(theirs.DictPath, VARNAM_MERGE_WEIGHT_MAX, true)
+	checkError(err)
+	assertEqual(t, len(report.AddedWords), 0)
+	assertEqual(t, report.AddedPatterns, 0)
+
+	// Tombstones go through exports
+	exportPath := path.Join(t.TempDir(), "export")
+	checkError(theirs.Export(exportPath, 100))
+
+	fresh := makeTestInstance(t, "ml", t.TempDir())
+	checkError(fresh.Learn("വയൽ", 0))
+	setLearnedOn(fresh, "വയൽ", 100)
+
+	report, err = fresh.MergeLearnings(exportPath+"-1.vlf", VARNAM_MERGE_WEIGHT_MAX, false)
+	checkError(err)
+	assertEqual(t, reflect.DeepEqual(report, MergeReport{
+		AddedWords:    []string{"തത്ത", "മലയാളം"},
+		RemovedWords:  []string{"വയൽ"},
+		AddedPatterns: 1,
+	}), true)
+
+	// and are merged on to others
+	var unlearnedOn int
+	checkError(fresh.dictConn.QueryRow("SELECT unlearned_on FROM unlearned_words WHERE word = ?", "വയൽ").Scan(&unlearnedOn))
+	assertEqual(t, unlearnedOn, 150)
+
+	// Unlearnt patterns of theirs are removed from ours
+	checkError(fresh.Unlearn("field"))
+	report, err = theirs.MergeLearnings(fresh.DictPath, VARNAM_MERGE_WEIGHT_MAX, false)
+	checkError(err)
+	assertEqual(t, report.RemovedPatterns, 1)
+	assertEqual(t, len(theirs.getFromPatternDictionary(context.Background(), "field")), 0)
+}
@@ -0,0 +1,14 @@
+-- Word sequences for context-aware ranking.
+-- count is the number of times word_id was learnt right after prev_word_id
+
+CREATE TABLE IF NOT EXISTS word_bigrams (
+  prev_word_id INTEGER NOT NULL,
+  word_id INTEGER NOT NULL,
+  count INTEGER DEFAULT 1,
+  learned_on INTEGER,
+  FOREIGN KEY(prev_word_id) REFERENCES words(id) ON DELETE CASCADE,
+  FOREIGN KEY(word_id) REFERENCES words(id) ON DELETE CASCADE,
+  PRIMARY KEY(prev_word_id, word_id)
+);
+
+CREATE INDEX IF NOT EXISTS index_word_bigrams_word_id ON word_bigrams (word_id);
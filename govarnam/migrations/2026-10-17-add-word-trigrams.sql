@@ -0,0 +1,18 @@
+-- Word sequences of 3 words for predicting next word.
+-- count is the number of times word_id was learnt right after
+-- prev_prev_word_id & prev_word_id
+
+CREATE TABLE IF NOT EXISTS word_trigrams (
+  prev_prev_word_id INTEGER NOT NULL,
+  prev_word_id INTEGER NOT NULL,
+  word_id INTEGER NOT NULL,
+  count INTEGER DEFAULT 1,
+  learned_on INTEGER,
+  FOREIGN KEY(prev_prev_word_id) REFERENCES words(id) ON DELETE CASCADE,
+  FOREIGN KEY(prev_word_id) REFERENCES words(id) ON DELETE CASCADE,
+  FOREIGN KEY(word_id) REFERENCES words(id) ON DELETE CASCADE,
+  PRIMARY KEY(prev_prev_word_id, prev_word_id, word_id)
+);
+
+CREATE INDEX IF NOT EXISTS index_word_trigrams_prev_word_id ON word_trigrams (prev_word_id);
+CREATE INDEX IF NOT EXISTS index_word_trigrams_word_id ON word_trigrams (word_id);
@@ -0,0 +1,13 @@
+-- Phonetic keys of words for matching loosely spelled input.
+-- Key is made from the patterns of the word in scheme_id, so
+-- each scheme sharing the dictionary has its own keys.
+
+CREATE TABLE IF NOT EXISTS word_phonetic_keys (
+  scheme_id TEXT NOT NULL,
+  key TEXT NOT NULL,
+  word_id INTEGER NOT NULL,
+  FOREIGN KEY(word_id) REFERENCES words(id) ON DELETE CASCADE,
+  PRIMARY KEY(scheme_id, key, word_id)
+);
+
+CREATE INDEX IF NOT EXISTS index_word_phonetic_keys_word_id ON word_phonetic_keys (word_id);
@@ -0,0 +1,13 @@
+-- Words unlearnt from the dictionary. Attached read only
+-- dictionaries can't be changed, so their words are hidden
+-- when they're in here. Learning the word again removes it.
+
+CREATE TABLE IF NOT EXISTS unlearned_words (
+  word TEXT PRIMARY KEY,
+  unlearned_on INTEGER NOT NULL
+);
+
+CREATE TRIGGER IF NOT EXISTS words_unlearned_ai AFTER INSERT ON words
+  BEGIN
+    DELETE FROM unlearned_words WHERE word = new.word;
+  END;
@@ -0,0 +1,14 @@
+-- Patterns unlearnt from the dictionary. With unlearned_words,
+-- these are tombstones for merging learnings of another machine
+-- so that unlearning is synced too. Learning the pattern again
+-- removes it.
+
+CREATE TABLE IF NOT EXISTS unlearned_patterns (
+  pattern TEXT PRIMARY KEY COLLATE NOCASE,
+  unlearned_on INTEGER NOT NULL
+);
+
+CREATE TRIGGER IF NOT EXISTS patterns_unlearned_ai AFTER INSERT ON patterns
+  BEGIN
+    DELETE FROM unlearned_patterns WHERE pattern = new.pattern;
+  END;
@@ -0,0 +1,221 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+/**
+ * Phonetic matching. People write the same sound in many ways,
+ * "thh", "th", "t" & "d" for example. A phonetic key is a latin
+ * skeleton of a word with these differences removed. Keys of
+ * learnt words are made from their patterns (reverse
+ * transliteration) and kept in word_phonetic_keys. Words whose key
+ * starts with the key of input are suggested.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+// Letters written for the same sound. Letters
+// not here are their own class.
+var phoneticClasses = map[rune]rune{
+	'd': 't',
+	'g': 'k',
+	'q': 'k',
+	'b': 'p',
+	'f': 'p',
+	'w': 'v',
+}
+
+func isLatinVowel(r rune) bool {
+	return strings.ContainsRune("aeiou", r)
+}
+
+// Get phonetic key of latin text. Aspiration (h after a consonant)
+// is removed, vowels are made "a" and same letters in a row are
+// made one. Eg: "thaththa", "thatha", "dada" => "tata"
+func getPhoneticKey(text string) string {
+	var (
+		key  []rune
+		prev rune
+	)
+
+	for _, r := range strings.ToLower(text) {
+		if r >= utf8.RuneSelf || r < 'a' || r > 'z' {
+			continue
+		}
+
+		if r == 'h' && prev != 0 && !isLatinVowel(prev) {
+			continue
+		}
+		prev = r
+
+		class := r
+		if isLatinVowel(r) {
+			class = 'a'
+		} else if c, found := phoneticClasses[r]; found {
+			class = c
+		}
+
+		if len(key) > 0 && key[len(key)-1] == class {
+			continue
+		}
+		key = append(key, class)
+	}
+
+	return string(key)
+}
+
+// Get phonetic key of a word in the language. Made from the
+// patterns of its conjuncts. Empty if word has other characters.
+func (varnam *Varnam) getWordPhoneticKey(ctx context.Context, word string) string {
+	var pattern strings.Builder
+
+	for _, token := range varnam.splitTextByConjunct(ctx, word) {
+		if token.tokenType != VARNAM_TOKEN_SYMBOL || len(token.symbols) == 0 {
+			return ""
+		}
+
+		// Exact match is the usual way of writing it
+		symbol := token.symbols[0]
+		for _, s := range token.symbols {
+			if s.MatchType == VARNAM_MATCH_EXACT {
+				symbol = s
+				break
+			}
+		}
+
+		pattern.WriteString(symbol.Pattern)
+	}
+
+	return getPhoneticKey(pattern.String())
+}
+
+// Add phonetic keys of words in dictionary to index. Done in
+// the transaction that adds the words, so that both are kept.
+func (varnam *Varnam) learnPhoneticKeys(ctx context.Context, tx *sql.Tx, words []string) error {
+	stmt, err := tx.PrepareContext(ctx, "INSERT OR IGNORE INTO word_phonetic_keys(scheme_id, key, word_id) SELECT ?, ?, id FROM words WHERE word = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, word := range words {
+		key := varnam.getWordPhoneticKey(ctx, word)
+		if key == "" {
+			continue
+		}
+
+		_, err = stmt.ExecContext(ctx, varnam.SchemeDetails.Identifier, key, word)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Make phonetic keys of all words for the scheme again
+func (varnam *Varnam) reIndexPhoneticKeys(ctx context.Context) error {
+	tx, err := varnam.dictConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM word_phonetic_keys WHERE scheme_id = ?", varnam.SchemeDetails.Identifier)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT word FROM words")
+	if err != nil {
+		return err
+	}
+
+	var words []string
+	for rows.Next() {
+		var word string
+		err = rows.Scan(&word)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		words = append(words, word)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	err = varnam.learnPhoneticKeys(ctx, tx, words)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get learnt words whose phonetic key starts with that of word.
+// Words with the same key come first.
+func (varnam *Varnam) getFromPhoneticIndex(ctx context.Context, word string) []Suggestion {
+	var results []Suggestion
+
+	if !isLatinWord(word) {
+		return results
+	}
+
+	key := getPhoneticKey(word)
+	if key == "" {
+		return results
+	}
+
+	select {
+	case <-ctx.Done():
+		return results
+	default:
+		// Keys are in a-z, so all keys starting
+		// with key are less than key + "{"
+		rows, err := varnam.dictConn.QueryContext(
+			ctx,
+			`SELECT w.word, w.weight, w.learned_on FROM word_phonetic_keys k
+			JOIN words w ON w.id = k.word_id
+			WHERE k.scheme_id = ? AND k.key >= ? AND k.key < ?
+			ORDER BY k.key = ? DESC, w.weight DESC LIMIT ?`,
+			varnam.SchemeDetails.Identifier,
+			key,
+			key+"{",
+			key,
+			varnam.DictionarySuggestionsLimit,
+		)
+		if err != nil {
+			log.Print(err)
+			return results
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				word      string
+				weight    int
+				learnedOn int
+			)
+			rows.Scan(&word, &weight, &learnedOn)
+			results = append(results, varnam.makeLearnedSuggestion(word, weight, learnedOn))
+		}
+
+		if err = rows.Err(); err != nil {
+			log.Print(err)
+		}
+
+		return results
+	}
+}
@@ -0,0 +1,92 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetPhoneticKey(t *testing.T) {
+	for input, key := range map[string]string{
+		"thaththa":   "tata",
+		"thatha":     "tata",
+		"dada":       "tata",
+		"tatta":      "tata",
+		"malayaaLam": "malayalam",
+		"bharatham":  "paratam",
+		"hari":       "hara",
+		"":           "",
+		"123":        "",
+	} {
+		assertEqual(t, getPhoneticKey(input), key)
+	}
+}
+
+func TestPhoneticMatch(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+	ctx := context.Background()
+
+	assertEqual(t, varnam.getWordPhoneticKey(ctx, "തത്ത"), "tata")
+	assertEqual(t, varnam.getWordPhoneticKey(ctx, "മലയാളം"), "malayalam")
+
+	checkError(varnam.Learn("തത്ത", 0))
+
+	hasWord := func(sugs []Suggestion, word string) bool {
+		for _, sug := range sugs {
+			if sug.Word == word {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Disabled by default
+	assertEqual(t, hasWord(varnam.Transliterate("dada"), "തത്ത"), false)
+
+	varnam.PhoneticMatch = true
+
+	for _, input := range []string{"thatha", "dada", "tatta", "tat"} {
+		sugs := varnam.TransliterateAdvanced(input).DictionarySuggestions
+		assertEqual(t, hasWord(sugs, "തത്ത"), true)
+	}
+
+	assertEqual(t, hasWord(varnam.Transliterate("vayal"), "തത്ത"), false)
+
+	// Keys are removed with the word
+	checkError(varnam.Unlearn("തത്ത"))
+	assertEqual(t, hasWord(varnam.Transliterate("dada"), "തത്ത"), false)
+
+	// Keys of words learnt without them are made by reindexing
+	_, err := varnam.dictConn.Exec("INSERT INTO words(word, weight, learned_on) VALUES ('തത്ത', 30, 0)")
+	checkError(err)
+	assertEqual(t, hasWord(varnam.Transliterate("dada"), "തത്ത"), false)
+
+	checkError(varnam.ReIndexDictionary())
+	assertEqual(t, hasWord(varnam.Transliterate("dada"), "തത്ത"), true)
+}
+
+// Word isn't stored when its phonetic key can't be
+func TestPhoneticKeyLearntWithWord(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+
+	_, err := varnam.dictConn.Exec("DROP TABLE word_phonetic_keys")
+	checkError(err)
+
+	countWords := func() int {
+		var count int
+		checkError(varnam.dictConn.QueryRow("SELECT COUNT(*) FROM words").Scan(&count))
+		return count
+	}
+
+	assertEqual(t, varnam.Learn("തത്ത", 0) != nil, true)
+	assertEqual(t, countWords(), 0)
+
+	_, err = varnam.LearnMany([]WordInfo{{word: "തത്ത"}})
+	assertEqual(t, err != nil, true)
+	assertEqual(t, countWords(), 0)
+}
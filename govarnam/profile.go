@@ -0,0 +1,212 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+/**
+ * Learnings profiles. A language can have more than one
+ * dictionary, eg: "formal" & "chat", or one for each person on a
+ * shared computer. Profiles of a language are files in the same
+ * directory, <lang>.vst.learnings is the default profile and
+ * <lang>.<profile>.vst.learnings are the others.
+ *
+ * A base profile can be set with SetBaseProfile(). It's attached
+ * read only as a dictionary layer and its words are suggested
+ * too. Words are learnt only to the profile in use.
+ */
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const learningsFileExtension = ".vst.learnings"
+
+var profileNameRegex = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
+// Profile names are part of file names
+func validateProfileName(profile string) error {
+	if !profileNameRegex.MatchString(profile) {
+		return fmt.Errorf("invalid profile name %q, use letters, digits, _ and -", profile)
+	}
+	return nil
+}
+
+func getLearningsFileName(langCode string, profile string) string {
+	if profile == "" || profile == VARNAM_DEFAULT_PROFILE {
+		return langCode + learningsFileExtension
+	}
+	return langCode + "." + profile + learningsFileExtension
+}
+
+// Path to dictionary of a profile. Profiles are
+// in the same directory as the one in use.
+func (varnam *Varnam) getProfilePath(profile string) string {
+	return path.Join(path.Dir(varnam.DictPath), getLearningsFileName(varnam.SchemeDetails.LangCode, profile))
+}
+
+// ListProfiles get names of learnings profiles of the language
+func (varnam *Varnam) ListProfiles() ([]string, error) {
+	varnam.mutex.RLock()
+	defer varnam.mutex.RUnlock()
+
+	var profiles []string
+
+	entries, err := os.ReadDir(path.Dir(varnam.DictPath))
+	if err != nil {
+		return profiles, err
+	}
+
+	prefix := varnam.SchemeDetails.LangCode + "."
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, learningsFileExtension) {
+			continue
+		}
+
+		if name == varnam.SchemeDetails.LangCode+learningsFileExtension {
+			profiles = append(profiles, VARNAM_DEFAULT_PROFILE)
+			continue
+		}
+
+		profile := strings.TrimSuffix(strings.TrimPrefix(name, prefix), learningsFileExtension)
+		if validateProfileName(profile) == nil {
+			profiles = append(profiles, profile)
+		}
+	}
+
+	sort.Strings(profiles)
+
+	return profiles, nil
+}
+
+// CreateProfile make an empty learnings profile
+func (varnam *Varnam) CreateProfile(profile string) error {
+	varnam.mutex.RLock()
+	defer varnam.mutex.RUnlock()
+
+	err := validateProfileName(profile)
+	if err != nil {
+		return err
+	}
+
+	profilePath := varnam.getProfilePath(profile)
+	if fileExists(profilePath) {
+		return fmt.Errorf("profile %q already exists", profile)
+	}
+
+	conn, err := openDB(profilePath)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = migrateDictionary(conn)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Exec("PRAGMA journal_mode=wal;")
+	return err
+}
+
+// DeleteProfile delete a learnings profile and all its words.
+// Profile in use and the base profile can't be deleted.
+func (varnam *Varnam) DeleteProfile(profile string) error {
+	varnam.mutex.RLock()
+	defer varnam.mutex.RUnlock()
+
+	err := validateProfileName(profile)
+	if err != nil {
+		return err
+	}
+
+	profilePath := varnam.getProfilePath(profile)
+	if profilePath == varnam.DictPath {
+		return fmt.Errorf("profile %q is in use", profile)
+	}
+	if varnam.BaseProfile != "" && profilePath == varnam.getProfilePath(varnam.BaseProfile) {
+		return fmt.Errorf("profile %q is the base profile", profile)
+	}
+	if !fileExists(profilePath) {
+		return fmt.Errorf("profile %q doesn't exist", profile)
+	}
+
+	err = os.Remove(profilePath)
+	if err != nil {
+		return err
+	}
+
+	// SQLite files made in WAL mode
+	for _, suffix := range []string{"-wal", "-shm"} {
+		err = os.Remove(profilePath + suffix)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetBaseProfile search the words of a profile along with the
+// profile in use. The profile is attached read only as a
+// dictionary layer, see layers.go. Empty profile removes the
+// base profile.
+func (varnam *Varnam) SetBaseProfile(profile string) error {
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
+	if profile == "" {
+		return varnam.removeBaseProfile()
+	}
+
+	err := validateProfileName(profile)
+	if err != nil {
+		return err
+	}
+
+	profilePath := varnam.getProfilePath(profile)
+	if profilePath == varnam.DictPath {
+		return fmt.Errorf("profile %q is in use, it can't be the base profile", profile)
+	}
+	if !fileExists(profilePath) {
+		return fmt.Errorf("profile %q doesn't exist", profile)
+	}
+
+	err = varnam.removeBaseProfile()
+	if err != nil {
+		return err
+	}
+
+	err = varnam.attachDictionary(profilePath)
+	if err != nil {
+		return err
+	}
+
+	varnam.BaseProfile = profile
+
+	return nil
+}
+
+func (varnam *Varnam) removeBaseProfile() error {
+	if varnam.BaseProfile == "" {
+		return nil
+	}
+
+	err := varnam.detachDictionary(varnam.getProfilePath(varnam.BaseProfile))
+	if err != nil {
+		return err
+	}
+
+	varnam.BaseProfile = ""
+
+	return nil
+}
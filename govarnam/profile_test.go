@@ -0,0 +1,120 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"reflect"
+	"testing"
+)
+
+func makeProfileInstance(t *testing.T, learningsDir string, profile string) *Varnam {
+	varnam, err := initFromID("ml", "", learningsDir, profile)
+	checkError(err)
+
+	t.Cleanup(func() {
+		varnam.Close()
+	})
+
+	return varnam
+}
+
+func hasSuggestion(sugs []Suggestion, word string) bool {
+	for _, sug := range sugs {
+		if sug.Word == word {
+			return true
+		}
+	}
+	return false
+}
+
+func TestProfiles(t *testing.T) {
+	dir := t.TempDir()
+	varnam := makeTestInstance(t, "ml", dir)
+
+	profiles, err := varnam.ListProfiles()
+	checkError(err)
+	assertEqual(t, reflect.DeepEqual(profiles, []string{VARNAM_DEFAULT_PROFILE}), true)
+
+	checkError(varnam.CreateProfile("chat"))
+	assertEqual(t, varnam.CreateProfile("chat") != nil, true)
+	assertEqual(t, varnam.CreateProfile("../chat") != nil, true)
+
+	profiles, err = varnam.ListProfiles()
+	checkError(err)
+	assertEqual(t, reflect.DeepEqual(profiles, []string{"chat", VARNAM_DEFAULT_PROFILE}), true)
+
+	// Profiles have their own words
+	chat := makeProfileInstance(t, dir, "chat")
+	checkError(chat.Learn("തത്ത", 0))
+	assertEqual(t, hasSuggestion(chat.TransliterateAdvanced("thaththa").ExactWords, "തത്ത"), true)
+	assertEqual(t, hasSuggestion(varnam.TransliterateAdvanced("thaththa").ExactWords, "തത്ത"), false)
+
+	// Made if it doesn't exist
+	makeProfileInstance(t, dir, "formal")
+
+	profiles, err = varnam.ListProfiles()
+	checkError(err)
+	assertEqual(t, reflect.DeepEqual(profiles, []string{"chat", VARNAM_DEFAULT_PROFILE, "formal"}), true)
+
+	_, err = initFromID("ml", "", dir, "")
+	assertEqual(t, err != nil, true)
+
+	// Profile in use
+	assertEqual(t, varnam.DeleteProfile(VARNAM_DEFAULT_PROFILE) != nil, true)
+	assertEqual(t, varnam.DeleteProfile("nothing") != nil, true)
+
+	checkError(varnam.DeleteProfile("chat"))
+
+	profiles, err = varnam.ListProfiles()
+	checkError(err)
+	assertEqual(t, reflect.DeepEqual(profiles, []string{VARNAM_DEFAULT_PROFILE, "formal"}), true)
+}
+
+func TestBaseProfile(t *testing.T) {
+	dir := t.TempDir()
+
+	base := makeProfileInstance(t, dir, "base")
+	checkError(base.Learn("വയൽ", 0))
+	checkError(base.Learn("തത്ത", 0))
+	checkError(base.Learn("തത്ത", 0))
+
+	varnam := makeTestInstance(t, "ml", dir)
+	checkError(varnam.Learn("തത്ത", 0))
+
+	assertEqual(t, hasSuggestion(varnam.TransliterateAdvanced("vayal").ExactWords, "വയൽ"), false)
+
+	assertEqual(t, varnam.SetBaseProfile(VARNAM_DEFAULT_PROFILE) != nil, true)
+	assertEqual(t, varnam.SetBaseProfile("nothing") != nil, true)
+
+	checkError(varnam.SetBaseProfile("base"))
+	assertEqual(t, varnam.BaseProfile, "base")
+
+	assertEqual(t, hasSuggestion(varnam.TransliterateAdvanced("vayal").ExactWords, "വയൽ"), true)
+
+	// Profile in use overrides base profile
+	sugs := varnam.TransliterateAdvanced("thaththa").ExactWords
+	assertEqual(t, len(sugs), 1)
+	assertEqual(t, sugs[0].Weight, VARNAM_LEARNT_WORD_MIN_WEIGHT)
+
+	// Learnt only to the profile in use
+	checkError(varnam.Learn("മലയാളം", 0))
+	assertEqual(t, hasSuggestion(base.TransliterateAdvanced("malayaaLam").ExactWords, "മലയാളം"), false)
+
+	// Changes to base profile are seen. Cached
+	// results don't know of them though.
+	checkError(base.Unlearn("വയൽ"))
+	varnam.cache.clear()
+	assertEqual(t, hasSuggestion(varnam.TransliterateAdvanced("vayal").ExactWords, "വയൽ"), false)
+
+	assertEqual(t, varnam.DeleteProfile("base") != nil, true)
+
+	checkError(varnam.SetBaseProfile(""))
+	assertEqual(t, varnam.BaseProfile, "")
+
+	checkError(base.Learn("വയൽ", 0))
+	assertEqual(t, hasSuggestion(varnam.TransliterateAdvanced("vayal").ExactWords, "വയൽ"), false)
+}
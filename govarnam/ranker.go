@@ -0,0 +1,166 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"math"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+// Ranker orders the lists of a transliteration result
+// into one list. Set Varnam.Ranker to use one.
+type Ranker interface {
+	Rank(result TransliterationResult) []Suggestion
+}
+
+// DefaultRanker orders by source. Dictionary results come first,
+// with the greedy tokenized result at 2nd place, or at first if
+// it's shorter than 3 characters. Tokenizer results are last.
+type DefaultRanker struct{}
+
+// Rank see DefaultRanker
+func (DefaultRanker) Rank(result TransliterationResult) []Suggestion {
+	return flattenTR(result)
+}
+
+// WeightedRanker orders by a score made from the weight of
+// suggestion, where it came from and when it was learnt.
+// Make it with NewWeightedRanker() for the default values.
+type WeightedRanker struct {
+	// Percentage of weight counted for each source,
+	// VARNAM_SOURCE_* => percentage. Sources not in
+	// the map are counted 100%.
+	SourceWeights map[int]int
+
+	// Score added to a word learnt right now. It halves every
+	// RecencyHalfLife seconds. 0 or less disables it.
+	RecencyBonus    int
+	RecencyHalfLife int
+
+	// Greedy tokenized results shorter than this many characters
+	// are put first, whatever their score is. 0 or less disables it.
+	ShortWordLength int
+
+	// Current time, changed in tests
+	now func() time.Time
+}
+
+// NewWeightedRanker make a WeightedRanker with default values
+func NewWeightedRanker() *WeightedRanker {
+	return &WeightedRanker{
+		SourceWeights: map[int]int{
+			VARNAM_SOURCE_EXACT_WORD:         300,
+			VARNAM_SOURCE_EXACT_MATCH:        200,
+			VARNAM_SOURCE_DICTIONARY:         100,
+			VARNAM_SOURCE_PATTERN_DICTIONARY: 100,
+			VARNAM_SOURCE_GREEDY:             100,
+			VARNAM_SOURCE_TOKENIZER:          50,
+		},
+		RecencyBonus:    30,
+		RecencyHalfLife: 30 * 24 * 60 * 60,
+		ShortWordLength: 3,
+	}
+}
+
+func (ranker *WeightedRanker) score(sug Suggestion, now time.Time) float64 {
+	percentage, found := ranker.SourceWeights[sug.Source]
+	if !found {
+		percentage = 100
+	}
+
+	score := float64(sug.Weight*percentage) / 100
+
+	if sug.LearnedOn != 0 && ranker.RecencyBonus > 0 && ranker.RecencyHalfLife > 0 {
+		age := now.Sub(time.Unix(int64(sug.LearnedOn), 0)).Seconds()
+		if age < 0 {
+			age = 0
+		}
+		score += float64(ranker.RecencyBonus) * math.Pow(0.5, age/float64(ranker.RecencyHalfLife))
+	}
+
+	return score
+}
+
+// Rank see WeightedRanker
+func (ranker *WeightedRanker) Rank(result TransliterationResult) []Suggestion {
+	now := time.Now()
+	if ranker.now != nil {
+		now = ranker.now()
+	}
+
+	// Same scores keep this order
+	var short, sugs []Suggestion
+
+	if ranker.ShortWordLength > 0 {
+		for _, sug := range result.GreedyTokenized {
+			if utf8.RuneCountInString(sug.Word) < ranker.ShortWordLength {
+				short = append(short, sug)
+			} else {
+				sugs = append(sugs, sug)
+			}
+		}
+	} else {
+		sugs = append(sugs, result.GreedyTokenized...)
+	}
+
+	sugs = append(sugs, result.ExactWords...)
+	sugs = append(sugs, result.ExactMatches...)
+	sugs = append(sugs, result.PatternDictionarySuggestions...)
+	sugs = append(sugs, result.DictionarySuggestions...)
+	sugs = append(sugs, result.TokenizerSuggestions...)
+
+	scores := make([]float64, len(sugs))
+	for i := range sugs {
+		scores[i] = ranker.score(sugs[i], now)
+	}
+
+	sort.Stable(suggestionsByScore{sugs, scores})
+
+	return append(short, sugs...)
+}
+
+type suggestionsByScore struct {
+	sugs   []Suggestion
+	scores []float64
+}
+
+func (s suggestionsByScore) Len() int {
+	return len(s.sugs)
+}
+
+func (s suggestionsByScore) Less(i, j int) bool {
+	return s.scores[i] > s.scores[j]
+}
+
+func (s suggestionsByScore) Swap(i, j int) {
+	s.sugs[i], s.sugs[j] = s.sugs[j], s.sugs[i]
+	s.scores[i], s.scores[j] = s.scores[j], s.scores[i]
+}
+
+// A word can be in multiple lists, keep the first one
+func deduplicateSuggestions(sugs []Suggestion) []Suggestion {
+	var result []Suggestion
+	added := map[string]bool{}
+	for _, sug := range sugs {
+		if !added[sug.Word] {
+			result = append(result, sug)
+			added[sug.Word] = true
+		}
+	}
+	return result
+}
+
+// Order result into one list with the ranker in config
+func (varnam *Varnam) rankResult(result TransliterationResult) []Suggestion {
+	var ranker Ranker = DefaultRanker{}
+	if varnam.Ranker != nil {
+		ranker = varnam.Ranker
+	}
+	return deduplicateSuggestions(ranker.Rank(result))
+}
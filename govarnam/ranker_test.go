@@ -0,0 +1,82 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func getWords(sugs []Suggestion) []string {
+	var words []string
+	for _, sug := range sugs {
+		words = append(words, sug.Word)
+	}
+	return words
+}
+
+func TestDefaultRanker(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+	checkError(varnam.Learn("വയൽ", 0))
+
+	expected := flattenTR(varnam.TransliterateAdvanced("vayal"))
+	assertEqual(t, reflect.DeepEqual(varnam.Transliterate("vayal"), expected), true)
+
+	varnam.Ranker = DefaultRanker{}
+	assertEqual(t, reflect.DeepEqual(varnam.Transliterate("vayal"), expected), true)
+}
+
+func TestWeightedRanker(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	ranker := NewWeightedRanker()
+	ranker.now = func() time.Time {
+		return now
+	}
+
+	result := TransliterationResult{
+		ExactWords: []Suggestion{
+			{Word: "exact", Weight: 40, LearnedOn: int(now.Unix()) - 10*ranker.RecencyHalfLife, Source: VARNAM_SOURCE_EXACT_WORD},
+		},
+		DictionarySuggestions: []Suggestion{
+			{Word: "old", Weight: 60, LearnedOn: int(now.Unix()) - 10*ranker.RecencyHalfLife, Source: VARNAM_SOURCE_DICTIONARY},
+			{Word: "recent", Weight: 60, LearnedOn: int(now.Unix()), Source: VARNAM_SOURCE_DICTIONARY},
+		},
+		TokenizerSuggestions: []Suggestion{
+			{Word: "tokenizer", Weight: 100, Source: VARNAM_SOURCE_TOKENIZER},
+		},
+		GreedyTokenized: []Suggestion{
+			{Word: "ക", Weight: 1, Source: VARNAM_SOURCE_GREEDY},
+			{Word: "greedy", Weight: 55, Source: VARNAM_SOURCE_GREEDY},
+		},
+	}
+
+	// Short greedy first, exact = 40 * 300%, recent = 60 + 30
+	// recency bonus, old = 60, greedy = 55, tokenizer = 100 * 50%
+	assertEqual(t, reflect.DeepEqual(getWords(ranker.Rank(result)), []string{"ക", "exact", "recent", "old", "greedy", "tokenizer"}), true)
+
+	ranker.SourceWeights[VARNAM_SOURCE_TOKENIZER] = 1000
+	ranker.RecencyBonus = 0
+	ranker.ShortWordLength = 0
+	assertEqual(t, reflect.DeepEqual(getWords(ranker.Rank(result)), []string{"tokenizer", "exact", "old", "recent", "greedy", "ക"}), true)
+}
+
+func TestWeightedRankerTransliterate(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+	checkError(varnam.Learn("വയൽ", 0))
+
+	defaultSugs := varnam.Transliterate("vayal")
+
+	varnam.Ranker = NewWeightedRanker()
+	sugs := varnam.Transliterate("vayal")
+
+	// Same words, no duplicates
+	assertEqual(t, len(sugs), len(defaultSugs))
+	assertEqual(t, sugs[0].Word, "വയൽ")
+	assertEqual(t, sugs[0].Source, VARNAM_SOURCE_EXACT_WORD)
+}
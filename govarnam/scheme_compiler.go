@@ -0,0 +1,566 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Scheme source is a text file to make VST from. Example:
+//
+//   # Comments start with #
+//   [metadata]
+//   scheme-id = ml
+//   lang-code = ml
+//   scheme-display-name = Malayalam
+//   scheme-author = Varnam Project
+//   scheme-stable = true
+//
+//   [settings]
+//   dead-consonants = true
+//   ignore-duplicates = false
+//
+//   [virama]
+//   ~ ്
+//
+//   [vowels]
+//   # pattern value1 value2 value3
+//   a  അ
+//   aa ആ ാ
+//   A  ആ ാ  match=possibility weight=10
+//
+//   [consonants tag=chill accept=ends-with]
+//   r ർ
+//
+//   [stem-rules]
+//   # old-ending new-ending
+//   ത്തിൽ ം
+//
+//   [stem-exceptions]
+//   # old-ending exception
+//   ത്തിൽ ിത്തിൽ
+//
+// Section name is the symbol type. Attributes given to a section
+// apply to all tokens in it, a token can override them. Attributes:
+//   match=exact|possibility
+//   accept=all|starts-with|in-between|ends-with
+//   tag=<tag> priority=<int> weight=<int>
+// Stem rules replace a word ending to find the stem of a word.
+// Words ending with an exception are not stemmed by the rule.
+// Fields are separated by whitespace. A field having whitespace, '='
+// or starting with '#', '"' or '[' should be double quoted. Quoted
+// fields can have Go escape sequences like "\u200c". Attribute values
+// are quoted after '=', like tag="my tag".
+
+var schemeSectionSymbolTypes = map[string]int{
+	"vowels":           VARNAM_SYMBOL_VOWEL,
+	"consonants":       VARNAM_SYMBOL_CONSONANT,
+	"conjuncts":        VARNAM_SYMBOL_CONSONANT,
+	"dead-consonants":  VARNAM_SYMBOL_DEAD_CONSONANT,
+	"consonant-vowels": VARNAM_SYMBOL_CONSONANT_VOWEL,
+	"numbers":          VARNAM_SYMBOL_NUMBER,
+	"symbols":          VARNAM_SYMBOL_SYMBOL,
+	"anusvara":         VARNAM_SYMBOL_ANUSVARA,
+	"visarga":          VARNAM_SYMBOL_VISARGA,
+	"virama":           VARNAM_SYMBOL_VIRAMA,
+	"others":           VARNAM_SYMBOL_OTHER,
+	"non-joiner":       VARNAM_SYMBOL_NON_JOINER,
+	"joiner":           VARNAM_SYMBOL_JOINER,
+	"period":           VARNAM_SYMBOL_PERIOD,
+}
+
+var schemeMatchTypes = map[string]int{
+	"exact":       VARNAM_MATCH_EXACT,
+	"possibility": VARNAM_MATCH_POSSIBILITY,
+}
+
+var schemeAcceptConditions = map[string]int{
+	"all":         VARNAM_TOKEN_ACCEPT_ALL,
+	"starts-with": VARNAM_TOKEN_ACCEPT_IF_STARTS_WITH,
+	"in-between":  VARNAM_TOKEN_ACCEPT_IF_IN_BETWEEN,
+	"ends-with":   VARNAM_TOKEN_ACCEPT_IF_ENDS_WITH,
+}
+
+// A token in scheme source
+type schemeToken struct {
+	line   int
+	symbol Symbol
+}
+
+// A stem rule or a stem exception in scheme source
+type schemeStemItem struct {
+	line int
+
+	// Old ending of the stem rule
+	ending string
+
+	// New ending or exception
+	value string
+}
+
+// Parsed scheme source
+type schemeSource struct {
+	details        SchemeDetails
+	config         VSTMakerConfig
+	tokens         []schemeToken
+	stemRules      []schemeStemItem
+	stemExceptions []schemeStemItem
+}
+
+// A field in a line of scheme source
+type schemeField struct {
+	value  string
+	quoted bool
+}
+
+// Read a quoted string starting at runes[start]. Returns the
+// unquoted string and the position after closing quote.
+func readSchemeQuoted(runes []rune, start int) (string, int, error) {
+	i := start + 1
+	for i < len(runes) && runes[i] != '"' {
+		if runes[i] == '\\' {
+			i++
+		}
+		i++
+	}
+	if i >= len(runes) {
+		return "", i, fmt.Errorf("unterminated quote")
+	}
+	i++
+
+	value, err := strconv.Unquote(string(runes[start:i]))
+	if err != nil {
+		return "", i, fmt.Errorf("invalid quoted field %s", string(runes[start:i]))
+	}
+	return value, i, nil
+}
+
+// Split a line into fields. Comments are skipped.
+func splitSchemeLine(line string) ([]schemeField, error) {
+	var fields []schemeField
+
+	runes := []rune(line)
+	i := 0
+	for i < len(runes) {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		if runes[i] == '#' {
+			break
+		}
+
+		if runes[i] == '"' {
+			value, end, err := readSchemeQuoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			fields = append(fields, schemeField{value, true})
+			continue
+		}
+
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) {
+			// Quoted attribute value: key="value"
+			if runes[i] == '=' && i+1 < len(runes) && runes[i+1] == '"' {
+				value, end, err := readSchemeQuoted(runes, i+1)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, schemeField{string(runes[start:i+1]) + value, false})
+				start = -1
+				i = end
+				break
+			}
+			i++
+		}
+		if start != -1 {
+			fields = append(fields, schemeField{string(runes[start:i]), false})
+		}
+	}
+
+	return fields, nil
+}
+
+// Get key & value of a token attribute field
+func getSchemeAttribute(field schemeField) (string, string, bool) {
+	if field.quoted {
+		return "", "", false
+	}
+
+	pos := strings.Index(field.value, "=")
+	if pos == -1 {
+		return "", "", false
+	}
+
+	key := field.value[:pos]
+	switch key {
+	case "match", "accept", "tag", "priority", "weight":
+		return key, field.value[pos+1:], true
+	}
+
+	return "", "", false
+}
+
+// Apply attribute to a symbol
+func setSchemeAttribute(symbol *Symbol, key string, value string) error {
+	var (
+		found bool
+		err   error
+	)
+
+	switch key {
+	case "match":
+		symbol.MatchType, found = schemeMatchTypes[value]
+		if !found {
+			return fmt.Errorf("invalid match type '%s'", value)
+		}
+	case "accept":
+		symbol.AcceptCondition, found = schemeAcceptConditions[value]
+		if !found {
+			return fmt.Errorf("invalid accept condition '%s'", value)
+		}
+	case "tag":
+		symbol.Tag = value
+	case "priority":
+		symbol.Priority, err = strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("priority should be a number")
+		}
+	case "weight":
+		symbol.Weight, err = strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("weight should be a number")
+		}
+	}
+
+	return nil
+}
+
+// Get value of a "key = value" line
+func getSchemeKeyValue(line string) (string, string, error) {
+	pos := strings.Index(line, "=")
+	if pos == -1 {
+		return "", "", fmt.Errorf("expected key = value")
+	}
+
+	key := strings.TrimSpace(line[:pos])
+	fields, err := splitSchemeLine(line[pos+1:])
+	if err != nil {
+		return "", "", err
+	}
+
+	var values []string
+	for _, field := range fields {
+		values = append(values, field.value)
+	}
+
+	return key, strings.Join(values, " "), nil
+}
+
+func (scheme *schemeSource) setMetadata(key string, value string) error {
+	switch key {
+	case VARNAM_METADATA_SCHEME_IDENTIFIER:
+		scheme.details.Identifier = value
+	case VARNAM_METADATA_SCHEME_LANGUAGE_CODE:
+		scheme.details.LangCode = value
+	case VARNAM_METADATA_SCHEME_DISPLAY_NAME:
+		scheme.details.DisplayName = value
+	case VARNAM_METADATA_SCHEME_AUTHOR:
+		scheme.details.Author = value
+	case VARNAM_METADATA_SCHEME_COMPILED_DATE:
+		scheme.details.CompiledDate = value
+	case VARNAM_METADATA_SCHEME_STABLE:
+		isStable, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s should be true or false", key)
+		}
+		scheme.details.IsStable = isStable
+	default:
+		return fmt.Errorf("unknown metadata '%s'", key)
+	}
+	return nil
+}
+
+func (scheme *schemeSource) setSetting(key string, value string) error {
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s should be true or false", key)
+	}
+
+	switch key {
+	case "dead-consonants":
+		scheme.config.UseDeadConsonants = enabled
+	case "ignore-duplicates":
+		scheme.config.IgnoreDuplicateTokens = enabled
+	default:
+		return fmt.Errorf("unknown setting '%s'", key)
+	}
+	return nil
+}
+
+// Parse scheme source. All errors found are returned together
+func parseScheme(reader io.Reader) (*schemeSource, error) {
+	var (
+		scheme  schemeSource
+		errs    []string
+		section string
+
+		// Attributes of current section
+		sectionSymbol Symbol
+	)
+
+	addError := func(lineNumber int, err error) {
+		errs = append(errs, fmt.Sprintf("line %d: %s", lineNumber, err.Error()))
+	}
+
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			end := strings.Index(line, "]")
+			if end == -1 {
+				addError(lineNumber, fmt.Errorf("invalid section header"))
+				section = ""
+				continue
+			}
+
+			rest := strings.TrimSpace(line[end+1:])
+			fields, err := splitSchemeLine(line[1:end])
+			if err != nil || len(fields) == 0 || (rest != "" && rest[0] != '#') {
+				addError(lineNumber, fmt.Errorf("invalid section header"))
+				section = ""
+				continue
+			}
+
+			section = fields[0].value
+			sectionSymbol = Symbol{MatchType: VARNAM_MATCH_EXACT}
+
+			symbolType, isTokenSection := schemeSectionSymbolTypes[section]
+			if !isTokenSection {
+				if section != "metadata" && section != "settings" && section != "stem-rules" && section != "stem-exceptions" {
+					addError(lineNumber, fmt.Errorf("unknown section '%s'", section))
+					section = ""
+				} else if len(fields) > 1 {
+					addError(lineNumber, fmt.Errorf("section '%s' can't have attributes", section))
+				}
+				continue
+			}
+			sectionSymbol.Type = symbolType
+
+			for _, field := range fields[1:] {
+				key, value, isAttribute := getSchemeAttribute(field)
+				if !isAttribute {
+					addError(lineNumber, fmt.Errorf("invalid attribute '%s'", field.value))
+					continue
+				}
+
+				err = setSchemeAttribute(&sectionSymbol, key, value)
+				if err != nil {
+					addError(lineNumber, err)
+				}
+			}
+			continue
+		}
+
+		switch section {
+		case "":
+			addError(lineNumber, fmt.Errorf("expected a section"))
+		case "metadata", "settings":
+			key, value, err := getSchemeKeyValue(line)
+			if err == nil {
+				if section == "metadata" {
+					err = scheme.setMetadata(key, value)
+				} else {
+					err = scheme.setSetting(key, value)
+				}
+			}
+			if err != nil {
+				addError(lineNumber, err)
+			}
+		case "stem-rules", "stem-exceptions":
+			fields, err := splitSchemeLine(line)
+			if err != nil {
+				addError(lineNumber, err)
+				continue
+			}
+
+			if len(fields) != 2 {
+				if section == "stem-rules" {
+					addError(lineNumber, fmt.Errorf("expected old ending and new ending"))
+				} else {
+					addError(lineNumber, fmt.Errorf("expected old ending and exception"))
+				}
+				continue
+			}
+
+			item := schemeStemItem{lineNumber, fields[0].value, fields[1].value}
+			if section == "stem-rules" {
+				scheme.stemRules = append(scheme.stemRules, item)
+			} else {
+				scheme.stemExceptions = append(scheme.stemExceptions, item)
+			}
+		default:
+			fields, err := splitSchemeLine(line)
+			if err != nil {
+				addError(lineNumber, err)
+				continue
+			}
+
+			symbol := sectionSymbol
+			var values []string
+
+			for _, field := range fields {
+				key, value, isAttribute := getSchemeAttribute(field)
+				if !isAttribute {
+					values = append(values, field.value)
+					continue
+				}
+
+				err = setSchemeAttribute(&symbol, key, value)
+				if err != nil {
+					addError(lineNumber, err)
+				}
+			}
+
+			if len(values) < 2 {
+				addError(lineNumber, fmt.Errorf("expected pattern and value"))
+				continue
+			}
+			if len(values) > 4 {
+				addError(lineNumber, fmt.Errorf("too many values, expected pattern value1 [value2 [value3]]"))
+				continue
+			}
+
+			values = append(values, "", "")
+			symbol.Pattern = values[0]
+			symbol.Value1 = values[1]
+			symbol.Value2 = values[2]
+			symbol.Value3 = values[3]
+
+			scheme.tokens = append(scheme.tokens, schemeToken{lineNumber, symbol})
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if scheme.details.Identifier == "" {
+		errs = append(errs, fmt.Sprintf("metadata: %s is required", VARNAM_METADATA_SCHEME_IDENTIFIER))
+	}
+	if scheme.details.LangCode == "" {
+		errs = append(errs, fmt.Sprintf("metadata: %s is required", VARNAM_METADATA_SCHEME_LANGUAGE_CODE))
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+
+	if scheme.details.CompiledDate == "" {
+		scheme.details.CompiledDate = time.Now().Format("2006-01-02")
+	}
+
+	return &scheme, nil
+}
+
+// Make VST tokens from the scheme source
+func (varnam *Varnam) vmCompileScheme(scheme *schemeSource) error {
+	err := varnam.VMSetSchemeDetails(scheme.details)
+	if err != nil {
+		return err
+	}
+
+	varnam.VSTMakerConfig.UseDeadConsonants = scheme.config.UseDeadConsonants
+	varnam.VSTMakerConfig.IgnoreDuplicateTokens = scheme.config.IgnoreDuplicateTokens
+
+	// Virama is needed for generating dead consonants
+	tokens := scheme.tokens
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].symbol.Type == VARNAM_SYMBOL_VIRAMA && tokens[j].symbol.Type != VARNAM_SYMBOL_VIRAMA
+	})
+
+	for _, token := range tokens {
+		s := token.symbol
+		err = varnam.vmCreateToken(s.Pattern, s.Value1, s.Value2, s.Value3, s.Tag, s.Type, s.MatchType, s.Priority, s.AcceptCondition, s.Weight, true)
+		if err != nil {
+			varnam.vmDiscardChanges()
+			return fmt.Errorf("line %d: %s", token.line, err.Error())
+		}
+	}
+
+	for _, rule := range scheme.stemRules {
+		err = varnam.VMCreateStemRule(rule.ending, rule.value)
+		if err != nil {
+			varnam.vmDiscardChanges()
+			return fmt.Errorf("line %d: %s", rule.line, err.Error())
+		}
+	}
+
+	for _, exception := range scheme.stemExceptions {
+		err = varnam.VMCreateStemException(exception.ending, exception.value)
+		if err != nil {
+			varnam.vmDiscardChanges()
+			return fmt.Errorf("line %d: %s", exception.line, err.Error())
+		}
+	}
+
+	return varnam.VMFlushBuffer()
+}
+
+// CompileScheme make a VST from scheme source file.
+// An existing file at vstPath is replaced only on success.
+func CompileScheme(srcPath string, vstPath string) error {
+	file, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scheme, err := parseScheme(file)
+	if err != nil {
+		return err
+	}
+
+	tempPath := vstPath + ".tmp"
+	os.Remove(tempPath)
+
+	varnam, err := VMInit(tempPath)
+	if err != nil {
+		return err
+	}
+
+	err = varnam.vmCompileScheme(scheme)
+	varnam.Close()
+
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	return os.Rename(tempPath, vstPath)
+}
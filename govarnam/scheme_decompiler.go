@@ -0,0 +1,213 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Quote a scheme source field if needed. See scheme_compiler.go
+func quoteSchemeField(value string) string {
+	if value == "" || value[0] == '#' || value[0] == '[' {
+		return strconv.Quote(value)
+	}
+
+	for _, r := range value {
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) || r == '=' || r == '"' || r == '\\' {
+			return strconv.Quote(value)
+		}
+	}
+
+	return value
+}
+
+// Get key of a value in a map of scheme names
+func getSchemeName(names map[string]int, value int) string {
+	for name, v := range names {
+		if v == value {
+			return name
+		}
+	}
+	return ""
+}
+
+// Section name of a symbol type
+func getSchemeSectionName(symbolType int) string {
+	if symbolType == VARNAM_SYMBOL_CONSONANT {
+		// conjuncts is also consonant
+		return "consonants"
+	}
+	return getSchemeName(schemeSectionSymbolTypes, symbolType)
+}
+
+// Make a token line of scheme source
+func formatSchemeToken(symbol Symbol) string {
+	fields := []string{
+		quoteSchemeField(symbol.Pattern),
+		quoteSchemeField(symbol.Value1),
+	}
+
+	if symbol.Value2 != "" || symbol.Value3 != "" {
+		fields = append(fields, quoteSchemeField(symbol.Value2))
+	}
+	if symbol.Value3 != "" {
+		fields = append(fields, quoteSchemeField(symbol.Value3))
+	}
+
+	if symbol.AcceptCondition != VARNAM_TOKEN_ACCEPT_ALL {
+		fields = append(fields, "accept="+getSchemeName(schemeAcceptConditions, symbol.AcceptCondition))
+	}
+	if symbol.Tag != "" {
+		fields = append(fields, "tag="+quoteSchemeField(symbol.Tag))
+	}
+	if symbol.Priority != 0 {
+		fields = append(fields, "priority="+strconv.Itoa(symbol.Priority))
+	}
+	if symbol.Weight != 0 {
+		fields = append(fields, "weight="+strconv.Itoa(symbol.Weight))
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// Write scheme source of VST
+func (varnam *Varnam) vmDecompile(writer *bufio.Writer) error {
+	metadata, err := varnam.vmGetMetadata()
+	if err != nil {
+		return err
+	}
+
+	writer.WriteString("[metadata]\n")
+	for _, key := range []string{
+		VARNAM_METADATA_SCHEME_IDENTIFIER,
+		VARNAM_METADATA_SCHEME_LANGUAGE_CODE,
+		VARNAM_METADATA_SCHEME_DISPLAY_NAME,
+		VARNAM_METADATA_SCHEME_AUTHOR,
+		VARNAM_METADATA_SCHEME_COMPILED_DATE,
+	} {
+		if value, found := metadata[key]; found {
+			writer.WriteString(key + " = " + quoteSchemeField(value) + "\n")
+		}
+	}
+	if value, found := metadata[VARNAM_METADATA_SCHEME_STABLE]; found {
+		writer.WriteString(VARNAM_METADATA_SCHEME_STABLE + " = " + strconv.FormatBool(value == "1") + "\n")
+	}
+
+	// Dead consonants in VST are written as is
+	writer.WriteString("\n[settings]\ndead-consonants = false\n")
+
+	// Symbols are written in the order they were made, so that the
+	// compiled VST gives them in the same order to tokenizer. A new
+	// section starts whenever type or match type changes.
+	rows, err := varnam.vstConn.Query(`
+		SELECT type, pattern, value1, IFNULL(value2, ''), IFNULL(value3, ''), IFNULL(tag, ''),
+			match_type, IFNULL(priority, 0), IFNULL(accept_condition, 0), IFNULL(weight, 0)
+		FROM symbols
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	sectionType := -1
+	sectionMatchType := -1
+
+	for rows.Next() {
+		var symbol Symbol
+		err = rows.Scan(&symbol.Type, &symbol.Pattern, &symbol.Value1, &symbol.Value2, &symbol.Value3, &symbol.Tag, &symbol.MatchType, &symbol.Priority, &symbol.AcceptCondition, &symbol.Weight)
+		if err != nil {
+			return err
+		}
+
+		if symbol.Type != sectionType || symbol.MatchType != sectionMatchType {
+			sectionName := getSchemeSectionName(symbol.Type)
+			if sectionName == "" {
+				return fmt.Errorf("unknown symbol type %d for pattern '%s'", symbol.Type, symbol.Pattern)
+			}
+
+			matchTypeName := getSchemeName(schemeMatchTypes, symbol.MatchType)
+			if matchTypeName == "" {
+				return fmt.Errorf("unknown match type %d for pattern '%s'", symbol.MatchType, symbol.Pattern)
+			}
+
+			writer.WriteString("\n[" + sectionName + " match=" + matchTypeName + "]\n")
+
+			sectionType = symbol.Type
+			sectionMatchType = symbol.MatchType
+		}
+
+		writer.WriteString(formatSchemeToken(symbol) + "\n")
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	stemRules, err := varnam.getStemRules()
+	if err != nil {
+		return err
+	}
+
+	if len(stemRules) > 0 {
+		writer.WriteString("\n[stem-rules]\n")
+		for _, rule := range stemRules {
+			writer.WriteString(quoteSchemeField(rule.OldEnding) + " " + quoteSchemeField(rule.NewEnding) + "\n")
+		}
+
+	}
+
+	sectionWritten := false
+	for _, rule := range stemRules {
+		for _, exception := range rule.Exceptions {
+			if !sectionWritten {
+				writer.WriteString("\n[stem-exceptions]\n")
+				sectionWritten = true
+			}
+			writer.WriteString(quoteSchemeField(rule.OldEnding) + " " + quoteSchemeField(exception) + "\n")
+		}
+	}
+
+	return writer.Flush()
+}
+
+// DecompileVST write scheme source of a VST.
+// Compiling the source with CompileScheme() makes an equivalent VST.
+func DecompileVST(vstPath string, writer io.Writer) error {
+	varnam, err := vmOpenReadOnly(vstPath)
+	if err != nil {
+		return err
+	}
+	defer varnam.Close()
+
+	return varnam.vmDecompile(bufio.NewWriter(writer))
+}
+
+// DecompileVSTToFile write scheme source of a VST to a file
+func DecompileVSTToFile(vstPath string, srcPath string) error {
+	file, err := os.Create(srcPath)
+	if err != nil {
+		return err
+	}
+
+	err = DecompileVST(vstPath, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(srcPath)
+	}
+	return err
+}
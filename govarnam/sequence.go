@@ -0,0 +1,352 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Words learnt one after the other. Each sequence is
+// [previous words..., word] with 2 (bigram) or 3 (trigram)
+// words. Words should be learnt already and be in the form
+// stored in dictionary.
+func (varnam *Varnam) learnWordNGrams(sequences [][]string) error {
+	if len(sequences) == 0 {
+		return nil
+	}
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+
+	tx, err := varnam.dictConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	bigramStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO word_bigrams (prev_word_id, word_id, count, learned_on)
+		SELECT p.id, w.id, 1, strftime('%s', 'now')
+		FROM words p, words w
+		WHERE p.word = ? AND w.word = ?
+		ON CONFLICT (prev_word_id, word_id) DO UPDATE SET
+			count = count + 1,
+			learned_on = excluded.learned_on
+	`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer bigramStmt.Close()
+
+	trigramStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO word_trigrams (prev_prev_word_id, prev_word_id, word_id, count, learned_on)
+		SELECT pp.id, p.id, w.id, 1, strftime('%s', 'now')
+		FROM words pp, words p, words w
+		WHERE pp.word = ? AND p.word = ? AND w.word = ?
+		ON CONFLICT (prev_prev_word_id, prev_word_id, word_id) DO UPDATE SET
+			count = count + 1,
+			learned_on = excluded.learned_on
+	`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer trigramStmt.Close()
+
+	for _, sequence := range sequences {
+		switch len(sequence) {
+		case 2:
+			_, err = bigramStmt.ExecContext(ctx, sequence[0], sequence[1])
+		case 3:
+			_, err = trigramStmt.ExecContext(ctx, sequence[0], sequence[1], sequence[2])
+		default:
+			err = fmt.Errorf("Invalid word sequence length %d", len(sequence))
+		}
+
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Whether word ends a sequence, like end of sentence, comma etc.
+func endsWithPunctuation(word string) bool {
+	lastChar, _ := getLastCharacter(strings.TrimSpace(word))
+	for _, r := range lastChar {
+		if unicode.IsPunct(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Make bigrams & trigrams that end with the last word
+func getNGramsEndingWith(words []string) [][]string {
+	var sequences [][]string
+
+	if len(words) >= 2 {
+		sequences = append(sequences, words[len(words)-2:])
+	}
+	if len(words) >= 3 {
+		sequences = append(sequences, words[len(words)-3:])
+	}
+
+	return sequences
+}
+
+// Learn bigrams & trigrams from words in the order they appeared
+// in a text. A sequence breaks at words ending with punctuation (end
+// of sentence, comma etc.) and at words that can't be learnt.
+func (varnam *Varnam) learnWordSequence(words []string) error {
+	var (
+		sequences [][]string
+		current   []string
+	)
+
+	for _, word := range words {
+		learnableWord, err := varnam.getLearnableWord(word)
+		if err != nil {
+			current = nil
+			continue
+		}
+
+		current = append(current, learnableWord)
+		sequences = append(sequences, getNGramsEndingWith(current)...)
+
+		if endsWithPunctuation(word) {
+			current = nil
+		}
+	}
+
+	return varnam.learnWordNGrams(sequences)
+}
+
+// Get words that were learnt after a word, along with how many times
+func (varnam *Varnam) getWordBigrams(ctx context.Context, prevWord string) map[string]int {
+	counts := map[string]int{}
+
+	select {
+	case <-ctx.Done():
+		return counts
+	default:
+		rows, err := varnam.dictConn.QueryContext(ctx, `
+			SELECT w.word, b.count
+			FROM word_bigrams b
+			LEFT JOIN words w ON w.id = b.word_id
+			WHERE b.prev_word_id = (SELECT id FROM words WHERE word = ?)
+		`, prevWord)
+
+		if err != nil {
+			log.Print(err)
+			return counts
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				word  string
+				count int
+			)
+			rows.Scan(&word, &count)
+			counts[word] = count
+		}
+
+		err = rows.Err()
+		if err != nil {
+			log.Print(err)
+		}
+
+		return counts
+	}
+}
+
+// Sort suggestions by the probability of it coming after previous word.
+// P(word | previous word) = count(previous word, word) / count(previous word, *)
+// Denominator is same for all, so comparing counts is enough.
+// Suggestions that never came after previous word retain their order.
+func sortSuggestionsByBigrams(sugs []Suggestion, counts map[string]int) []Suggestion {
+	sort.SliceStable(sugs, func(i, j int) bool {
+		return counts[sugs[i].Word] > counts[sugs[j].Word]
+	})
+	return sugs
+}
+
+// Re-rank transliteration result using previously committed words.
+// Only the last word is used for now (bigrams).
+func (varnam *Varnam) rankByPreviousWords(ctx context.Context, result *TransliterationResult, previousWords []string) {
+	if len(previousWords) == 0 {
+		return
+	}
+
+	prevWord, err := varnam.getLearnableWord(previousWords[len(previousWords)-1])
+	if err != nil {
+		return
+	}
+
+	counts := varnam.getWordBigrams(ctx, prevWord)
+	if len(counts) == 0 {
+		return
+	}
+
+	result.ExactWords = sortSuggestionsByBigrams(result.ExactWords, counts)
+	result.ExactMatches = sortSuggestionsByBigrams(result.ExactMatches, counts)
+	result.DictionarySuggestions = sortSuggestionsByBigrams(result.DictionarySuggestions, counts)
+	result.TokenizerSuggestions = sortSuggestionsByBigrams(result.TokenizerSuggestions, counts)
+}
+
+// TransliterateAdvancedWithPreviousWords transliterate with a detailed structure as result.
+// Suggestions are ranked considering the previously committed words.
+func (varnam *Varnam) TransliterateAdvancedWithPreviousWords(ctx context.Context, previousWords []string, word string, resultChannel chan<- TransliterationResult) {
+	select {
+	case <-ctx.Done():
+		return
+
+	default:
+		varnam.mutex.RLock()
+		_, result := varnam.transliterate(ctx, word)
+		varnam.rankByPreviousWords(ctx, &result, previousWords)
+		varnam.mutex.RUnlock()
+		resultChannel <- result
+		close(resultChannel)
+	}
+}
+
+// TransliterateWithPreviousWords Transliterate but suggestions are
+// ranked considering the previously committed words.
+func (varnam *Varnam) TransliterateWithPreviousWords(ctx context.Context, previousWords []string, word string, resultChannel chan<- []Suggestion) {
+	select {
+	case <-ctx.Done():
+		return
+
+	default:
+		varnam.mutex.RLock()
+		_, result := varnam.transliterate(ctx, word)
+		varnam.rankByPreviousWords(ctx, &result, previousWords)
+		varnam.mutex.RUnlock()
+		resultChannel <- varnam.rankResult(result)
+		close(resultChannel)
+	}
+}
+
+// Get words learnt after a sequence of previous words.
+// Weight of suggestion is the number of times it came after them.
+func (varnam *Varnam) getNextWords(ctx context.Context, previousWords []string, limit int) []Suggestion {
+	var (
+		result []Suggestion
+		query  string
+	)
+
+	switch len(previousWords) {
+	case 1:
+		query = `
+			SELECT w.word, b.count, b.learned_on
+			FROM word_bigrams b
+			LEFT JOIN words w ON w.id = b.word_id
+			WHERE b.prev_word_id = (SELECT id FROM words WHERE word = ?)
+			ORDER BY b.count DESC, b.learned_on DESC
+			LIMIT ?
+		`
+	case 2:
+		query = `
+			SELECT w.word, t.count, t.learned_on
+			FROM word_trigrams t
+			LEFT JOIN words w ON w.id = t.word_id
+			WHERE t.prev_prev_word_id = (SELECT id FROM words WHERE word = ?)
+				AND t.prev_word_id = (SELECT id FROM words WHERE word = ?)
+			ORDER BY t.count DESC, t.learned_on DESC
+			LIMIT ?
+		`
+	default:
+		return result
+	}
+
+	var args []interface{}
+	for _, word := range previousWords {
+		args = append(args, word)
+	}
+	args = append(args, limit)
+
+	rows, err := varnam.dictConn.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Print(err)
+		return result
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item Suggestion
+		rows.Scan(&item.Word, &item.Weight, &item.LearnedOn)
+		result = append(result, item)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Print(err)
+	}
+
+	return result
+}
+
+// PredictNextWords predict words that may come after the previous words.
+// Uses the last 2 words (trigram) and falls back to the last word
+// (bigram) to fill up to limit. Weight of a suggestion is the number
+// of times it was learnt after the previous words.
+func (varnam *Varnam) PredictNextWords(ctx context.Context, previousWords []string, limit int) []Suggestion {
+	varnam.mutex.RLock()
+	defer varnam.mutex.RUnlock()
+
+	var result []Suggestion
+
+	select {
+	case <-ctx.Done():
+		return result
+	default:
+		if limit <= 0 {
+			return result
+		}
+
+		// Stored form of the last 2 words
+		var words []string
+		for i := len(previousWords) - 1; i >= 0 && len(words) < 2; i-- {
+			if endsWithPunctuation(previousWords[i]) {
+				break
+			}
+
+			word, err := varnam.getLearnableWord(previousWords[i])
+			if err != nil {
+				break
+			}
+			words = append([]string{word}, words...)
+		}
+
+		if len(words) == 0 {
+			return result
+		}
+
+		added := map[string]bool{}
+
+		for n := len(words); n > 0 && len(result) < limit; n-- {
+			for _, sug := range varnam.getNextWords(ctx, words[len(words)-n:], limit) {
+				if !added[sug.Word] && len(result) < limit {
+					added[sug.Word] = true
+					result = append(result, sug)
+				}
+			}
+		}
+
+		return result
+	}
+}
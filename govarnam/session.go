@@ -0,0 +1,115 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+/**
+ * Typing session. An IME gives one key press at a time,
+ * session keeps the tokens & dictionary search state of
+ * what's typed so far, so that each key press only has to
+ * work on the new character.
+ */
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Number of prefixes a session remembers. Backspace
+// goes back to a prefix, so this is the undo depth.
+const sessionCacheSize = 64
+
+// Session an incremental typing session made with NewSession()
+type Session struct {
+	varnam *Varnam
+
+	mutex sync.Mutex
+	input []rune
+
+	// State of prefixes of input
+	cache *transliterationCache
+
+	// varnam.changes when cache was filled
+	changes uint64
+}
+
+// NewSession start a typing session. A session is for one
+// word being typed. Call Reset() after the word is committed.
+func (varnam *Varnam) NewSession() *Session {
+	return &Session{
+		varnam:  varnam,
+		cache:   newTransliterationCache(sessionCacheSize),
+		changes: atomic.LoadUint64(&varnam.changes),
+	}
+}
+
+// Append add typed text, usually a single character
+func (session *Session) Append(text string) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	session.input = append(session.input, []rune(text)...)
+}
+
+// Backspace remove the last character typed
+func (session *Session) Backspace() {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if len(session.input) > 0 {
+		session.input = session.input[:len(session.input)-1]
+	}
+}
+
+// Reset clear the input to start a new word
+func (session *Session) Reset() {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	session.input = nil
+	session.cache.clear()
+}
+
+// Input get the text typed so far
+func (session *Session) Input() string {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	return string(session.input)
+}
+
+// Suggestions transliterate the input typed so far. Result is
+// same as of TransliterateAdvanced. Returns ctx.Err() when
+// cancelled.
+func (session *Session) Suggestions(ctx context.Context) (TransliterationResult, error) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	varnam := session.varnam
+
+	varnam.mutex.RLock()
+	defer varnam.mutex.RUnlock()
+
+	// State is outdated if dictionary or VST changed
+	changes := atomic.LoadUint64(&varnam.changes)
+	if changes != session.changes {
+		session.cache.clear()
+		session.changes = changes
+	}
+
+	if len(session.input) == 0 {
+		return TransliterationResult{}, nil
+	}
+
+	_, result := varnam.transliterateWithCache(ctx, session.cache, string(session.input))
+
+	if ctx.Err() != nil {
+		return TransliterationResult{}, ctx.Err()
+	}
+
+	return result, nil
+}
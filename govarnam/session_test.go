@@ -0,0 +1,92 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func checkSessionResult(t *testing.T, varnam *Varnam, session *Session) {
+	result, err := session.Suggestions(context.Background())
+	checkError(err)
+
+	expected := varnam.TransliterateAdvanced(session.Input())
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Session result of %s differs: %v, expected %v", session.Input(), result, expected)
+	}
+}
+
+// Results of a session should be same as transliterating the input
+func TestSession(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+
+	for _, word := range concurrentWords {
+		checkError(varnam.Learn(word, 0))
+	}
+
+	session := varnam.NewSession()
+
+	result, err := session.Suggestions(context.Background())
+	checkError(err)
+	assertEqual(t, len(flattenTR(result)), 0)
+
+	for _, char := range "vayalil" {
+		session.Append(string(char))
+		checkSessionResult(t, varnam, session)
+	}
+	assertEqual(t, session.Input(), "vayalil")
+
+	// Back to "vayal"
+	session.Backspace()
+	session.Backspace()
+	checkSessionResult(t, varnam, session)
+	assertEqual(t, session.Input(), "vayal")
+
+	session.Reset()
+	assertEqual(t, session.Input(), "")
+
+	session.Backspace()
+	assertEqual(t, session.Input(), "")
+
+	session.Append("nithyam")
+	checkSessionResult(t, varnam, session)
+}
+
+func TestSessionInvalidation(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+
+	session := varnam.NewSession()
+	session.Append("vayal")
+
+	result, err := session.Suggestions(context.Background())
+	checkError(err)
+	assertEqual(t, len(result.ExactWords), 0)
+
+	checkError(varnam.Learn("വയൽ", 0))
+
+	result, err = session.Suggestions(context.Background())
+	checkError(err)
+	assertEqual(t, result.ExactWords[0].Word, "വയൽ")
+}
+
+func TestSessionCancel(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+
+	session := varnam.NewSession()
+	session.Append("nithyam")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := session.Suggestions(ctx)
+	assertEqual(t, err, context.Canceled)
+
+	// Cancelled result is not kept
+	checkSessionResult(t, varnam, session)
+}
@@ -0,0 +1,344 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StemRule replaces a word ending to find stem of the word.
+// Eg: "ത്തിൽ" => "ം" makes "മരത്തിൽ" => "മരം"
+type StemRule struct {
+	OldEnding string
+	NewEnding string
+
+	// Words ending with these are not stemmed by the rule
+	Exceptions []string
+}
+
+// Load stem rules from VST. Longest ending is tried first.
+func (varnam *Varnam) setStemRules() error {
+	var count int
+	err := varnam.vstConn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('stemrules', 'stem_exceptions')").Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	// VST doesn't support stemming
+	if count != 2 {
+		varnam.stemRules = nil
+		return nil
+	}
+
+	rules, err := varnam.getStemRules()
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].OldEnding) > len(rules[j].OldEnding)
+	})
+
+	varnam.stemRules = rules
+
+	return nil
+}
+
+// Get all stem rules in VST in the order they were made
+func (varnam *Varnam) getStemRules() ([]StemRule, error) {
+	var rules []StemRule
+
+	rows, err := varnam.vstConn.Query("SELECT old_ending, IFNULL(new_ending, '') FROM stemrules ORDER BY id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ruleIndex := map[string]int{}
+	for rows.Next() {
+		var rule StemRule
+		err = rows.Scan(&rule.OldEnding, &rule.NewEnding)
+		if err != nil {
+			return nil, err
+		}
+		ruleIndex[rule.OldEnding] = len(rules)
+		rules = append(rules, rule)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	// stem column is the rule's old ending
+	exceptionRows, err := varnam.vstConn.Query("SELECT stem, exception FROM stem_exceptions ORDER BY id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer exceptionRows.Close()
+
+	for exceptionRows.Next() {
+		var rule, exception string
+		err = exceptionRows.Scan(&rule, &exception)
+		if err != nil {
+			return nil, err
+		}
+
+		if i, found := ruleIndex[rule]; found {
+			rules[i].Exceptions = append(rules[i].Exceptions, exception)
+		}
+	}
+
+	return rules, exceptionRows.Err()
+}
+
+// Apply the longest matching stem rule on word
+func (varnam *Varnam) stemWord(word string) (string, bool) {
+	for _, rule := range varnam.stemRules {
+		if len(word) <= len(rule.OldEnding) || !strings.HasSuffix(word, rule.OldEnding) {
+			continue
+		}
+
+		isException := false
+		for _, exception := range rule.Exceptions {
+			if strings.HasSuffix(word, exception) {
+				isException = true
+				break
+			}
+		}
+		if isException {
+			continue
+		}
+
+		return word[:len(word)-len(rule.OldEnding)] + rule.NewEnding, true
+	}
+
+	return word, false
+}
+
+// Find stems of a word by applying stem rules repeatedly.
+// Eg: "മരങ്ങളിൽ" => "മരങ്ങൾ" => "മരം"
+func (varnam *Varnam) getStems(word string) []string {
+	var stems []string
+
+	found := map[string]bool{word: true}
+	for {
+		stem, stemmed := varnam.stemWord(word)
+		if !stemmed || found[stem] {
+			break
+		}
+
+		stems = append(stems, stem)
+		found[stem] = true
+		word = stem
+	}
+
+	return stems
+}
+
+// Learn stems of a learnt word
+func (varnam *Varnam) learnStems(word string, weight int) error {
+	for _, stem := range varnam.getStems(word) {
+		// Stem can be too short to learn
+		_, err := varnam.getLearnableWord(stem)
+		if err != nil {
+			continue
+		}
+
+		_, err = varnam.learnWord(stem, weight)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Find words whose stem is in dictionary. The words are
+// made by tokenizer, so the ending of it is tokenized.
+// Eg: "മരത്തിൽ" is suggested if "മരം" was learnt
+func (varnam *Varnam) getFromStemDictionary(ctx context.Context, sugs []Suggestion) []Suggestion {
+	var results []Suggestion
+
+	if len(varnam.stemRules) == 0 {
+		return results
+	}
+
+	var (
+		stems       []string
+		stemToWords = map[string][]string{}
+	)
+
+	for _, sug := range sugs {
+		for _, stem := range varnam.getStems(sug.Word) {
+			if _, found := stemToWords[stem]; !found {
+				stems = append(stems, stem)
+			}
+			stemToWords[stem] = append(stemToWords[stem], sug.Word)
+		}
+	}
+
+	if len(stems) == 0 {
+		return results
+	}
+
+	added := map[string]bool{}
+	for _, item := range varnam.searchDictionary(ctx, stems, searchExactWords) {
+		for _, word := range stemToWords[item.word] {
+			if added[word] {
+				continue
+			}
+			added[word] = true
+
+			results = append(results, varnam.makeLearnedSuggestion(word, item.weight, item.learnedOn))
+		}
+	}
+
+	return results
+}
+
+// Add suggestions from stems in dictionary to result
+func (varnam *Varnam) addStemSuggestions(ctx context.Context, result *TransliterationResult) {
+	var tokenized []Suggestion
+	tokenized = append(tokenized, result.GreedyTokenized...)
+	tokenized = append(tokenized, result.TokenizerSuggestions...)
+
+	stemSugs := varnam.getFromStemDictionary(ctx, tokenized)
+	if len(stemSugs) == 0 {
+		return
+	}
+
+	found := map[string]bool{}
+	for _, sugs := range [][]Suggestion{result.ExactWords, result.ExactMatches, result.DictionarySuggestions} {
+		for _, sug := range sugs {
+			found[sug.Word] = true
+		}
+	}
+
+	for _, sug := range stemSugs {
+		if !found[sug.Word] {
+			result.DictionarySuggestions = append(result.DictionarySuggestions, sug)
+		}
+	}
+	result.DictionarySuggestions = SortSuggestions(result.DictionarySuggestions)
+}
+
+// VMCreateStemRule make a stem rule. newEnding can be empty
+func (varnam *Varnam) VMCreateStemRule(oldEnding string, newEnding string) error {
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
+	if oldEnding == "" {
+		return fmt.Errorf("old ending is empty")
+	}
+
+	if len(oldEnding) > VARNAM_SYMBOL_MAX || len(newEnding) > VARNAM_SYMBOL_MAX {
+		return fmt.Errorf("length of old ending or new ending should be less than VARNAM_SYMBOL_MAX")
+	}
+
+	var count int
+	err := varnam.vstConn.QueryRow("SELECT COUNT(*) FROM stemrules WHERE old_ending = ?", oldEnding).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		if varnam.VSTMakerConfig.IgnoreDuplicateTokens {
+			varnam.log(fmt.Sprintf("Stem rule for %s is already available. Ignoring duplicate", oldEnding))
+			return nil
+		}
+		return fmt.Errorf("there is already a stem rule for '%s'", oldEnding)
+	}
+
+	_, err = varnam.vstConn.Exec("INSERT INTO stemrules (old_ending, new_ending) VALUES (?, ?)", oldEnding, newEnding)
+	if err != nil {
+		return fmt.Errorf("Failed to persist stem rule: %s", err.Error())
+	}
+
+	return varnam.vmReloadStemRules()
+}
+
+// VMDeleteStemRule removes a stem rule and its exceptions
+func (varnam *Varnam) VMDeleteStemRule(oldEnding string) error {
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
+	_, err := varnam.vstConn.Exec("DELETE FROM stem_exceptions WHERE stem = ?", oldEnding)
+	if err != nil {
+		return err
+	}
+
+	_, err = varnam.vstConn.Exec("DELETE FROM stemrules WHERE old_ending = ?", oldEnding)
+	if err != nil {
+		return err
+	}
+
+	return varnam.vmReloadStemRules()
+}
+
+// VMCreateStemException make words ending with exception
+// not stemmed by the stem rule of oldEnding
+func (varnam *Varnam) VMCreateStemException(oldEnding string, exception string) error {
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
+	if exception == "" {
+		return fmt.Errorf("exception is empty")
+	}
+
+	if len(exception) > VARNAM_SYMBOL_MAX {
+		return fmt.Errorf("length of exception should be less than VARNAM_SYMBOL_MAX")
+	}
+
+	var count int
+	err := varnam.vstConn.QueryRow("SELECT COUNT(*) FROM stemrules WHERE old_ending = ?", oldEnding).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("stem rule for '%s' not found", oldEnding)
+	}
+
+	err = varnam.vstConn.QueryRow("SELECT COUNT(*) FROM stem_exceptions WHERE stem = ? AND exception = ?", oldEnding, exception).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err = varnam.vstConn.Exec("INSERT INTO stem_exceptions (stem, exception) VALUES (?, ?)", oldEnding, exception)
+	if err != nil {
+		return fmt.Errorf("Failed to persist stem exception: %s", err.Error())
+	}
+
+	return varnam.vmReloadStemRules()
+}
+
+// VMDeleteStemException removes an exception of a stem rule
+func (varnam *Varnam) VMDeleteStemException(oldEnding string, exception string) error {
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
+	_, err := varnam.vstConn.Exec("DELETE FROM stem_exceptions WHERE stem = ? AND exception = ?", oldEnding, exception)
+	if err != nil {
+		return err
+	}
+
+	return varnam.vmReloadStemRules()
+}
+
+// Load stem rules again after changing them, like
+// vmReloadSymbols(). Not done while buffering.
+func (varnam *Varnam) vmReloadStemRules() error {
+	if varnam.VSTMakerConfig.Buffering {
+		return nil
+	}
+	return varnam.setStemRules()
+}
@@ -9,9 +9,13 @@ package govarnam
 import (
 	"context"
 	sql "database/sql"
+	"database/sql/driver"
 	"fmt"
 	"log"
+	"net/url"
+	"path/filepath"
 	"strings"
+	"sync"
 	"unicode"
 
 	"github.com/mattn/go-sqlite3"
@@ -19,18 +23,18 @@ import (
 
 // Symbol result from VST
 type Symbol struct {
-	Identifier      int
-	Type            int
-	MatchType       int
-	Pattern         string
-	Value1          string
-	Value2          string
-	Value3          string
-	Tag             string
-	Weight          int
-	Priority        int
-	AcceptCondition int
-	Flags           int
+	Identifier      int    `json:"id"`
+	Type            int    `json:"type"`
+	MatchType       int    `json:"match_type"`
+	Pattern         string `json:"pattern"`
+	Value1          string `json:"value1"`
+	Value2          string `json:"value2"`
+	Value3          string `json:"value3"`
+	Tag             string `json:"tag"`
+	Weight          int    `json:"weight"`
+	Priority        int    `json:"priority"`
+	AcceptCondition int    `json:"accept_condition"`
+	Flags           int    `json:"flags"`
 }
 
 // Token info for making a suggestion
@@ -41,29 +45,101 @@ type Token struct {
 	character string // Non language character
 }
 
-var sqlite3WithLimitDriverRegistered bool
-var sqlite3Conn *sqlite3.SQLiteConn
-
-func openDB(path string) (*sql.DB, error) {
-	if !sqlite3WithLimitDriverRegistered {
-		sql.Register("sqlite3_with_limit", &sqlite3.SQLiteDriver{
-			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
-				sqlite3Conn = conn
-				return nil
-			},
-		})
-		sqlite3WithLimitDriverRegistered = true
+// SQLite driver of a DB. Each DB has its own driver so that
+// DBs of different instances don't share any state.
+type sqliteDriver struct {
+	*sqlite3.SQLiteDriver
+
+	limitsMutex sync.Mutex
+	limits      *sqliteLimits
+}
+
+// Limits of SQLite. Same for all connections, hence
+// read from the first connection made.
+type sqliteLimits struct {
+	variableNumber int
+	exprDepth      int
+}
+
+type sqliteConnector struct {
+	driver *sqliteDriver
+	dsn    string
+}
+
+func (connector *sqliteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return connector.driver.Open(connector.dsn)
+}
+
+func (connector *sqliteConnector) Driver() driver.Driver {
+	return connector.driver
+}
+
+// SQLite URI to open a file read only. Characters like
+// "?", "#" & "%" in path are percent-encoded.
+func getReadOnlyURI(path string) string {
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
 	}
+	return "file:" + strings.Join(segments, "/") + "?mode=ro"
+}
 
-	conn, err := sql.Open("sqlite3_with_limit", path)
+// Open a DB. DBs in attach are attached read only to every
+// connection, as schemas named by getLayerSchema()
+func openDB(path string, attach ...string) (*sql.DB, error) {
+	d := &sqliteDriver{}
+	d.SQLiteDriver = &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			// Used to decay stored weight when a word is learnt again
+			err := conn.RegisterFunc("decay_weight", decayWeight, true)
+			if err != nil {
+				return err
+			}
+
+			for i, attachPath := range attach {
+				_, err := conn.Exec("ATTACH DATABASE ? AS "+getLayerSchema(i+1), []driver.Value{getReadOnlyURI(attachPath)})
+				if err != nil {
+					return err
+				}
+			}
+
+			d.limitsMutex.Lock()
+			defer d.limitsMutex.Unlock()
+
+			if d.limits == nil {
+				d.limits = &sqliteLimits{
+					variableNumber: conn.GetLimit(sqlite3.SQLITE_LIMIT_VARIABLE_NUMBER),
+					exprDepth:      conn.GetLimit(sqlite3.SQLITE_LIMIT_EXPR_DEPTH),
+				}
+			}
+			return nil
+		},
+	}
+
+	return sql.OpenDB(&sqliteConnector{d, path}), nil
+}
+
+// Get SQLite limits of a DB made with openDB()
+func getDBLimits(db *sql.DB) (sqliteLimits, error) {
+	// Makes sure a connection was made
+	err := db.Ping()
 	if err != nil {
-		return nil, err
+		return sqliteLimits{}, err
 	}
-	return conn, nil
+
+	d := db.Driver().(*sqliteDriver)
+
+	d.limitsMutex.Lock()
+	defer d.limitsMutex.Unlock()
+
+	return *d.limits, nil
 }
 
 // InitVST initialize
 func (varnam *Varnam) InitVST(vstPath string) error {
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
 	var err error
 	varnam.vstConn, err = openDB(vstPath + "?_case_sensitive_like=on")
 
@@ -71,7 +147,16 @@ func (varnam *Varnam) InitVST(vstPath string) error {
 		return err
 	}
 
-	err = varnam.setPatternLongestLength()
+	err = varnam.setSymbols()
+	if err != nil {
+		return err
+	}
+
+	if varnam.LangRules.PatternLongestLength == 0 {
+		return fmt.Errorf("couldn't find longest pattern length")
+	}
+
+	err = varnam.setStemRules()
 	if err != nil {
 		return err
 	}
@@ -85,9 +170,25 @@ func (varnam *Varnam) InitVST(vstPath string) error {
 	return nil
 }
 
-// Find the longest pattern length
+// Load symbols of VST to memory. Done again
+// when VST is changed with VM functions.
+func (varnam *Varnam) setSymbols() error {
+	err := varnam.setPatternLongestLength()
+	if err != nil {
+		return err
+	}
+
+	err = varnam.setPatternRunes()
+	if err != nil {
+		return err
+	}
+
+	return varnam.setSymbolTries()
+}
+
+// Find the longest pattern length, 0 if there are no symbols
 func (varnam *Varnam) setPatternLongestLength() error {
-	rows, err := varnam.vstConn.Query("SELECT MAX(LENGTH(pattern)) FROM symbols")
+	rows, err := varnam.vstConn.Query("SELECT COALESCE(MAX(LENGTH(pattern)), 0) FROM symbols")
 	if err != nil {
 		return err
 	}
@@ -100,10 +201,6 @@ func (varnam *Varnam) setPatternLongestLength() error {
 		}
 	}
 
-	if length == 0 {
-		return fmt.Errorf("couldn't find longest pattern length")
-	}
-
 	varnam.LangRules.PatternLongestLength = length
 
 	return nil
@@ -143,103 +240,58 @@ func (varnam *Varnam) setSchemeInfo() {
 	}
 }
 
+// Find symbols having value1 or value2 as ch
 func (varnam *Varnam) searchPattern(ctx context.Context, ch string, matchType int, acceptCondition int) []Symbol {
-	var (
-		rows    *sql.Rows
-		err     error
-		results []Symbol
-	)
+	var results []Symbol
 
 	select {
 	case <-ctx.Done():
 		return results
 	default:
-		if matchType == VARNAM_MATCH_ALL {
-			rows, err = varnam.vstConn.QueryContext(ctx, "SELECT * FROM symbols WHERE (value1 = ? OR value2 = ?) AND (accept_condition = 0 OR accept_condition = ?) ORDER BY match_type ASC, weight DESC, priority DESC", ch, ch, acceptCondition)
-		} else {
-			rows, err = varnam.vstConn.QueryContext(ctx, "SELECT * FROM symbols WHERE (value1 = ? OR value2 = ?) AND match_type = ? AND (accept_condition = 0 OR accept_condition = ?)", ch, ch, matchType, acceptCondition)
-		}
-
-		if err != nil {
-			log.Print(err)
-			return results
-		}
-		defer rows.Close()
-
-		for rows.Next() {
-			var item Symbol
-			rows.Scan(&item.Identifier, &item.Type, &item.Pattern, &item.Value1, &item.Value2, &item.Value3, &item.Tag, &item.MatchType, &item.Priority, &item.AcceptCondition, &item.Flags, &item.Weight)
-			results = append(results, item)
-		}
-
-		err = rows.Err()
-		if err != nil {
-			log.Print(err)
-		}
-
-		return results
+		return varnam.valueTrie.get(ch).matchingSymbols(matchType, acceptCondition)
 	}
 }
 
-// Find longest pattern prefix matching symbols from VST
+// Find longest pattern prefix matching symbols from VST.
+// Results are ordered by length of pattern DESC, then
+// match_type ASC, weight DESC, priority DESC
 func (varnam *Varnam) findLongestPatternMatchSymbols(ctx context.Context, pattern []rune, matchType int, acceptCondition int) []Symbol {
 	var (
-		query      string
-		results    []Symbol
-		patternINs string
-		vals       []interface{}
+		results       []Symbol
+		prefixMatches [][]Symbol
 	)
 
-	if matchType != VARNAM_MATCH_ALL {
-		vals = append(vals, matchType)
-	}
-
-	vals = append(vals, acceptCondition)
-	vals = append(vals, string(pattern[0]))
-
-	for i := range pattern {
-		if i == 0 {
-			continue
-		}
-		patternINs += ", ?"
-		vals = append(vals, string(pattern[0:i+1]))
-	}
-
-	if varnam.Debug {
-		// The query will be made like :
-		//   SELECT * FROM symbols WHERE pattern IN ('e', 'en', 'ent', 'enth', 'entho')
-		// Will fetch the longest prefix match
-		// Idea from https://stackoverflow.com/a/1860279/1372424
-		fmt.Println(patternINs, vals)
-	}
-
 	select {
 	case <-ctx.Done():
 		return results
 	default:
-		if matchType == VARNAM_MATCH_ALL {
-			query = "SELECT * FROM `symbols` WHERE (accept_condition = 0 OR accept_condition = ?) AND pattern IN (? " + patternINs + ") ORDER BY LENGTH(pattern) DESC, match_type ASC, weight DESC, priority DESC"
-		} else {
-			query = "SELECT * FROM `symbols` WHERE match_type = ? AND (accept_condition = 0 OR accept_condition = ?) AND pattern IN (? " + patternINs + ") ORDER BY LENGTH(pattern) DESC"
-		}
+		// Walk down the trie with each character:
+		//   e -> en -> ent -> enth -> entho
+		node := varnam.patternTrie
+		for _, r := range pattern {
+			node = node.child(r)
+			if node == nil {
+				break
+			}
 
-		rows, err := varnam.vstConn.QueryContext(ctx, query, vals...)
+			matches := node.matchingSymbols(matchType, acceptCondition)
+			if len(matches) > 0 {
+				prefixMatches = append(prefixMatches, matches)
+			}
 
-		if err != nil {
-			log.Print(err)
-			return results
+			if len(node.symbols) > 0 && !hasMoreMatches(node.symbols, VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_PATTERN) {
+				// No longer pattern starts with this
+				break
+			}
 		}
-		defer rows.Close()
 
-		for rows.Next() {
-			var item Symbol
-			rows.Scan(&item.Identifier, &item.Type, &item.Pattern, &item.Value1, &item.Value2, &item.Value3, &item.Tag, &item.MatchType, &item.Priority, &item.AcceptCondition, &item.Flags, &item.Weight)
-			results = append(results, item)
+		if varnam.Debug {
+			fmt.Println(string(pattern), prefixMatches)
 		}
 
-		err = rows.Err()
-		if err != nil {
-			log.Print(err)
+		// Longest prefix first
+		for i := len(prefixMatches) - 1; i >= 0; i-- {
+			results = append(results, prefixMatches[i]...)
 		}
 
 		return results
@@ -247,7 +299,7 @@ func (varnam *Varnam) findLongestPatternMatchSymbols(ctx context.Context, patter
 }
 
 // Convert a string into Tokens for later processing
-func (varnam *Varnam) tokenizeWord(ctx context.Context, word string, matchType int, partial bool) *[]Token {
+func (varnam *Varnam) tokenizeWord(ctx context.Context, cache *transliterationCache, word string, matchType int, partial bool) *[]Token {
 	var results []Token
 
 	select {
@@ -256,8 +308,35 @@ func (varnam *Varnam) tokenizeWord(ctx context.Context, word string, matchType i
 	default:
 		runes := []rune(word)
 
+		cacheKey := tokensCacheKey{word, matchType, partial, varnam.LangRules.IndicDigits}
+		if cached, found := cache.getTokens(cacheKey); found {
+			return &cached.tokens
+		}
+
+		// Position in word where each token starts
+		var starts []int
+
 		i := 0
+
+		// Continue from tokens of a prefix of the word
+		if prefix, prefixLength, found := cache.getPrefixTokens(cacheKey); found {
+			for j := range prefix.tokens {
+				// A token is same for the word only if the characters
+				// after it checked for patterns are in the prefix too.
+				// The last character is checked differently, see
+				// VARNAM_TOKEN_ACCEPT_IF_ENDS_WITH below.
+				if j+1 == len(prefix.tokens) || prefix.starts[j]+varnam.LangRules.PatternLongestLength >= prefixLength {
+					break
+				}
+				results = append(results, prefix.tokens[j])
+				starts = append(starts, prefix.starts[j])
+				i = prefix.starts[j+1]
+			}
+		}
+
 		for i < len(runes) {
+			starts = append(starts, i)
+
 			end := i + varnam.LangRules.PatternLongestLength
 			if len(runes) < end {
 				end = len(runes)
@@ -322,12 +401,18 @@ func (varnam *Varnam) tokenizeWord(ctx context.Context, word string, matchType i
 				}
 			}
 		}
+
+		// Tokenization is complete only if it wasn't cancelled
+		if ctx.Err() == nil {
+			cache.addTokens(cacheKey, cachedTokens{results, starts})
+		}
+
 		return &results
 	}
 }
 
 // Tokenize end part of a word and append it to results
-func (varnam *Varnam) tokenizeRestOfWord(ctx context.Context, word string, sugs []Suggestion, limit int) []Suggestion {
+func (varnam *Varnam) tokenizeRestOfWord(ctx context.Context, cache *transliterationCache, word string, sugs []Suggestion, limit int) []Suggestion {
 	var results []Suggestion
 
 	if varnam.Debug {
@@ -335,7 +420,7 @@ func (varnam *Varnam) tokenizeRestOfWord(ctx context.Context, word string, sugs
 	}
 
 	tokensPointerChan := make(chan *[]Token)
-	go varnam.channelTokenizeWord(ctx, word, VARNAM_MATCH_ALL, true, tokensPointerChan)
+	go varnam.channelTokenizeWord(ctx, cache, word, VARNAM_MATCH_ALL, true, tokensPointerChan)
 
 	select {
 	case <-ctx.Done():
@@ -356,6 +441,9 @@ func (varnam *Varnam) tokenizeRestOfWord(ctx context.Context, word string, sugs
 				// Preserve original word's weight and timestamp
 				restOfWordSug.Weight += sug.Weight
 				restOfWordSug.LearnedOn = sug.LearnedOn
+				if sug.Score != nil {
+					varnam.addScore(&restOfWordSug, *sug.Score)
+				}
 				results = append(results, restOfWordSug)
 			}
 		}
@@ -417,6 +505,14 @@ func (varnam *Varnam) splitTextByConjunct(ctx context.Context, inputStr string)
 				// Last character
 				results = append(results, Token{VARNAM_TOKEN_SYMBOL, symbols, position, sequence})
 				position++
+			} else if !hasMoreMatches(symbols, VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_VALUE) {
+				// No longer conjunct starts with this sequence,
+				// no need to look at the next character
+				results = append(results, Token{VARNAM_TOKEN_SYMBOL, symbols, position, sequence})
+
+				sequence = ""
+				sequenceLength = 0
+				position++
 			} else {
 				prevSequence = sequence
 				prevSequenceMatches = symbols
@@ -428,6 +524,17 @@ func (varnam *Varnam) splitTextByConjunct(ctx context.Context, inputStr string)
 	return results
 }
 
+// Whether a longer pattern or value starts with that of symbols.
+// mask is one of VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_XXX
+func hasMoreMatches(symbols []Symbol, mask int) bool {
+	for _, symbol := range symbols {
+		if symbol.Flags&mask != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // Split a word by conjuncts. Returns string of conjuncts of first full word found
 func (varnam *Varnam) splitWordByConjunct(word string) []string {
 	ctx := context.Background()
@@ -596,6 +703,13 @@ func (varnam *Varnam) makeSearchSymbolQuery(queryPrefix string, searchCriteria S
 
 // SearchSymbolTable For searching symbol table
 func (varnam *Varnam) SearchSymbolTable(ctx context.Context, searchCriteria Symbol) ([]Symbol, error) {
+	varnam.mutex.RLock()
+	defer varnam.mutex.RUnlock()
+
+	return varnam.searchSymbolTable(ctx, searchCriteria)
+}
+
+func (varnam *Varnam) searchSymbolTable(ctx context.Context, searchCriteria Symbol) ([]Symbol, error) {
 	var results []Symbol
 
 	select {
@@ -621,7 +735,7 @@ func (varnam *Varnam) SearchSymbolTable(ctx context.Context, searchCriteria Symb
 func (varnam *Varnam) getVirama() (string, error) {
 	viramaSymbol := NewSearchSymbol()
 	viramaSymbol.Pattern = "~"
-	results, _ := varnam.SearchSymbolTable(context.Background(), viramaSymbol)
+	results, _ := varnam.searchSymbolTable(context.Background(), viramaSymbol)
 
 	if len(results) == 0 {
 		return "", fmt.Errorf("virama not found")
@@ -0,0 +1,182 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"sort"
+)
+
+// Prefix tree of VST symbols. Tokenizing happens on
+// every keystroke, so symbols are looked up from memory
+// instead of querying the VST for each character.
+type symbolTrie struct {
+	children map[rune]*symbolTrie
+
+	// Symbols whose key ends at this node. Sorted by
+	// match_type ASC, weight DESC, priority DESC
+	symbols []Symbol
+}
+
+func newSymbolTrie() *symbolTrie {
+	return &symbolTrie{
+		children: map[rune]*symbolTrie{},
+	}
+}
+
+func (trie *symbolTrie) insert(key string, symbol Symbol) {
+	node := trie
+	for _, r := range key {
+		child, found := node.children[r]
+		if !found {
+			child = newSymbolTrie()
+			node.children[r] = child
+		}
+		node = child
+	}
+	node.symbols = append(node.symbols, symbol)
+}
+
+// Get the child node of a character. Returns nil if not found
+func (trie *symbolTrie) child(r rune) *symbolTrie {
+	if trie == nil {
+		return nil
+	}
+	return trie.children[r]
+}
+
+// Get the node at the end of key. Returns nil if not found
+func (trie *symbolTrie) get(key string) *symbolTrie {
+	node := trie
+	for _, r := range key {
+		node = node.child(r)
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// Sort symbols in all nodes
+func (trie *symbolTrie) sort() {
+	sort.SliceStable(trie.symbols, func(i, j int) bool {
+		a, b := trie.symbols[i], trie.symbols[j]
+		if a.MatchType != b.MatchType {
+			return a.MatchType < b.MatchType
+		}
+		if a.Weight != b.Weight {
+			return a.Weight > b.Weight
+		}
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.Identifier < b.Identifier
+	})
+
+	for _, child := range trie.children {
+		child.sort()
+	}
+}
+
+// Symbols of a node that can be used at a position in word
+func (trie *symbolTrie) matchingSymbols(matchType int, acceptCondition int) []Symbol {
+	var results []Symbol
+
+	if trie == nil {
+		return results
+	}
+
+	for _, symbol := range trie.symbols {
+		if matchType != VARNAM_MATCH_ALL && symbol.MatchType != matchType {
+			continue
+		}
+		if symbol.AcceptCondition != 0 && symbol.AcceptCondition != acceptCondition {
+			continue
+		}
+		results = append(results, symbol)
+	}
+
+	return results
+}
+
+// Same as vmMakePrefixTree() but in memory
+func setSymbolPrefixFlags(symbols []Symbol) {
+	var patterns, values []string
+	for _, symbol := range symbols {
+		patterns = append(patterns, symbol.Pattern)
+		values = append(values, symbol.Value1, symbol.Value2)
+	}
+
+	patternPrefixes := findPrefixes(patterns)
+	valuePrefixes := findPrefixes(values)
+
+	for i := range symbols {
+		if patternPrefixes[symbols[i].Pattern] {
+			symbols[i].Flags |= VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_PATTERN
+		}
+		if valuePrefixes[symbols[i].Value1] || valuePrefixes[symbols[i].Value2] {
+			symbols[i].Flags |= VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_VALUE
+		}
+	}
+}
+
+// Load symbols from VST to tries. One indexed by
+// pattern for tokenizing and one by value1 & value2
+// for reverse lookups.
+func (varnam *Varnam) setSymbolTries() error {
+	rows, err := varnam.vstConn.Query("SELECT * FROM symbols")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var (
+		symbols        []Symbol
+		hasPrefixFlags bool
+	)
+
+	for rows.Next() {
+		var item Symbol
+		// weight can be NULL in VSTs, it'll be 0 then
+		rows.Scan(&item.Identifier, &item.Type, &item.Pattern, &item.Value1, &item.Value2, &item.Value3, &item.Tag, &item.MatchType, &item.Priority, &item.AcceptCondition, &item.Flags, &item.Weight)
+		symbols = append(symbols, item)
+
+		if item.Flags&VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_PATTERN != 0 {
+			hasPrefixFlags = true
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	// VSTs made before prefix flags were
+	// implemented in VST maker won't have them
+	if !hasPrefixFlags {
+		setSymbolPrefixFlags(symbols)
+	}
+
+	patternTrie := newSymbolTrie()
+	valueTrie := newSymbolTrie()
+
+	for _, item := range symbols {
+		patternTrie.insert(item.Pattern, item)
+
+		valueTrie.insert(item.Value1, item)
+		if item.Value2 != "" && item.Value2 != item.Value1 {
+			valueTrie.insert(item.Value2, item)
+		}
+	}
+
+	patternTrie.sort()
+	valueTrie.sort()
+
+	varnam.patternTrie = patternTrie
+	varnam.valueTrie = valueTrie
+
+	return nil
+}
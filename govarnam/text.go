@@ -0,0 +1,177 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"time"
+	"unicode"
+)
+
+// TextSegment is a part of text given for transliteration.
+// A text is split into words and non-word spans (whitespace,
+// punctuation, numbers etc.). Only words are transliterated.
+type TextSegment struct {
+	// Position of segment in text. Counted in characters (runes), not bytes.
+	// Start is inclusive, End is exclusive.
+	Start int
+	End   int
+
+	// Original text of segment
+	Text string
+
+	IsWord bool
+
+	// Ranked suggestions for the word, same as Transliterate().
+	// Empty for non-word segments.
+	Suggestions []Suggestion
+}
+
+// Find characters that are used in VST patterns.
+// Non-letter characters can be patterns too (~ for virama, _ for ZWNJ,
+// inscript uses most punctuation keys). These are part of words.
+func (varnam *Varnam) setPatternRunes() error {
+	rows, err := varnam.vstConn.Query(
+		"SELECT pattern FROM symbols WHERE type NOT IN (?, ?, ?)",
+		VARNAM_SYMBOL_NUMBER,
+		VARNAM_SYMBOL_SYMBOL,
+		VARNAM_SYMBOL_PERIOD,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	varnam.patternRunes = map[rune]bool{}
+
+	for rows.Next() {
+		var pattern string
+		rows.Scan(&pattern)
+
+		for _, r := range pattern {
+			varnam.patternRunes[r] = true
+		}
+	}
+
+	return rows.Err()
+}
+
+// Whether a character should be considered part of a word
+func (varnam *Varnam) isWordRune(r rune) bool {
+	if unicode.IsDigit(r) {
+		// Numbers are transliterated only if indic digits is enabled
+		return varnam.LangRules.IndicDigits
+	}
+
+	if unicode.IsLetter(r) || unicode.IsMark(r) {
+		return true
+	}
+
+	if string(r) == ZWJ || string(r) == ZWNJ {
+		return true
+	}
+
+	return varnam.patternRunes[r]
+}
+
+// Split text into word and non-word segments
+func (varnam *Varnam) splitTextIntoSegments(text string) []TextSegment {
+	var segments []TextSegment
+
+	runes := []rune(text)
+
+	start := 0
+	for start < len(runes) {
+		isWord := varnam.isWordRune(runes[start])
+
+		end := start + 1
+		for end < len(runes) && varnam.isWordRune(runes[end]) == isWord {
+			end++
+		}
+
+		segments = append(segments, TextSegment{
+			Start:  start,
+			End:    end,
+			Text:   string(runes[start:end]),
+			IsWord: isWord,
+		})
+
+		start = end
+	}
+
+	return segments
+}
+
+// TransliterateText transliterate a text having multiple words.
+// Text is split into segments, and each word is transliterated in parallel.
+// Whitespace, punctuation & numbers in between are kept as non-word segments.
+func (varnam *Varnam) TransliterateText(ctx context.Context, text string) ([]TextSegment, error) {
+	start := time.Now()
+
+	segments := varnam.splitTextIntoSegments(text)
+
+	// Same word may repeat in the text. Transliterate it only once.
+	channels := map[string]chan []Suggestion{}
+
+	// Don't run too many transliterations at once,
+	// each of them makes multiple DB queries
+	semaphore := make(chan struct{}, runtime.NumCPU())
+
+	for _, segment := range segments {
+		if !segment.IsWord {
+			continue
+		}
+		if _, ok := channels[segment.Text]; ok {
+			continue
+		}
+
+		// Buffered so that goroutines won't block if we return early
+		channel := make(chan []Suggestion, 1)
+		channels[segment.Text] = channel
+
+		go func(word string, channel chan []Suggestion) {
+			select {
+			case <-ctx.Done():
+				close(channel)
+				return
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+				varnam.channelTransliterate(ctx, word, channel)
+			}
+		}(segment.Text, channel)
+	}
+
+	results := map[string][]Suggestion{}
+
+	for word, channel := range channels {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case sugs := <-channel:
+			results[word] = sugs
+		}
+	}
+
+	// A transliteration could've been cut short by the context
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	for i := range segments {
+		if segments[i].IsWord {
+			segments[i].Suggestions = results[segments[i].Text]
+		}
+	}
+
+	if LOG_TIME_TAKEN {
+		log.Printf("%s took %v\n", "TransliterateText", time.Since(start))
+	}
+
+	return segments, nil
+}
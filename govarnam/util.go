@@ -2,6 +2,8 @@ package govarnam
 
 import (
 	"os"
+	"sort"
+	"strings"
 	"unicode/utf8"
 )
 
@@ -44,3 +46,30 @@ func dirExists(loc string) bool {
 	}
 	return info.IsDir()
 }
+
+// Find strings that are a prefix of another string in items.
+// When sorted, if a string is a prefix of any other string,
+// the string right after it will have it as prefix.
+func findPrefixes(items []string) map[string]bool {
+	sorted := make([]string, len(items))
+	copy(sorted, items)
+	sort.Strings(sorted)
+
+	prefixes := map[string]bool{}
+	for i := 0; i < len(sorted)-1; i++ {
+		if sorted[i] == "" {
+			continue
+		}
+
+		next := i + 1
+		for next < len(sorted) && sorted[next] == sorted[i] {
+			next++
+		}
+
+		if next < len(sorted) && strings.HasPrefix(sorted[next], sorted[i]) {
+			prefixes[sorted[i]] = true
+		}
+	}
+
+	return prefixes
+}
@@ -0,0 +1,242 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// VSTMetadataChange a metadata that differs between VSTs.
+// Old or New is empty if the key was added or removed.
+type VSTMetadataChange struct {
+	Key string `json:"key"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// VSTSymbolChange a symbol that is modified
+type VSTSymbolChange struct {
+	Old Symbol `json:"old"`
+	New Symbol `json:"new"`
+
+	// Changed columns. See diffSymbol()
+	Fields []string `json:"fields"`
+}
+
+// VSTDiff difference between two VSTs. Symbols are matched
+// by pattern, value1, match_type and accept_condition.
+type VSTDiff struct {
+	Metadata []VSTMetadataChange `json:"metadata"`
+	Added    []Symbol            `json:"added"`
+	Removed  []Symbol            `json:"removed"`
+	Modified []VSTSymbolChange   `json:"modified"`
+}
+
+// IsEmpty whether VSTs are same
+func (diff *VSTDiff) IsEmpty() bool {
+	return len(diff.Metadata) == 0 && len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Modified) == 0
+}
+
+type vstSymbolKey struct {
+	pattern         string
+	value1          string
+	matchType       int
+	acceptCondition int
+}
+
+func getVSTSymbolKey(symbol Symbol) vstSymbolKey {
+	return vstSymbolKey{symbol.Pattern, symbol.Value1, symbol.MatchType, symbol.AcceptCondition}
+}
+
+// Find changed columns of a symbol. Identifier and flags
+// are made by VST maker, so they're not compared.
+func diffSymbol(a Symbol, b Symbol) []string {
+	var fields []string
+
+	if a.Type != b.Type {
+		fields = append(fields, "type")
+	}
+	if a.Value2 != b.Value2 {
+		fields = append(fields, "value2")
+	}
+	if a.Value3 != b.Value3 {
+		fields = append(fields, "value3")
+	}
+	if a.Tag != b.Tag {
+		fields = append(fields, "tag")
+	}
+	if a.Priority != b.Priority {
+		fields = append(fields, "priority")
+	}
+	if a.Weight != b.Weight {
+		fields = append(fields, "weight")
+	}
+
+	return fields
+}
+
+func diffVSTMetadata(a map[string]string, b map[string]string) []VSTMetadataChange {
+	var changes []VSTMetadataChange
+
+	keys := map[string]bool{}
+	for key := range a {
+		keys[key] = true
+	}
+	for key := range b {
+		keys[key] = true
+	}
+
+	for key := range keys {
+		if a[key] != b[key] {
+			changes = append(changes, VSTMetadataChange{key, a[key], b[key]})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+
+	return changes
+}
+
+func diffVSTSymbols(diff *VSTDiff, a []Symbol, b []Symbol) {
+	// A key can have more than one symbol in a broken VST
+	aSymbols := map[vstSymbolKey][]Symbol{}
+	for _, symbol := range a {
+		key := getVSTSymbolKey(symbol)
+		aSymbols[key] = append(aSymbols[key], symbol)
+	}
+
+	matched := map[vstSymbolKey]int{}
+	for _, symbol := range b {
+		key := getVSTSymbolKey(symbol)
+
+		i := matched[key]
+		if i >= len(aSymbols[key]) {
+			diff.Added = append(diff.Added, symbol)
+			continue
+		}
+		matched[key]++
+
+		old := aSymbols[key][i]
+		fields := diffSymbol(old, symbol)
+		if len(fields) > 0 {
+			diff.Modified = append(diff.Modified, VSTSymbolChange{old, symbol, fields})
+		}
+	}
+
+	for _, symbol := range a {
+		key := getVSTSymbolKey(symbol)
+		if matched[key] > 0 {
+			matched[key]--
+			continue
+		}
+		diff.Removed = append(diff.Removed, symbol)
+	}
+}
+
+// Describe a symbol like in scheme source
+func formatDiffSymbol(symbol Symbol) string {
+	sectionName := getSchemeSectionName(symbol.Type)
+	if sectionName == "" {
+		sectionName = fmt.Sprintf("type=%d", symbol.Type)
+	}
+
+	matchTypeName := getSchemeName(schemeMatchTypes, symbol.MatchType)
+	if matchTypeName == "" {
+		matchTypeName = fmt.Sprint(symbol.MatchType)
+	}
+
+	return "[" + sectionName + " match=" + matchTypeName + "] " + formatSchemeToken(symbol)
+}
+
+// Text human readable diff
+func (diff *VSTDiff) Text() string {
+	var text strings.Builder
+
+	for _, change := range diff.Metadata {
+		text.WriteString(fmt.Sprintf("metadata %s: %q => %q\n", change.Key, change.Old, change.New))
+	}
+
+	for _, symbol := range diff.Added {
+		text.WriteString("+ " + formatDiffSymbol(symbol) + "\n")
+	}
+
+	for _, symbol := range diff.Removed {
+		text.WriteString("- " + formatDiffSymbol(symbol) + "\n")
+	}
+
+	for _, change := range diff.Modified {
+		var changes []string
+		for _, field := range change.Fields {
+			switch field {
+			case "type":
+				changes = append(changes, fmt.Sprintf("type %s => %s", getSchemeSectionName(change.Old.Type), getSchemeSectionName(change.New.Type)))
+			case "value2":
+				changes = append(changes, fmt.Sprintf("value2 %q => %q", change.Old.Value2, change.New.Value2))
+			case "value3":
+				changes = append(changes, fmt.Sprintf("value3 %q => %q", change.Old.Value3, change.New.Value3))
+			case "tag":
+				changes = append(changes, fmt.Sprintf("tag %q => %q", change.Old.Tag, change.New.Tag))
+			case "priority":
+				changes = append(changes, fmt.Sprintf("priority %d => %d", change.Old.Priority, change.New.Priority))
+			case "weight":
+				changes = append(changes, fmt.Sprintf("weight %d => %d", change.Old.Weight, change.New.Weight))
+			}
+		}
+		text.WriteString("~ " + formatDiffSymbol(change.Old) + " : " + strings.Join(changes, ", ") + "\n")
+	}
+
+	text.WriteString(fmt.Sprintf(
+		"%d added, %d removed, %d modified, %d metadata changed\n",
+		len(diff.Added),
+		len(diff.Removed),
+		len(diff.Modified),
+		len(diff.Metadata),
+	))
+
+	return text.String()
+}
+
+// DiffVST compare VST at vstPathB against VST at vstPathA
+func DiffVST(vstPathA string, vstPathB string) (*VSTDiff, error) {
+	var (
+		metadata [2]map[string]string
+		symbols  [2][]Symbol
+	)
+
+	for i, vstPath := range []string{vstPathA, vstPathB} {
+		varnam, err := vmOpenReadOnly(vstPath)
+		if err != nil {
+			return nil, err
+		}
+
+		metadata[i], err = varnam.vmGetMetadata()
+		if err == nil {
+			symbols[i], err = varnam.vmGetAllSymbols()
+		}
+		varnam.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Empty lists instead of null in JSON
+	diff := &VSTDiff{
+		Metadata: []VSTMetadataChange{},
+		Added:    []Symbol{},
+		Removed:  []Symbol{},
+		Modified: []VSTSymbolChange{},
+	}
+	diff.Metadata = append(diff.Metadata, diffVSTMetadata(metadata[0], metadata[1])...)
+	diffVSTSymbols(diff, symbols[0], symbols[1])
+
+	return diff, nil
+}
@@ -8,15 +8,13 @@ package govarnam
 
 import (
 	"context"
-	sql "database/sql"
 	"fmt"
+	"strings"
 	"time"
 )
 
 // VM, vm = Vst Maker
-// Ported from libvarnam. Some are not ported:
-// * stem rules
-// * symbols flag setting
+// Ported from libvarnam. Stem rules are in stemmer.go
 
 // VMInit init
 func VMInit(vstPath string) (*Varnam, error) {
@@ -33,9 +31,75 @@ func VMInit(vstPath string) (*Varnam, error) {
 		return nil, err
 	}
 
+	err = varnam.setSymbols()
+	if err != nil {
+		return nil, err
+	}
+
 	return &varnam, nil
 }
 
+// Open a VST only for reading
+func vmOpenReadOnly(vstPath string) (*Varnam, error) {
+	if !fileExists(vstPath) {
+		return nil, fmt.Errorf("VST not found: %s", vstPath)
+	}
+
+	varnam := Varnam{}
+
+	var err error
+	varnam.vstConn, err = openDB(getReadOnlyURI(vstPath))
+	if err != nil {
+		return nil, err
+	}
+
+	return &varnam, nil
+}
+
+func (varnam *Varnam) vmGetMetadata() (map[string]string, error) {
+	metadata := map[string]string{}
+
+	rows, err := varnam.vstConn.Query("SELECT key, value FROM metadata")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, value string
+		rows.Scan(&key, &value)
+		metadata[key] = value
+	}
+
+	return metadata, rows.Err()
+}
+
+// Get all symbols in the order they were made
+func (varnam *Varnam) vmGetAllSymbols() ([]Symbol, error) {
+	rows, err := varnam.vstConn.Query(`
+		SELECT id, type, pattern, value1, IFNULL(value2, ''), IFNULL(value3, ''), IFNULL(tag, ''),
+			match_type, IFNULL(priority, 0), IFNULL(accept_condition, 0), IFNULL(flags, 0), IFNULL(weight, 0)
+		FROM symbols
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var symbols []Symbol
+	for rows.Next() {
+		var symbol Symbol
+		err = rows.Scan(&symbol.Identifier, &symbol.Type, &symbol.Pattern, &symbol.Value1, &symbol.Value2, &symbol.Value3, &symbol.Tag, &symbol.MatchType, &symbol.Priority, &symbol.AcceptCondition, &symbol.Flags, &symbol.Weight)
+		if err != nil {
+			return nil, err
+		}
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols, rows.Err()
+}
+
 func (varnam *Varnam) vmEnsureSchemaExists() error {
 	queries := []string{
 		`
@@ -130,6 +194,19 @@ func (varnam *Varnam) vmDiscardChanges() error {
 
 // VMCreateToken Create Token
 func (varnam *Varnam) VMCreateToken(pattern string, value1 string, value2 string, value3 string, tag string, symbolType int, matchType int, priority int, acceptCondition int, buffered bool) error {
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
+	err := varnam.vmCreateToken(pattern, value1, value2, value3, tag, symbolType, matchType, priority, acceptCondition, 0, buffered)
+	if err != nil {
+		return err
+	}
+
+	return varnam.vmReloadSymbols()
+}
+
+// Create token with a weight. Weight 0 is stored as NULL
+func (varnam *Varnam) vmCreateToken(pattern string, value1 string, value2 string, value3 string, tag string, symbolType int, matchType int, priority int, acceptCondition int, weight int, buffered bool) error {
 	if pattern == "" || value1 == "" {
 		return fmt.Errorf("pattern or value1 is empty")
 	}
@@ -177,7 +254,7 @@ func (varnam *Varnam) VMCreateToken(pattern string, value1 string, value2 string
 				value2WithVirama += virama
 			}
 
-			err := varnam.vmPersistToken(patternExceptLastChar, value1WithVirama, value2WithVirama, value3, tag, VARNAM_SYMBOL_DEAD_CONSONANT, matchType, priority, acceptCondition)
+			err := varnam.vmPersistToken(patternExceptLastChar, value1WithVirama, value2WithVirama, value3, tag, VARNAM_SYMBOL_DEAD_CONSONANT, matchType, priority, acceptCondition, 0)
 
 			if err != nil {
 				varnam.vmDiscardChanges()
@@ -196,7 +273,7 @@ func (varnam *Varnam) VMCreateToken(pattern string, value1 string, value2 string
 		value2 = ZWJ
 	}
 
-	err := varnam.vmPersistToken(pattern, value1, value2, value3, tag, symbolType, matchType, priority, acceptCondition)
+	err := varnam.vmPersistToken(pattern, value1, value2, value3, tag, symbolType, matchType, priority, acceptCondition, weight)
 	if err != nil {
 		if buffered {
 			varnam.vmDiscardChanges()
@@ -205,11 +282,10 @@ func (varnam *Varnam) VMCreateToken(pattern string, value1 string, value2 string
 	}
 
 	if !buffered {
-		// TODO flags is not used in govarnam
-		// err = varnam.vmMakePrefixTree()
-		// if err != nil {
-		// 	return err
-		// }
+		err = varnam.vmMakePrefixTree()
+		if err != nil {
+			return err
+		}
 
 		err = varnam.vmStampVersion()
 		if err != nil {
@@ -220,7 +296,7 @@ func (varnam *Varnam) VMCreateToken(pattern string, value1 string, value2 string
 	return nil
 }
 
-func (varnam *Varnam) vmPersistToken(pattern string, value1 string, value2 string, value3 string, tag string, symbolType int, matchType int, priority int, acceptCondition int) error {
+func (varnam *Varnam) vmPersistToken(pattern string, value1 string, value2 string, value3 string, tag string, symbolType int, matchType int, priority int, acceptCondition int, weight int) error {
 	if pattern == "" || value1 == "" || !(symbolType >= VARNAM_SYMBOL_VOWEL && symbolType <= VARNAM_SYMBOL_PERIOD) {
 		return fmt.Errorf("arguments invalid")
 	}
@@ -239,7 +315,7 @@ func (varnam *Varnam) vmPersistToken(pattern string, value1 string, value2 strin
 		return fmt.Errorf("there is already a match available for '%s => %s'. Duplicate entries are not allowed", pattern, value1)
 	}
 
-	query := "INSERT OR IGNORE INTO symbols (type, pattern, value1, value2, value3, tag, match_type, priority, accept_condition) VALUES (?, trim(?), trim(?), trim(?), trim(?), trim(?), ?, ?, ?)"
+	query := "INSERT OR IGNORE INTO symbols (type, pattern, value1, value2, value3, tag, match_type, priority, accept_condition, weight) VALUES (?, trim(?), trim(?), trim(?), trim(?), trim(?), ?, ?, ?, NULLIF(?, 0))"
 
 	bgContext := context.Background()
 
@@ -252,7 +328,7 @@ func (varnam *Varnam) vmPersistToken(pattern string, value1 string, value2 strin
 	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, symbolType, pattern, value1, value2, value3, tag, matchType, priority, acceptCondition)
+	_, err = stmt.ExecContext(ctx, symbolType, pattern, value1, value2, value3, tag, matchType, priority, acceptCondition, weight)
 	if err != nil {
 		return fmt.Errorf("Failed to persist token: %s", err.Error())
 	}
@@ -271,7 +347,7 @@ func (varnam *Varnam) vmAlreadyPersisted(pattern string, value1 string, matchTyp
 		searchCriteria.Value1 = value1
 	}
 
-	result, err := varnam.SearchSymbolTable(context.Background(), searchCriteria)
+	result, err := varnam.searchSymbolTable(context.Background(), searchCriteria)
 	if err != nil {
 		return false, err
 	}
@@ -281,52 +357,89 @@ func (varnam *Varnam) vmAlreadyPersisted(pattern string, value1 string, matchTyp
 
 // VMDeleteToken Removes a token from VST
 func (varnam *Varnam) VMDeleteToken(searchCriteria Symbol) error {
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
 	query, values := varnam.makeSearchSymbolQuery("DELETE FROM symbols", searchCriteria)
 	_, err := varnam.vstConn.Exec(query, values...)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return varnam.vmReloadSymbols()
+}
+
+// Make symbols in memory again after changing VST, so that
+// transliteration sees the change. Not done while buffering,
+// VMFlushBuffer() does it after writing the changes.
+func (varnam *Varnam) vmReloadSymbols() error {
+	if varnam.VSTMakerConfig.Buffering {
+		return nil
+	}
+	return varnam.setSymbols()
 }
 
-// Makes a prefix tree. This fills up the flags column
-// TODO incomplete
+// Makes a prefix tree. This fills up the flags column.
+// A symbol is flagged if its pattern (or value) is a prefix
+// of another symbol's pattern (or value). Tokenizer can stop
+// reading more characters when there are no more matches.
 func (varnam *Varnam) vmMakePrefixTree() error {
-	for _, columnName := range []string{"pattern", "value1", "value2"} {
-		stmt, err := varnam.vstConn.Prepare(fmt.Sprintf("SELECT id, %s FROM symbols GROUP BY %s ORDER BY LENGTH(%s) ASC", columnName, columnName, columnName))
+	err := varnam.vmFindPrefixesAndUpdateFlags([]string{"pattern"}, VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_PATTERN)
+	if err != nil {
+		return err
+	}
+
+	// Values are searched with value1 OR value2
+	return varnam.vmFindPrefixesAndUpdateFlags([]string{"value1", "value2"}, VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_VALUE)
+}
 
+func (varnam *Varnam) vmFindPrefixesAndUpdateFlags(columnNames []string, mask int) error {
+	var (
+		values  []string
+		clauses []string
+	)
+
+	for _, columnName := range columnNames {
+		rows, err := varnam.vstConn.Query(fmt.Sprintf("SELECT DISTINCT %s FROM symbols WHERE %s != ''", columnName, columnName))
 		if err != nil {
-			varnam.log(err.Error())
-			return nil
+			return err
 		}
 
-		var mask int
-		if columnName == "pattern" {
-			mask = VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_PATTERN
-		} else {
-			mask = VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_VALUE
+		for rows.Next() {
+			var value string
+			rows.Scan(&value)
+			values = append(values, value)
 		}
+		rows.Close()
 
-		updateStmt, err := varnam.vstConn.Prepare(fmt.Sprintf("UPDATE symbols SET flags = flags | %d WHERE %s = ?", mask, columnName))
-		if err != nil {
-			varnam.log(err.Error())
-		}
+		clauses = append(clauses, columnName+" = ?")
+	}
 
-		varnam.vmFindPrefixesAndUpdateFlags(stmt, updateStmt)
-		stmt.Close()
-		updateStmt.Close()
+	// Tokens might have been removed, so clear old flags
+	_, err := varnam.vstConn.Exec(fmt.Sprintf("UPDATE symbols SET flags = flags & ~%d", mask))
+	if err != nil {
+		return err
 	}
 
-	return nil
-}
+	updateStmt, err := varnam.vstConn.Prepare(fmt.Sprintf("UPDATE symbols SET flags = flags | %d WHERE %s", mask, strings.Join(clauses, " OR ")))
+	if err != nil {
+		return err
+	}
+	defer updateStmt.Close()
+
+	for prefix := range findPrefixes(values) {
+		var args []interface{}
+		for range columnNames {
+			args = append(args, prefix)
+		}
 
-func (varnam *Varnam) vmFindPrefixesAndUpdateFlags(stmt *sql.Stmt, updateStmt *sql.Stmt) {
-	type symbolIDMap struct {
-		symbol string
-		id     int
+		_, err = updateStmt.Exec(args...)
+		if err != nil {
+			return err
+		}
 	}
-	// TODO incomplete
+
+	return nil
 }
 
 func (varnam *Varnam) vmStampVersion() error {
@@ -341,6 +454,9 @@ func (varnam *Varnam) vmAddMetadata(key string, value string) error {
 
 // VMSetSchemeDetails set scheme details
 func (varnam *Varnam) VMSetSchemeDetails(sd SchemeDetails) error {
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
 	if len(sd.LangCode) != 2 {
 		return fmt.Errorf("language code should be one of ISO 639-1 two letter codes")
 	}
@@ -378,14 +494,30 @@ func (varnam *Varnam) VMSetSchemeDetails(sd SchemeDetails) error {
 
 // VMFlushBuffer flush
 func (varnam *Varnam) VMFlushBuffer() error {
-	// varnam.vmMakePrefixTree()
+	varnam.lockVST()
+	defer varnam.unlockVST()
+
+	err := varnam.vmMakePrefixTree()
+	if err != nil {
+		return err
+	}
+
+	err = varnam.vmStampVersion()
+	if err != nil {
+		return err
+	}
+
+	err = varnam.vmFlushChanges()
+	if err != nil {
+		return err
+	}
 
-	err := varnam.vmStampVersion()
+	err = varnam.vmReloadSymbols()
 	if err != nil {
 		return err
 	}
 
-	return varnam.vmFlushChanges()
+	return varnam.vmReloadStemRules()
 }
 
 // Checks if the string has inherent 'a' sound. If yes, we can infer dead consonant from it
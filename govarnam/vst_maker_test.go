@@ -2,7 +2,10 @@ package govarnam
 
 import (
 	"context"
+	"encoding/json"
+	"os"
 	"path"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -227,8 +230,504 @@ func TestMaxLengthCheck(t *testing.T) {
 	assertEqual(t, err != nil, true)
 }
 
-// TODO: incomplete API
 func TestPrefixTree(t *testing.T) {
-	// varnam, err := initTestVM()
-	// checkError(err)
+	varnam, err := initTestVM()
+	checkError(err)
+
+	getFlags := func(pattern string) int {
+		search := NewSearchSymbol()
+		search.Pattern = pattern
+		symbols, err := varnam.SearchSymbolTable(context.Background(), search)
+		checkError(err)
+		return symbols[0].Flags
+	}
+
+	err = varnam.VMCreateToken("ptka", "ക", "", "", "", VARNAM_SYMBOL_CONSONANT, VARNAM_MATCH_EXACT, 0, 0, false)
+	checkError(err)
+
+	// Buffered tokens get flags on flush
+	err = varnam.VMCreateToken("ptk", "ക്", "", "", "", VARNAM_SYMBOL_DEAD_CONSONANT, VARNAM_MATCH_EXACT, 0, 0, true)
+	checkError(err)
+	err = varnam.VMCreateToken("ptkka", "ക്ക", "", "", "", VARNAM_SYMBOL_CONSONANT, VARNAM_MATCH_EXACT, 0, 0, true)
+	checkError(err)
+	assertEqual(t, getFlags("ptk"), 0)
+
+	err = varnam.VMFlushBuffer()
+	checkError(err)
+
+	// ptk is prefix of ptka, ptkka. ക് is prefix of ക്ക
+	assertEqual(t, getFlags("ptk"), VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_PATTERN|VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_VALUE)
+
+	// ക is prefix of ക്, ക്ക
+	assertEqual(t, getFlags("ptka"), VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_VALUE)
+
+	assertEqual(t, getFlags("ptkka"), 0)
+
+	// Flags are updated when token is made without buffering
+	err = varnam.VMCreateToken("ptkkaa", "ക്കാ", "", "", "", VARNAM_SYMBOL_CONSONANT_VOWEL, VARNAM_MATCH_EXACT, 0, 0, false)
+	checkError(err)
+	assertEqual(t, getFlags("ptkka"), VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_PATTERN|VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_VALUE)
+}
+
+func TestCompileScheme(t *testing.T) {
+	srcPath := makeFile("scheme.txt", `
+# Test scheme
+[metadata]
+scheme-id = test
+lang-code = ml
+scheme-display-name = "Test Scheme" # Comment
+scheme-author = Varnam
+scheme-stable = true
+
+[settings]
+dead-consonants = true
+
+[vowels]
+a അ
+aa ആ ാ
+A ആ ാ match=possibility weight=10
+
+[consonants]
+ka ക
+ra ര tag="my \"tag\" x"
+
+[consonants tag=chill accept=ends-with]
+n ൻ priority=2
+
+[virama]
+~ ്
+
+[symbols]
+"#" "#"
+"=" =
+
+[stem-rules]
+ിൽ ്
+ത്തിൽ ം
+
+[stem-exceptions]
+ിൽ ടിൽ
+`)
+	vstPath := path.Join(testTempDir, "scheme.vst")
+
+	err := CompileScheme(srcPath, vstPath)
+	checkError(err)
+
+	varnam, err := Init(vstPath, path.Join(testTempDir, "scheme.vst.learnings"))
+	checkError(err)
+	defer varnam.Close()
+
+	assertEqual(t, varnam.SchemeDetails.Identifier, "test")
+	assertEqual(t, varnam.SchemeDetails.DisplayName, "Test Scheme")
+	assertEqual(t, varnam.SchemeDetails.IsStable, true)
+
+	search := func(pattern string) []Symbol {
+		searchCriteria := NewSearchSymbol()
+		searchCriteria.Pattern = pattern
+		symbols, err := varnam.SearchSymbolTable(context.Background(), searchCriteria)
+		checkError(err)
+		return symbols
+	}
+
+	symbols := search("A")
+	assertEqual(t, len(symbols), 1)
+	assertEqual(t, symbols[0].Value2, "ാ")
+	assertEqual(t, symbols[0].MatchType, VARNAM_MATCH_POSSIBILITY)
+	assertEqual(t, symbols[0].Weight, 10)
+
+	symbols = search("ra")
+	assertEqual(t, len(symbols), 1)
+	assertEqual(t, symbols[0].Tag, `my "tag" x`)
+
+	symbols = search("n")
+	assertEqual(t, len(symbols), 1)
+	assertEqual(t, symbols[0].Tag, CHIL_TAG)
+	assertEqual(t, symbols[0].AcceptCondition, VARNAM_TOKEN_ACCEPT_IF_ENDS_WITH)
+	assertEqual(t, symbols[0].Priority, 2)
+
+	// Dead consonant generated from ka
+	symbols = search("k")
+	assertEqual(t, len(symbols), 1)
+	assertEqual(t, symbols[0].Value1, "ക്")
+	assertEqual(t, symbols[0].Type, VARNAM_SYMBOL_DEAD_CONSONANT)
+
+	assertEqual(t, search("#")[0].Value1, "#")
+	assertEqual(t, search("=")[0].Value1, "=")
+
+	// Prefix flags are set
+	assertEqual(t, search("a")[0].Flags&VARNAM_SYMBOL_FLAGS_MORE_MATCHES_FOR_PATTERN != 0, true)
+
+	// Longest ending first
+	assertEqual(t, len(varnam.stemRules), 2)
+	assertEqual(t, varnam.stemRules[0].OldEnding, "ത്തിൽ")
+	assertEqual(t, varnam.stemRules[1].NewEnding, "്")
+	assertEqual(t, len(varnam.stemRules[1].Exceptions), 1)
+	assertEqual(t, varnam.stemRules[1].Exceptions[0], "ടിൽ")
+}
+
+func TestCompileSchemeErrors(t *testing.T) {
+	srcPath := makeFile("scheme-errors.txt", `[metadata]
+lang-code = ml
+
+[vowels]
+a
+aa ആ ാ match=exact2
+[unknown]
+`)
+	vstPath := path.Join(testTempDir, "scheme-errors.vst")
+
+	err := CompileScheme(srcPath, vstPath)
+	assertEqual(t, err.Error(), `line 5: expected pattern and value
+line 6: invalid match type 'exact2'
+line 7: unknown section 'unknown'
+metadata: scheme-id is required`)
+	assertEqual(t, fileExists(vstPath), false)
+
+	// Errors from VST maker have line number too
+	srcPath = makeFile("scheme-errors.txt", `[metadata]
+scheme-id = test
+lang-code = ml
+
+[vowels]
+a അ
+a ആ
+`)
+
+	err = CompileScheme(srcPath, vstPath)
+	assertEqual(t, strings.HasPrefix(err.Error(), "line 7: "), true)
+	assertEqual(t, fileExists(vstPath), false)
+}
+
+func TestTokenChangesInUse(t *testing.T) {
+	varnam := makeTestInstance(t, "ml", t.TempDir())
+
+	// Changes are made to a copy of the VST
+	vstCopy, err := os.ReadFile(varnam.VSTPath)
+	checkError(err)
+	vstPath := path.Join(t.TempDir(), "ml.vst")
+	checkError(os.WriteFile(vstPath, vstCopy, 0644))
+	checkError(varnam.InitVST(vstPath))
+
+	assertEqual(t, hasSuggestion(varnam.Transliterate("qz"), "₹"), false)
+
+	checkError(varnam.VMCreateToken("qz", "₹", "", "", "", VARNAM_SYMBOL_SYMBOL, VARNAM_MATCH_EXACT, 0, 0, false))
+	assertEqual(t, hasSuggestion(varnam.Transliterate("qz"), "₹"), true)
+
+	search := NewSearchSymbol()
+	search.Pattern = "qz"
+	checkError(varnam.VMDeleteToken(search))
+	assertEqual(t, hasSuggestion(varnam.Transliterate("qz"), "₹"), false)
+}
+
+func TestStemRules(t *testing.T) {
+	varnam, err := VMInit(path.Join(testTempDir, "stem.vst"))
+	checkError(err)
+	defer varnam.Close()
+
+	checkError(varnam.VMCreateStemRule("ത്തിൽ", "ം"))
+	checkError(varnam.VMCreateStemRule("ിൽ", "്"))
+	checkError(varnam.VMCreateStemRule("ലിൽ", "ൽ"))
+
+	// Duplicate
+	assertEqual(t, varnam.VMCreateStemRule("ിൽ", "") != nil, true)
+	assertEqual(t, varnam.VMCreateStemRule("", "്") != nil, true)
+
+	checkError(varnam.VMCreateStemException("ിൽ", "ടിൽ"))
+	assertEqual(t, varnam.VMCreateStemException("ുടെ", "ടുടെ") != nil, true)
+
+	stems := varnam.getStems("മരത്തിൽ")
+	assertEqual(t, len(stems), 1)
+	assertEqual(t, stems[0], "മരം")
+
+	// Longest ending is used
+	stems = varnam.getStems("വയലിൽ")
+	assertEqual(t, len(stems), 1)
+	assertEqual(t, stems[0], "വയൽ")
+
+	stems = varnam.getStems("കാറിൽ")
+	assertEqual(t, len(stems), 1)
+	assertEqual(t, stems[0], "കാറ്")
+
+	// Exception
+	assertEqual(t, len(varnam.getStems("വീട്ടിൽ")), 0)
+
+	// Word should be longer than the ending
+	assertEqual(t, len(varnam.getStems("ിൽ")), 0)
+
+	checkError(varnam.VMDeleteStemRule("ിൽ"))
+	checkError(varnam.VMDeleteStemException("ലിൽ", "ടിൽ"))
+
+	assertEqual(t, len(varnam.stemRules), 2)
+	assertEqual(t, len(varnam.getStems("കാറിൽ")), 0)
+
+	var count int
+	checkError(varnam.vstConn.QueryRow("SELECT COUNT(*) FROM stem_exceptions").Scan(&count))
+	assertEqual(t, count, 0)
+}
+
+func TestDecompileVST(t *testing.T) {
+	getSymbols := func(vstPath string) []Symbol {
+		varnam, err := VMInit(vstPath)
+		checkError(err)
+		defer varnam.Close()
+
+		// id will differ. flags are made by VST maker
+		rows, err := varnam.vstConn.Query("SELECT type, pattern, value1, value2, value3, tag, match_type, priority, accept_condition, IFNULL(weight, 0) FROM symbols ORDER BY type, match_type, pattern, value1, accept_condition")
+		checkError(err)
+		defer rows.Close()
+
+		var symbols []Symbol
+		for rows.Next() {
+			var item Symbol
+			rows.Scan(&item.Type, &item.Pattern, &item.Value1, &item.Value2, &item.Value3, &item.Tag, &item.MatchType, &item.Priority, &item.AcceptCondition, &item.Weight)
+			symbols = append(symbols, item)
+		}
+		return symbols
+	}
+
+	decompile := func(vstPath string) string {
+		var src strings.Builder
+		err := DecompileVST(vstPath, &src)
+		checkError(err)
+		return src.String()
+	}
+
+	vstPaths := []string{path.Join(testTempDir, "scheme.vst")}
+	if varnam, ok := varnamInstances["ml"]; ok {
+		vstPaths = append(vstPaths, varnam.VSTPath)
+	}
+
+	for _, vstPath := range vstPaths {
+		src := decompile(vstPath)
+
+		srcPath := makeFile("decompiled.txt", src)
+		recompiledPath := path.Join(testTempDir, "recompiled.vst")
+
+		err := CompileScheme(srcPath, recompiledPath)
+		checkError(err)
+
+		// Same source again
+		assertEqual(t, decompile(recompiledPath), src)
+
+		expected := getSymbols(vstPath)
+		symbols := getSymbols(recompiledPath)
+		assertEqual(t, len(symbols), len(expected))
+		for i := 0; i < len(symbols) && i < len(expected); i++ {
+			assertEqual(t, symbols[i], expected[i])
+		}
+	}
+
+	src := decompile(path.Join(testTempDir, "scheme.vst"))
+	assertEqual(t, strings.Contains(src, "\n[symbols match=exact]\n\"#\" \"#\"\n\"=\" \"=\"\n"), true)
+	assertEqual(t, strings.Contains(src, "\nn ൻ accept=ends-with tag=chill priority=2\n"), true)
+	assertEqual(t, strings.Contains(src, "\nra ര tag=\"my \\\"tag\\\" x\"\n"), true)
+	assertEqual(t, strings.Contains(src, "\n[vowels match=possibility]\nA ആ ാ weight=10\n"), true)
+	assertEqual(t, strings.HasSuffix(src, "\n[stem-rules]\nിൽ ്\nത്തിൽ ം\n\n[stem-exceptions]\nിൽ ടിൽ\n"), true)
+}
+
+// Symbols of the same pattern are tried in the order they were
+// made, a round trip shouldn't change that
+func TestDecompileVSTTokenizerOrder(t *testing.T) {
+	srcPath := makeFile("order.txt", `
+[metadata]
+scheme-id = order
+lang-code = ml
+
+[consonants match=possibility weight=1]
+ka ക
+q ഖ
+
+[symbols match=possibility weight=1]
+q ക്യു
+
+[consonants match=possibility weight=1]
+q ക്ക
+ka ഗ
+`)
+	vstPath := path.Join(testTempDir, "order.vst")
+	checkError(CompileScheme(srcPath, vstPath))
+
+	var src strings.Builder
+	checkError(DecompileVST(vstPath, &src))
+
+	recompiledSrcPath := makeFile("order-decompiled.txt", src.String())
+	recompiledPath := path.Join(testTempDir, "order-recompiled.vst")
+	checkError(CompileScheme(recompiledSrcPath, recompiledPath))
+
+	tokenize := func(vstPath string, input string) []string {
+		varnam, err := Init(vstPath, path.Join(t.TempDir(), "order.vst.learnings"))
+		checkError(err)
+		defer varnam.Close()
+
+		var words []string
+		for _, sug := range varnam.TransliterateAdvanced(input).TokenizerSuggestions {
+			words = append(words, sug.Word)
+		}
+		return words
+	}
+
+	for _, input := range []string{"q", "ka", "qka"} {
+		expected := tokenize(vstPath, input)
+		assertEqual(t, len(expected) > 1, true)
+		assertEqual(t, reflect.DeepEqual(tokenize(recompiledPath, input), expected), true)
+	}
+}
+
+func TestValidateVST(t *testing.T) {
+	hasIssue := func(report *VSTReport, severity int, check string) bool {
+		for _, issue := range report.Issues {
+			if issue.Severity == severity && issue.Check == check {
+				return true
+			}
+		}
+		return false
+	}
+
+	report, err := ValidateVST(path.Join(testTempDir, "scheme.vst"))
+	checkError(err)
+
+	assertEqual(t, report.HasErrors(), false)
+	assertEqual(t, report.SchemaVersion, VARNAM_SCHEMA_SYMBOLS_VERSION)
+	assertEqual(t, len(report.Issues), 1)
+	assertEqual(t, report.Issues[0].Check, VARNAM_VST_CHECK_ORPHAN_POSSIBILITY)
+
+	vstPath := path.Join(testTempDir, "broken.vst")
+	varnam, err := VMInit(vstPath)
+	checkError(err)
+
+	checkError(varnam.vmAddMetadata(VARNAM_METADATA_SCHEME_IDENTIFIER, "broken"))
+	checkError(varnam.vmAddMetadata(VARNAM_METADATA_SCHEME_LANGUAGE_CODE, "ml"))
+
+	checkError(varnam.VMCreateToken("ka", "ക", "", "", "", VARNAM_SYMBOL_CONSONANT, VARNAM_MATCH_EXACT, 0, 0, false))
+	checkError(varnam.VMCreateToken("x", "ക്ഷ", "", "", "", VARNAM_SYMBOL_CONSONANT, VARNAM_MATCH_POSSIBILITY, 0, 0, false))
+	checkError(varnam.VMCreateToken("q", "q", "", "", "", VARNAM_SYMBOL_VOWEL, VARNAM_MATCH_EXACT, 0, 0, false))
+
+	// VST maker won't allow these
+	_, err = varnam.vstConn.Exec("INSERT INTO symbols (type, pattern, value1, match_type, accept_condition) VALUES (?, ?, ?, ?, 0)", VARNAM_SYMBOL_CONSONANT, "ka", "ഖ", VARNAM_MATCH_EXACT)
+	checkError(err)
+	_, err = varnam.vstConn.Exec("INSERT INTO symbols (type, pattern, value1, match_type, accept_condition) VALUES (?, ?, ?, ?, 0)", VARNAM_SYMBOL_DEAD_CONSONANT, "k", "ക", VARNAM_MATCH_EXACT)
+	checkError(err)
+	_, err = varnam.vstConn.Exec("INSERT INTO symbols (type, pattern, value1, match_type, accept_condition) VALUES (?, ?, ?, ?, 0)", VARNAM_SYMBOL_OTHER, strings.Repeat("o", VARNAM_SYMBOL_MAX+1), "ഓ", VARNAM_MATCH_EXACT)
+	checkError(err)
+	_, err = varnam.vstConn.Exec("PRAGMA user_version=1")
+	checkError(err)
+
+	report, err = ValidateVST(vstPath)
+	checkError(err)
+
+	assertEqual(t, report.HasErrors(), true)
+	assertEqual(t, report.SymbolsCount, 6)
+	assertEqual(t, report.SchemaVersion, 1)
+	assertEqual(t, report.Issues[0].Severity, VARNAM_VST_ISSUE_ERROR)
+
+	assertEqual(t, hasIssue(report, VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_SCHEMA_VERSION), true)
+	assertEqual(t, hasIssue(report, VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_METADATA), true)
+	assertEqual(t, hasIssue(report, VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_VIRAMA), true)
+	assertEqual(t, hasIssue(report, VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_DUPLICATE), true)
+	assertEqual(t, hasIssue(report, VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_LENGTH), true)
+	assertEqual(t, hasIssue(report, VARNAM_VST_ISSUE_WARNING, VARNAM_VST_CHECK_ORPHAN_POSSIBILITY), true)
+	assertEqual(t, hasIssue(report, VARNAM_VST_ISSUE_WARNING, VARNAM_VST_CHECK_UNICODE_BLOCK), true)
+
+	// Dead consonants can be checked only with virama
+	assertEqual(t, hasIssue(report, VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_DEAD_CONSONANT), false)
+
+	checkError(varnam.VMCreateToken("~", "്", "", "", "", VARNAM_SYMBOL_VIRAMA, VARNAM_MATCH_EXACT, 0, 0, false))
+	varnam.Close()
+
+	report, err = ValidateVST(vstPath)
+	checkError(err)
+
+	assertEqual(t, hasIssue(report, VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_VIRAMA), false)
+	assertEqual(t, hasIssue(report, VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_DEAD_CONSONANT), true)
+	// VMCreateToken stamps version
+	assertEqual(t, hasIssue(report, VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_SCHEMA_VERSION), false)
+}
+
+func TestDiffVST(t *testing.T) {
+	compile := func(name string, src string) string {
+		vstPath := path.Join(testTempDir, name+".vst")
+		err := CompileScheme(makeFile(name+".txt", src), vstPath)
+		checkError(err)
+		return vstPath
+	}
+
+	vstA := compile("diff-a", `
+[metadata]
+scheme-id = test
+lang-code = ml
+scheme-compiled-date = 2021-11-01
+
+[vowels]
+a അ
+aa ആ ാ
+i ഇ ി
+
+[consonants]
+ka ക
+ga ഗ priority=1
+
+[virama]
+~ ്
+`)
+
+	vstB := compile("diff-b", `
+[metadata]
+scheme-id = test
+lang-code = ml
+scheme-compiled-date = 2022-01-01
+scheme-author = Varnam
+
+[vowels]
+a അ weight=5
+aa ആ ാ
+ee ഈ ീ
+
+[consonants]
+ka ക tag=x
+ga ഗ
+
+[virama]
+~ ്
+`)
+
+	diff, err := DiffVST(vstA, vstB)
+	checkError(err)
+
+	assertEqual(t, len(diff.Metadata), 2)
+	assertEqual(t, diff.Metadata[0].Key, VARNAM_METADATA_SCHEME_AUTHOR)
+	assertEqual(t, diff.Metadata[0].Old, "")
+	assertEqual(t, diff.Metadata[0].New, "Varnam")
+	assertEqual(t, diff.Metadata[1].Key, VARNAM_METADATA_SCHEME_COMPILED_DATE)
+
+	assertEqual(t, len(diff.Added), 1)
+	assertEqual(t, diff.Added[0].Pattern, "ee")
+
+	assertEqual(t, len(diff.Removed), 1)
+	assertEqual(t, diff.Removed[0].Pattern, "i")
+
+	assertEqual(t, len(diff.Modified), 3)
+	assertEqual(t, diff.Modified[0].New.Pattern, "a")
+	assertEqual(t, strings.Join(diff.Modified[0].Fields, ","), "weight")
+	assertEqual(t, diff.Modified[1].New.Pattern, "ka")
+	assertEqual(t, strings.Join(diff.Modified[1].Fields, ","), "tag")
+	assertEqual(t, diff.Modified[2].New.Pattern, "ga")
+	assertEqual(t, strings.Join(diff.Modified[2].Fields, ","), "priority")
+
+	text := diff.Text()
+	assertEqual(t, strings.Contains(text, "\n+ [vowels match=exact] ee ഈ ീ\n"), true)
+	assertEqual(t, strings.Contains(text, "\n- [vowels match=exact] i ഇ ി\n"), true)
+	assertEqual(t, strings.Contains(text, "\n~ [vowels match=exact] a അ : weight 0 => 5\n"), true)
+	assertEqual(t, strings.HasSuffix(text, "1 added, 1 removed, 3 modified, 2 metadata changed\n"), true)
+
+	jsonData, err := json.Marshal(diff)
+	checkError(err)
+	assertEqual(t, strings.Contains(string(jsonData), `"added":[{"id":`), true)
+	assertEqual(t, strings.Contains(string(jsonData), `"fields":["weight"]`), true)
+
+	diff, err = DiffVST(vstA, vstA)
+	checkError(err)
+	assertEqual(t, diff.IsEmpty(), true)
+
+	_, err = DiffVST(vstA, path.Join(testTempDir, "nonexistent.vst"))
+	assertEqual(t, err != nil, true)
 }
@@ -0,0 +1,270 @@
+package govarnam
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// VSTIssue a problem found in VST
+type VSTIssue struct {
+	// VARNAM_VST_ISSUE_ERROR or VARNAM_VST_ISSUE_WARNING
+	Severity int
+
+	// Which check found the issue. One of VARNAM_VST_CHECK_XXX
+	Check string
+
+	// Identifier of the symbol. 0 if issue is not of a symbol
+	SymbolID int
+
+	Message string
+}
+
+// VSTReport result of ValidateVST()
+type VSTReport struct {
+	SchemaVersion int
+	SymbolsCount  int
+	Issues        []VSTIssue
+}
+
+// HasErrors whether any VARNAM_VST_ISSUE_ERROR was found
+func (report *VSTReport) HasErrors() bool {
+	for _, issue := range report.Issues {
+		if issue.Severity == VARNAM_VST_ISSUE_ERROR {
+			return true
+		}
+	}
+	return false
+}
+
+func (report *VSTReport) addIssue(severity int, check string, symbolID int, msg string, args ...interface{}) {
+	report.Issues = append(report.Issues, VSTIssue{
+		Severity: severity,
+		Check:    check,
+		SymbolID: symbolID,
+		Message:  fmt.Sprintf(msg, args...),
+	})
+}
+
+// Metadata keys that VMSetSchemeDetails() sets
+var vstRequiredMetadata = []string{
+	VARNAM_METADATA_SCHEME_IDENTIFIER,
+	VARNAM_METADATA_SCHEME_LANGUAGE_CODE,
+	VARNAM_METADATA_SCHEME_DISPLAY_NAME,
+	VARNAM_METADATA_SCHEME_AUTHOR,
+	VARNAM_METADATA_SCHEME_COMPILED_DATE,
+	VARNAM_METADATA_SCHEME_STABLE,
+}
+
+func (varnam *Varnam) vmValidateMetadata(report *VSTReport) error {
+	metadata, err := varnam.vmGetMetadata()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range vstRequiredMetadata {
+		if _, found := metadata[key]; !found {
+			report.addIssue(VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_METADATA, 0, "metadata '%s' not found", key)
+		}
+	}
+
+	if value, found := metadata[VARNAM_METADATA_SCHEME_IDENTIFIER]; found && value == "" {
+		report.addIssue(VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_METADATA, 0, "metadata '%s' is empty", VARNAM_METADATA_SCHEME_IDENTIFIER)
+	}
+	if value, found := metadata[VARNAM_METADATA_SCHEME_LANGUAGE_CODE]; found && len(value) != 2 {
+		report.addIssue(VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_METADATA, 0, "metadata '%s' should be an ISO 639-1 two letter code, found '%s'", VARNAM_METADATA_SCHEME_LANGUAGE_CODE, value)
+	}
+
+	return nil
+}
+
+func (varnam *Varnam) vmValidateSchemaVersion(report *VSTReport) error {
+	err := varnam.vstConn.QueryRow("PRAGMA user_version").Scan(&report.SchemaVersion)
+	if err != nil {
+		return err
+	}
+
+	if report.SchemaVersion != VARNAM_SCHEMA_SYMBOLS_VERSION {
+		report.addIssue(VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_SCHEMA_VERSION, 0, "schema version is %d, expected %d", report.SchemaVersion, VARNAM_SCHEMA_SYMBOLS_VERSION)
+	}
+
+	return nil
+}
+
+// Find virama. Returns "" if not found
+func validateVirama(report *VSTReport, symbols []Symbol) string {
+	for _, symbol := range symbols {
+		if symbol.Pattern != "~" {
+			continue
+		}
+
+		if symbol.Type != VARNAM_SYMBOL_VIRAMA {
+			report.addIssue(VARNAM_VST_ISSUE_WARNING, VARNAM_VST_CHECK_VIRAMA, symbol.Identifier, "symbol with pattern '~' should be of type virama")
+		}
+		return symbol.Value1
+	}
+
+	report.addIssue(VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_VIRAMA, 0, "virama (pattern '~') not found")
+	return ""
+}
+
+func validateDeadConsonants(report *VSTReport, symbols []Symbol, virama string) {
+	if virama == "" {
+		return
+	}
+
+	for _, symbol := range symbols {
+		endsWithVirama := strings.HasSuffix(symbol.Value1, virama)
+
+		if symbol.Type == VARNAM_SYMBOL_DEAD_CONSONANT && !endsWithVirama {
+			report.addIssue(VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_DEAD_CONSONANT, symbol.Identifier, "dead consonant '%s => %s' doesn't end with virama", symbol.Pattern, symbol.Value1)
+		} else if symbol.Type == VARNAM_SYMBOL_CONSONANT && endsWithVirama {
+			report.addIssue(VARNAM_VST_ISSUE_WARNING, VARNAM_VST_CHECK_DEAD_CONSONANT, symbol.Identifier, "consonant '%s => %s' ends with virama, it should be a dead consonant", symbol.Pattern, symbol.Value1)
+		}
+	}
+}
+
+func validateMatches(report *VSTReport, symbols []Symbol) {
+	type exactKey struct {
+		pattern         string
+		acceptCondition int
+	}
+
+	exactMatches := map[exactKey]int{}
+	exactPatterns := map[string]bool{}
+
+	for _, symbol := range symbols {
+		if symbol.MatchType != VARNAM_MATCH_EXACT {
+			continue
+		}
+
+		key := exactKey{symbol.Pattern, symbol.AcceptCondition}
+		if firstID, found := exactMatches[key]; found {
+			report.addIssue(VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_DUPLICATE, symbol.Identifier, "pattern '%s' already has an exact match (symbol %d)", symbol.Pattern, firstID)
+		} else {
+			exactMatches[key] = symbol.Identifier
+		}
+		exactPatterns[symbol.Pattern] = true
+	}
+
+	for _, symbol := range symbols {
+		if symbol.MatchType == VARNAM_MATCH_POSSIBILITY && !exactPatterns[symbol.Pattern] {
+			report.addIssue(VARNAM_VST_ISSUE_WARNING, VARNAM_VST_CHECK_ORPHAN_POSSIBILITY, symbol.Identifier, "possibility match '%s => %s' has no exact match for the pattern", symbol.Pattern, symbol.Value1)
+		}
+	}
+}
+
+func validateLengths(report *VSTReport, symbols []Symbol) {
+	for _, symbol := range symbols {
+		for _, column := range []struct {
+			name  string
+			value string
+		}{
+			{"pattern", symbol.Pattern},
+			{"value1", symbol.Value1},
+			{"value2", symbol.Value2},
+			{"value3", symbol.Value3},
+			{"tag", symbol.Tag},
+		} {
+			if len(column.value) > VARNAM_SYMBOL_MAX {
+				report.addIssue(VARNAM_VST_ISSUE_ERROR, VARNAM_VST_CHECK_LENGTH, symbol.Identifier, "length of %s '%s' is more than VARNAM_SYMBOL_MAX (%d)", column.name, column.value, VARNAM_SYMBOL_MAX)
+			}
+		}
+	}
+}
+
+// Symbol types whose values should be in the language's unicode block
+var vstLanguageSymbolTypes = map[int]bool{
+	VARNAM_SYMBOL_VOWEL:           true,
+	VARNAM_SYMBOL_CONSONANT:       true,
+	VARNAM_SYMBOL_DEAD_CONSONANT:  true,
+	VARNAM_SYMBOL_CONSONANT_VOWEL: true,
+	VARNAM_SYMBOL_NUMBER:          true,
+	VARNAM_SYMBOL_ANUSVARA:        true,
+	VARNAM_SYMBOL_VISARGA:         true,
+	VARNAM_SYMBOL_VIRAMA:          true,
+}
+
+func validateUnicodeBlock(report *VSTReport, symbols []Symbol, langCode string, block *unicode.RangeTable) {
+	if len(block.R16) == 0 && len(block.R32) == 0 {
+		report.addIssue(VARNAM_VST_ISSUE_WARNING, VARNAM_VST_CHECK_UNICODE_BLOCK, 0, "unicode block of language '%s' is not known, skipped checking characters", langCode)
+		return
+	}
+
+	for _, symbol := range symbols {
+		if !vstLanguageSymbolTypes[symbol.Type] {
+			continue
+		}
+
+		for _, value := range []string{symbol.Value1, symbol.Value2} {
+			for _, r := range value {
+				// Chillu can be made with ZWJ
+				if string(r) == ZWJ || string(r) == ZWNJ {
+					continue
+				}
+				if !unicode.In(r, block) {
+					report.addIssue(VARNAM_VST_ISSUE_WARNING, VARNAM_VST_CHECK_UNICODE_BLOCK, symbol.Identifier, "'%s => %s' has character '%c' (%U) outside language's unicode block", symbol.Pattern, value, r, r)
+					break
+				}
+			}
+		}
+	}
+}
+
+// Validate the VST opened with vstConn
+func (varnam *Varnam) vmValidate() (*VSTReport, error) {
+	report := &VSTReport{}
+
+	err := varnam.vmValidateSchemaVersion(report)
+	if err != nil {
+		return nil, err
+	}
+
+	err = varnam.vmValidateMetadata(report)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols, err := varnam.vmGetAllSymbols()
+	if err != nil {
+		return nil, err
+	}
+
+	report.SymbolsCount = len(symbols)
+
+	virama := validateVirama(report, symbols)
+	validateDeadConsonants(report, symbols, virama)
+	validateMatches(report, symbols)
+	validateLengths(report, symbols)
+
+	varnam.setSchemeInfo()
+	block := varnam.getUnicodeBlock()
+	validateUnicodeBlock(report, symbols, varnam.SchemeDetails.LangCode, &block)
+
+	// Errors first
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return report.Issues[i].Severity < report.Issues[j].Severity
+	})
+
+	return report, nil
+}
+
+// ValidateVST check a VST for problems. VSTs with
+// problems can be loaded, but gives wrong suggestions.
+// Returns error only if VST couldn't be read.
+func ValidateVST(vstPath string) (*VSTReport, error) {
+	varnam, err := vmOpenReadOnly(vstPath)
+	if err != nil {
+		return nil, err
+	}
+	defer varnam.Close()
+
+	return varnam.vmValidate()
+}
@@ -5,7 +5,106 @@ package govarnamgo
 // #include "stdlib.h"
 import "C"
 
+import "unsafe"
+
 func (handle *VarnamHandle) ReIndexDictionary() error {
 	err := C.varnam_reindex_dictionary(handle.connectionID)
 	return handle.checkError(err)
 }
+
+// CompactLearnings store decayed weights of words. Needs
+// Config.LearningHalfLife. Returns number of words changed
+func (handle *VarnamHandle) CompactLearnings() (int, error) {
+	var changed C.int
+	err := C.varnam_compact_learnings(handle.connectionID, unsafe.Pointer(&changed))
+	return int(changed), handle.checkError(err)
+}
+
+// ListProfiles get names of learnings profiles of the language
+func (handle *VarnamHandle) ListProfiles() ([]string, error) {
+	var (
+		result        []string
+		resultPointer *C.varray
+	)
+
+	err := C.varnam_list_profiles(handle.connectionID, &resultPointer)
+	if err != C.VARNAM_SUCCESS {
+		return result, handle.checkError(err)
+	}
+
+	i := 0
+	for i < int(C.varray_length(resultPointer)) {
+		cProfile := (*C.char)(C.varray_get(resultPointer, C.int(i)))
+		result = append(result, C.GoString(cProfile))
+		i++
+	}
+
+	return result, nil
+}
+
+// CreateProfile make an empty learnings profile
+func (handle *VarnamHandle) CreateProfile(profile string) error {
+	cProfile := C.CString(profile)
+	defer C.free(unsafe.Pointer(cProfile))
+
+	err := C.varnam_create_profile(handle.connectionID, cProfile)
+	return handle.checkError(err)
+}
+
+// DeleteProfile delete a learnings profile and its words
+func (handle *VarnamHandle) DeleteProfile(profile string) error {
+	cProfile := C.CString(profile)
+	defer C.free(unsafe.Pointer(cProfile))
+
+	err := C.varnam_delete_profile(handle.connectionID, cProfile)
+	return handle.checkError(err)
+}
+
+// SetBaseProfile search the words of a profile too, read
+// only. Empty profile removes the base profile
+func (handle *VarnamHandle) SetBaseProfile(profile string) error {
+	cProfile := C.CString(profile)
+	defer C.free(unsafe.Pointer(cProfile))
+
+	err := C.varnam_set_base_profile(handle.connectionID, cProfile)
+	return handle.checkError(err)
+}
+
+// AttachDictionary search the words of a read only
+// dictionary too, after the dictionary in use
+func (handle *VarnamHandle) AttachDictionary(dictPath string) error {
+	cDictPath := C.CString(dictPath)
+	defer C.free(unsafe.Pointer(cDictPath))
+
+	err := C.varnam_attach_dictionary(handle.connectionID, cDictPath)
+	return handle.checkError(err)
+}
+
+// DetachDictionary stop searching an attached dictionary
+func (handle *VarnamHandle) DetachDictionary(dictPath string) error {
+	cDictPath := C.CString(dictPath)
+	defer C.free(unsafe.Pointer(cDictPath))
+
+	err := C.varnam_detach_dictionary(handle.connectionID, cDictPath)
+	return handle.checkError(err)
+}
+
+// GetAttachedDictionaries get paths of attached
+// dictionaries in the order they're searched
+func (handle *VarnamHandle) GetAttachedDictionaries() []string {
+	var (
+		result        []string
+		resultPointer *C.varray
+	)
+
+	C.varnam_get_attached_dictionaries(handle.connectionID, &resultPointer)
+
+	i := 0
+	for i < int(C.varray_length(resultPointer)) {
+		cDictPath := (*C.char)(C.varray_get(resultPointer, C.int(i)))
+		result = append(result, C.GoString(cDictPath))
+		i++
+	}
+
+	return result
+}
@@ -1,6 +1,9 @@
 package govarnamgo
 
-import "testing"
+import (
+	"path"
+	"testing"
+)
 
 func TestReIndex(t *testing.T) {
 	varnam := getVarnamInstance("ml")
@@ -8,3 +11,53 @@ func TestReIndex(t *testing.T) {
 	err := varnam.ReIndexDictionary()
 	assertEqual(t, err, nil)
 }
+
+func TestProfiles(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+
+	checkError(varnam.CreateProfile("govarnamgo-test"))
+
+	profiles, err := varnam.ListProfiles()
+	checkError(err)
+
+	found := false
+	for _, profile := range profiles {
+		if profile == "govarnamgo-test" {
+			found = true
+		}
+	}
+	assertEqual(t, found, true)
+
+	assertEqual(t, varnam.SetBaseProfile("nothing") != nil, true)
+	checkError(varnam.SetBaseProfile("govarnamgo-test"))
+
+	// Base profile can't be deleted
+	assertEqual(t, varnam.DeleteProfile("govarnamgo-test") != nil, true)
+
+	checkError(varnam.SetBaseProfile(""))
+	checkError(varnam.DeleteProfile("govarnamgo-test"))
+}
+
+func TestAttachDictionary(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+
+	assertEqual(t, varnam.AttachDictionary("/nonexistent/ml.vst.learnings") != nil, true)
+	assertEqual(t, varnam.DetachDictionary("/nonexistent/ml.vst.learnings") != nil, true)
+	assertEqual(t, len(varnam.GetAttachedDictionaries()), 0)
+}
+
+func TestMergeLearnings(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+
+	_, err := varnam.MergeLearnings("/nonexistent/export-1.vlf", VARNAM_MERGE_WEIGHT_MAX, true)
+	assertEqual(t, err != nil, true)
+
+	exportPath := path.Join(testTempDir, "merge-export")
+	checkError(varnam.Export(exportPath, 1000))
+
+	// Nothing to change with its own learnings
+	report, err := varnam.MergeLearnings(exportPath+"-1.vlf", VARNAM_MERGE_WEIGHT_MAX, true)
+	checkError(err)
+	assertEqual(t, len(report.AddedWords)+len(report.UpdatedWords)+len(report.RemovedWords), 0)
+	assertEqual(t, report.AddedPatterns+report.RemovedPatterns, 0)
+}
@@ -17,6 +17,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"unsafe"
 )
 
@@ -28,6 +30,45 @@ type Config struct {
 	PatternDictionarySuggestionsLimit int
 	TokenizerSuggestionsLimit         int
 	TokenizerSuggestionsAlways        bool
+
+	// Maximum transliteration results to cache, 0 disables it
+	CacheSize int
+
+	// Fill Suggestion.Score with how its weight was made
+	ExplainSuggestions bool
+
+	// Rank with the weighted ranker instead of the default one
+	Ranker *WeightedRankerConfig
+
+	// Learnt weights halve every this many seconds since the
+	// word was last learnt. 0 disables it
+	LearningHalfLife int
+
+	// Typos tolerated in input when no dictionary word is found,
+	// at most 2. 0 disables it
+	FuzzyMatchMaxEdits int
+
+	// Maximum inputs with typos fixed searched for a
+	// transliteration. 0 keeps the default (200)
+	FuzzyMatchCostLimit int
+
+	// Suggest learnt words that sound like the input
+	PhoneticMatch bool
+
+	// How weights of a word in more than one dictionary layer
+	// are combined, one of VARNAM_LAYER_WEIGHT_*
+	LayerWeightPolicy int
+}
+
+// WeightedRankerConfig values of weighted ranker, see
+// govarnam.WeightedRanker. 0 keeps the default value,
+// use -1 to disable recency bonus or short words.
+type WeightedRankerConfig struct {
+	// VARNAM_SOURCE_* => percentage of weight counted
+	SourceWeights   map[int]int
+	RecencyBonus    int
+	RecencyHalfLife int
+	ShortWordLength int
 }
 
 // VarnamHandle for making things easier
@@ -37,29 +78,79 @@ type VarnamHandle struct {
 
 // Suggestion suggestion
 type Suggestion struct {
-	Word      string
-	Weight    int
-	LearnedOn int
+	Word      string `json:"word"`
+	Weight    int    `json:"weight"`
+	LearnedOn int    `json:"learned_on"`
+
+	// Result list it came from, one of VARNAM_SOURCE_*
+	Source int `json:"source"`
+
+	// How Weight was made. Only set if
+	// Config.ExplainSuggestions is true
+	Score *ScoreBreakdown `json:"score,omitempty"`
+}
+
+// ScoreBreakdown parts of the weight of a suggestion
+type ScoreBreakdown struct {
+	SymbolWeight       int `json:"symbol_weight"`
+	LearnedWeight      int `json:"learned_weight"`
+	Recency            int `json:"recency"`
+	PatternLengthBonus int `json:"pattern_length_bonus"`
+	EditPenalty        int `json:"edit_penalty"`
 }
 
+// Source of Suggestion
+const (
+	VARNAM_SOURCE_EXACT_WORD         = int(C.VARNAM_SOURCE_EXACT_WORD)
+	VARNAM_SOURCE_EXACT_MATCH        = int(C.VARNAM_SOURCE_EXACT_MATCH)
+	VARNAM_SOURCE_DICTIONARY         = int(C.VARNAM_SOURCE_DICTIONARY)
+	VARNAM_SOURCE_PATTERN_DICTIONARY = int(C.VARNAM_SOURCE_PATTERN_DICTIONARY)
+	VARNAM_SOURCE_TOKENIZER          = int(C.VARNAM_SOURCE_TOKENIZER)
+	VARNAM_SOURCE_GREEDY             = int(C.VARNAM_SOURCE_GREEDY)
+)
+
+// How weights of a word in more than one dictionary
+// layer are combined, see Config.LayerWeightPolicy
+const (
+	VARNAM_LAYER_WEIGHT_OVERRIDE = int(C.VARNAM_LAYER_WEIGHT_OVERRIDE)
+	VARNAM_LAYER_WEIGHT_MAX      = int(C.VARNAM_LAYER_WEIGHT_MAX)
+	VARNAM_LAYER_WEIGHT_SUM      = int(C.VARNAM_LAYER_WEIGHT_SUM)
+)
+
+// How weights of a word in both are combined by MergeLearnings
+const (
+	VARNAM_MERGE_WEIGHT_MAX = int(C.VARNAM_MERGE_WEIGHT_MAX)
+	VARNAM_MERGE_WEIGHT_SUM = int(C.VARNAM_MERGE_WEIGHT_SUM)
+)
+
 // TransliterationResult result
 type TransliterationResult struct {
-	ExactWords                   []Suggestion
-	ExactMatches                 []Suggestion
-	DictionarySuggestions        []Suggestion
-	PatternDictionarySuggestions []Suggestion
-	TokenizerSuggestions         []Suggestion
-	GreedyTokenized              []Suggestion
+	ExactWords                   []Suggestion `json:"exact_words"`
+	ExactMatches                 []Suggestion `json:"exact_matches"`
+	DictionarySuggestions        []Suggestion `json:"dictionary_suggestions"`
+	PatternDictionarySuggestions []Suggestion `json:"pattern_dictionary_suggestions"`
+	TokenizerSuggestions         []Suggestion `json:"tokenizer_suggestions"`
+	GreedyTokenized              []Suggestion `json:"greedy_tokenized"`
+}
+
+// TextSegment a word or non-word part of a text.
+// Start & End are character (rune) positions in text
+type TextSegment struct {
+	Start       int
+	End         int
+	Text        string
+	IsWord      bool
+	Suggestions []Suggestion
 }
 
 // SchemeDetails of VST
 type SchemeDetails struct {
-	Identifier   string
-	LangCode     string
-	DisplayName  string
-	Author       string
-	CompiledDate string
-	IsStable     bool
+	Identifier   string `json:"identifier"`
+	LangCode     string `json:"lang_code"`
+	DisplayName  string `json:"display_name"`
+	Author       string `json:"author"`
+	CompiledDate string `json:"compiled_date"`
+	IsStable     bool   `json:"is_stable"`
 }
 
 // LearnStatus output of bulk learn
@@ -70,23 +161,58 @@ type LearnStatus struct {
 
 // Symbol result from VST
 type Symbol struct {
-	Identifier      int
-	Type            int
-	MatchType       int
-	Pattern         string
-	Value1          string
-	Value2          string
-	Value3          string
-	Tag             string
-	Weight          int
-	Priority        int
-	AcceptCondition int
-	Flags           int
+	Identifier      int    `json:"id"`
+	Type            int    `json:"type"`
+	MatchType       int    `json:"match_type"`
+	Pattern         string `json:"pattern"`
+	Value1          string `json:"value1"`
+	Value2          string `json:"value2"`
+	Value3          string `json:"value3"`
+	Tag             string `json:"tag"`
+	Weight          int    `json:"weight"`
+	Priority        int    `json:"priority"`
+	AcceptCondition int    `json:"accept_condition"`
+	Flags           int    `json:"flags"`
+}
+
+// Severity of VSTIssue
+const (
+	VARNAM_VST_ISSUE_ERROR   = int(C.VARNAM_VST_ISSUE_ERROR)
+	VARNAM_VST_ISSUE_WARNING = int(C.VARNAM_VST_ISSUE_WARNING)
+)
+
+// VSTIssue a problem found in VST
+type VSTIssue struct {
+	Severity int
+	Check    string
+	SymbolID int
+	Message  string
+}
+
+// VSTReport result of ValidateVST
+type VSTReport struct {
+	SchemaVersion int
+	SymbolsCount  int
+	Issues        []VSTIssue
+}
+
+// MergeReport changes made by MergeLearnings,
+// or to be made in a dry run
+type MergeReport struct {
+	AddedWords      []string
+	UpdatedWords    []string
+	RemovedWords    []string
+	AddedPatterns   int
+	RemovedPatterns int
 }
 
 var contextOperationCount = C.int(0)
+var contextOperationCountMutex = sync.Mutex{}
 
 func makeContextOperation() C.int {
+	contextOperationCountMutex.Lock()
+	defer contextOperationCountMutex.Unlock()
+
 	operationID := contextOperationCount
 	contextOperationCount++
 
@@ -99,6 +225,17 @@ func makeSuggestion(cSug *C.struct_Suggestion_t) Suggestion {
 	sug.Word = C.GoString(cSug.Word)
 	sug.Weight = int(cSug.Weight)
 	sug.LearnedOn = int(cSug.LearnedOn)
+	sug.Source = int(cSug.Source)
+
+	if cSug.Score != nil {
+		sug.Score = &ScoreBreakdown{
+			SymbolWeight:       int(cSug.Score.SymbolWeight),
+			LearnedWeight:      int(cSug.Score.LearnedWeight),
+			Recency:            int(cSug.Score.Recency),
+			PatternLengthBonus: int(cSug.Score.PatternLengthBonus),
+			EditPenalty:        int(cSug.Score.EditPenalty),
+		}
+	}
 
 	return sug
 }
@@ -178,7 +315,7 @@ func makeGoTransliterationResult(ctx context.Context, cResult *C.struct_Translit
 	}
 }
 
-//VarnamError Custom error for varnam
+// VarnamError Custom error for varnam
 type VarnamError struct {
 	ErrorCode int
 	Message   string
@@ -239,6 +376,22 @@ func InitFromID(id string) (*VarnamHandle, error) {
 	return &VarnamHandle{handleID}, nil
 }
 
+// InitFromIDWithProfile Initialize with learnings stored in
+// the profile. Profile is made if it doesn't exist
+func InitFromIDWithProfile(id string, profile string) (*VarnamHandle, error) {
+	handleID := C.int(0)
+	cID := C.CString(id)
+	cProfile := C.CString(profile)
+	err := C.varnam_init_from_id_with_profile(cID, cProfile, unsafe.Pointer(&handleID))
+	C.free(unsafe.Pointer(cID))
+	C.free(unsafe.Pointer(cProfile))
+
+	if err != C.VARNAM_SUCCESS {
+		return nil, fmt.Errorf(C.GoString(C.varnam_get_last_error(handleID)))
+	}
+	return &VarnamHandle{handleID}, nil
+}
+
 // GetLastError get last error
 func (handle *VarnamHandle) GetLastError() string {
 	cStr := C.varnam_get_last_error(handle.connectionID)
@@ -281,6 +434,68 @@ func (handle *VarnamHandle) SetConfig(config Config) {
 	} else {
 		C.varnam_set_dictionary_match_exact(handle.connectionID, C.int(0))
 	}
+
+	C.varnam_config(handle.connectionID, C.VARNAM_CONFIG_SET_CACHE_SIZE, C.int(config.CacheSize))
+
+	if config.ExplainSuggestions {
+		C.varnam_config(handle.connectionID, C.VARNAM_CONFIG_EXPLAIN_SUGGESTIONS, C.int(1))
+	} else {
+		C.varnam_config(handle.connectionID, C.VARNAM_CONFIG_EXPLAIN_SUGGESTIONS, C.int(0))
+	}
+
+	C.varnam_config(handle.connectionID, C.VARNAM_CONFIG_SET_LEARNING_HALF_LIFE, C.int(config.LearningHalfLife))
+
+	C.varnam_config(handle.connectionID, C.VARNAM_CONFIG_SET_FUZZY_MATCH_MAX_EDITS, C.int(config.FuzzyMatchMaxEdits))
+	if config.FuzzyMatchCostLimit == 0 {
+		C.varnam_config(handle.connectionID, C.VARNAM_CONFIG_SET_FUZZY_MATCH_COST_LIMIT, C.VARNAM_FUZZY_MATCH_COST_LIMIT)
+	} else {
+		C.varnam_config(handle.connectionID, C.VARNAM_CONFIG_SET_FUZZY_MATCH_COST_LIMIT, C.int(config.FuzzyMatchCostLimit))
+	}
+
+	if config.PhoneticMatch {
+		C.varnam_config(handle.connectionID, C.VARNAM_CONFIG_PHONETIC_MATCH, C.int(1))
+	} else {
+		C.varnam_config(handle.connectionID, C.VARNAM_CONFIG_PHONETIC_MATCH, C.int(0))
+	}
+
+	C.varnam_config(handle.connectionID, C.VARNAM_CONFIG_SET_LAYER_WEIGHT_POLICY, C.int(config.LayerWeightPolicy))
+
+	handle.setRankerConfig(config.Ranker)
+}
+
+var rankerSourceWeightKeys = map[int]C.int{
+	VARNAM_SOURCE_EXACT_WORD:         C.VARNAM_CONFIG_RANKER_EXACT_WORD_WEIGHT,
+	VARNAM_SOURCE_EXACT_MATCH:        C.VARNAM_CONFIG_RANKER_EXACT_MATCH_WEIGHT,
+	VARNAM_SOURCE_DICTIONARY:         C.VARNAM_CONFIG_RANKER_DICTIONARY_WEIGHT,
+	VARNAM_SOURCE_PATTERN_DICTIONARY: C.VARNAM_CONFIG_RANKER_PATTERN_DICTIONARY_WEIGHT,
+	VARNAM_SOURCE_TOKENIZER:          C.VARNAM_CONFIG_RANKER_TOKENIZER_WEIGHT,
+	VARNAM_SOURCE_GREEDY:             C.VARNAM_CONFIG_RANKER_GREEDY_WEIGHT,
+}
+
+func (handle *VarnamHandle) setRankerConfig(config *WeightedRankerConfig) {
+	if config == nil {
+		C.varnam_config(handle.connectionID, C.VARNAM_CONFIG_SET_RANKER, C.VARNAM_RANKER_DEFAULT)
+		return
+	}
+
+	// Resets to default values
+	C.varnam_config(handle.connectionID, C.VARNAM_CONFIG_SET_RANKER, C.VARNAM_RANKER_WEIGHTED)
+
+	for source, weight := range config.SourceWeights {
+		if key, found := rankerSourceWeightKeys[source]; found {
+			C.varnam_config(handle.connectionID, key, C.int(weight))
+		}
+	}
+
+	if config.RecencyBonus != 0 {
+		C.varnam_config(handle.connectionID, C.VARNAM_CONFIG_RANKER_RECENCY_BONUS, C.int(config.RecencyBonus))
+	}
+	if config.RecencyHalfLife != 0 {
+		C.varnam_config(handle.connectionID, C.VARNAM_CONFIG_RANKER_RECENCY_HALF_LIFE, C.int(config.RecencyHalfLife))
+	}
+	if config.ShortWordLength != 0 {
+		C.varnam_config(handle.connectionID, C.VARNAM_CONFIG_RANKER_SHORT_WORD_LENGTH, C.int(config.ShortWordLength))
+	}
 }
 
 type cgoVarnamTransliterateResult struct {
@@ -392,6 +607,79 @@ func (handle *VarnamHandle) TransliterateAdvanced(ctx context.Context, word stri
 	}
 }
 
+type cgoVarnamTransliterateTextResult struct {
+	result *C.varray
+	err    error
+}
+
+func (handle *VarnamHandle) cgoVarnamTransliterateText(operationID C.int, resultChannel chan<- cgoVarnamTransliterateTextResult, text string) {
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+
+	var resultPointer *C.varray
+
+	code := C.varnam_transliterate_text(handle.connectionID, operationID, cText, &resultPointer)
+	if code == C.VARNAM_SUCCESS {
+		resultChannel <- cgoVarnamTransliterateTextResult{
+			resultPointer,
+			nil,
+		}
+	} else {
+		resultChannel <- cgoVarnamTransliterateTextResult{
+			resultPointer,
+			fmt.Errorf(handle.GetLastError()),
+		}
+	}
+
+	close(resultChannel)
+}
+
+// TransliterateText transliterate a text with multiple words
+func (handle *VarnamHandle) TransliterateText(ctx context.Context, text string) ([]TextSegment, error) {
+	var result []TextSegment
+
+	operationID := makeContextOperation()
+	channel := make(chan cgoVarnamTransliterateTextResult)
+
+	go handle.cgoVarnamTransliterateText(operationID, channel, text)
+
+	select {
+	case <-ctx.Done():
+		C.varnam_cancel(operationID)
+		return result, nil
+	case channelResult := <-channel:
+		if channelResult.err != nil {
+			return result, channelResult.err
+		}
+
+		i := 0
+		for i < int(C.varray_length(channelResult.result)) {
+			cSegment := (*C.TextSegment)(C.varray_get(channelResult.result, C.int(i)))
+
+			segment := TextSegment{
+				Start:  int(cSegment.Start),
+				End:    int(cSegment.End),
+				Text:   C.GoString(cSegment.Text),
+				IsWord: cSegment.IsWord != 0,
+			}
+
+			j := 0
+			for j < int(C.varray_length(cSegment.Suggestions)) {
+				cSug := (*C.Suggestion)(C.varray_get(cSegment.Suggestions, C.int(j)))
+				segment.Suggestions = append(segment.Suggestions, makeSuggestion(cSug))
+				j++
+			}
+
+			result = append(result, segment)
+			i++
+		}
+
+		go C.destroyTextSegmentsArray(channelResult.result)
+
+		return result, nil
+	}
+}
+
 // TransliterateGreedyTokenized transliterate but only tokenizer output
 func (handle *VarnamHandle) TransliterateGreedyTokenized(word string) []Suggestion {
 	var result []Suggestion
@@ -547,6 +835,49 @@ func (handle *VarnamHandle) Import(filePath string) error {
 	return handle.checkError(err)
 }
 
+// Convert a C varray of strings to Go
+func makeStrings(cArray *C.varray) []string {
+	var result []string
+
+	i := 0
+	for i < int(C.varray_length(cArray)) {
+		result = append(result, C.GoString((*C.char)(C.varray_get(cArray, C.int(i)))))
+		i++
+	}
+
+	return result
+}
+
+// MergeLearnings merge learnings of another dictionary or a .vlf
+// export into the dictionary in use. strategy is one of
+// VARNAM_MERGE_WEIGHT_*. With dryRun, nothing is changed
+func (handle *VarnamHandle) MergeLearnings(filePath string, strategy int, dryRun bool) (MergeReport, error) {
+	var report MergeReport
+
+	cFilePath := C.CString(filePath)
+	defer C.free(unsafe.Pointer(cFilePath))
+
+	cDryRun := C.int(0)
+	if dryRun {
+		cDryRun = C.int(1)
+	}
+
+	var cReport *C.MergeReport
+	code := C.varnam_merge_learnings(handle.connectionID, cFilePath, C.int(strategy), cDryRun, &cReport)
+	if code != C.VARNAM_SUCCESS {
+		return report, handle.checkError(code)
+	}
+	defer C.destroyMergeReport(cReport)
+
+	report.AddedWords = makeStrings(cReport.AddedWords)
+	report.UpdatedWords = makeStrings(cReport.UpdatedWords)
+	report.RemovedWords = makeStrings(cReport.RemovedWords)
+	report.AddedPatterns = int(cReport.AddedPatterns)
+	report.RemovedPatterns = int(cReport.RemovedPatterns)
+
+	return report, nil
+}
+
 // GetRecentlyLearntWords get recently learn words
 func (handle *VarnamHandle) GetRecentlyLearntWords(ctx context.Context, offset int, limit int) ([]Suggestion, error) {
 	var result []Suggestion
@@ -616,6 +947,137 @@ func (handle *VarnamHandle) GetSuggestions(ctx context.Context, word string) ([]
 	}
 }
 
+// PredictNextWords predict words that may come after previous words
+func (handle *VarnamHandle) PredictNextWords(ctx context.Context, previousWords []string, limit int) ([]Suggestion, error) {
+	var result []Suggestion
+
+	operationID := makeContextOperation()
+
+	select {
+	case <-ctx.Done():
+		C.varnam_cancel(operationID)
+		return result, nil
+	default:
+		var resultPointer *C.varray
+
+		cPreviousWords := C.CString(strings.Join(previousWords, " "))
+		defer C.free(unsafe.Pointer(cPreviousWords))
+
+		code := C.varnam_predict_next_words(handle.connectionID, operationID, cPreviousWords, C.int(limit), &resultPointer)
+		if code != C.VARNAM_SUCCESS {
+			return result, &VarnamError{
+				ErrorCode: int(code),
+				Message:   handle.GetLastError(),
+			}
+		}
+		defer C.destroySuggestionsArray(resultPointer)
+
+		i := 0
+		for i < int(C.varray_length(resultPointer)) {
+			cSug := (*C.Suggestion)(C.varray_get(resultPointer, C.int(i)))
+			sug := makeSuggestion(cSug)
+			result = append(result, sug)
+			i++
+		}
+
+		return result, nil
+	}
+}
+
+// Session an incremental typing session
+type Session struct {
+	handle    *VarnamHandle
+	sessionID C.int
+}
+
+// NewSession start a typing session. Close it when done.
+func (handle *VarnamHandle) NewSession() (*Session, error) {
+	var sessionID C.int
+	code := C.varnam_session_new(handle.connectionID, unsafe.Pointer(&sessionID))
+	if code != C.VARNAM_SUCCESS {
+		return nil, handle.checkError(code)
+	}
+	return &Session{handle, sessionID}, nil
+}
+
+// Append add typed text, usually a single character
+func (session *Session) Append(text string) {
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+
+	C.varnam_session_append(session.sessionID, cText)
+}
+
+// Backspace remove the last character typed
+func (session *Session) Backspace() {
+	C.varnam_session_backspace(session.sessionID)
+}
+
+// Reset clear the input to start a new word
+func (session *Session) Reset() {
+	C.varnam_session_reset(session.sessionID)
+}
+
+// Input get the text typed so far
+func (session *Session) Input() string {
+	cInput := C.varnam_session_get_input(session.sessionID)
+	defer C.free(unsafe.Pointer(cInput))
+
+	return C.GoString(cInput)
+}
+
+type cgoVarnamSessionSuggestionsResult struct {
+	result *C.struct_TransliterationResult_t
+	code   C.int
+}
+
+func (session *Session) cgoVarnamSessionSuggestions(operationID C.int, resultChannel chan<- cgoVarnamSessionSuggestionsResult) {
+	var resultPointer *C.struct_TransliterationResult_t
+
+	code := C.varnam_session_suggestions(session.sessionID, operationID, &resultPointer)
+	resultChannel <- cgoVarnamSessionSuggestionsResult{resultPointer, code}
+
+	close(resultChannel)
+}
+
+// Suggestions transliterate the input typed so far.
+// Returns ctx.Err() when cancelled.
+func (session *Session) Suggestions(ctx context.Context) (TransliterationResult, error) {
+	var result TransliterationResult
+
+	operationID := makeContextOperation()
+	channel := make(chan cgoVarnamSessionSuggestionsResult)
+
+	go session.cgoVarnamSessionSuggestions(operationID, channel)
+
+	select {
+	case <-ctx.Done():
+		C.varnam_cancel(operationID)
+		return result, ctx.Err()
+	case channelResult := <-channel:
+		if channelResult.code == C.VARNAM_CANCELLED {
+			return result, context.Canceled
+		}
+		if channelResult.code != C.VARNAM_SUCCESS {
+			return result, session.handle.checkError(channelResult.code)
+		}
+		result = makeGoTransliterationResult(ctx, channelResult.result)
+		return result, nil
+	}
+}
+
+// Close end the session
+func (session *Session) Close() error {
+	code := C.varnam_session_close(session.sessionID)
+	if code != C.VARNAM_SUCCESS {
+		return &VarnamError{
+			ErrorCode: int(code),
+			Message:   "session already closed",
+		}
+	}
+	return nil
+}
+
 func makeGoSchemeDetails(cSD *C.struct_SchemeDetails_t) SchemeDetails {
 	isStable := true
 	if cSD.IsStable == 0 {
@@ -720,6 +1182,50 @@ func GetVSTDir() string {
 	return C.GoString(cStr)
 }
 
+// CompileScheme make a VST from scheme source file
+func CompileScheme(srcPath string, vstPath string) error {
+	cSrcPath := C.CString(srcPath)
+	defer C.free(unsafe.Pointer(cSrcPath))
+
+	cVSTPath := C.CString(vstPath)
+	defer C.free(unsafe.Pointer(cVSTPath))
+
+	code := C.vm_compile_scheme(cSrcPath, cVSTPath)
+	if code != C.VARNAM_SUCCESS {
+		cStr := C.varnam_get_last_error(-1)
+		defer C.free(unsafe.Pointer(cStr))
+
+		return &VarnamError{
+			ErrorCode: int(code),
+			Message:   C.GoString(cStr),
+		}
+	}
+
+	return nil
+}
+
+// DecompileVST write scheme source file of a VST
+func DecompileVST(vstPath string, srcPath string) error {
+	cSrcPath := C.CString(srcPath)
+	defer C.free(unsafe.Pointer(cSrcPath))
+
+	cVSTPath := C.CString(vstPath)
+	defer C.free(unsafe.Pointer(cVSTPath))
+
+	code := C.vm_decompile_vst(cVSTPath, cSrcPath)
+	if code != C.VARNAM_SUCCESS {
+		cStr := C.varnam_get_last_error(-1)
+		defer C.free(unsafe.Pointer(cStr))
+
+		return &VarnamError{
+			ErrorCode: int(code),
+			Message:   C.GoString(cStr),
+		}
+	}
+
+	return nil
+}
+
 // GetAllSchemeDetails get all available scheme details. The bool is for error
 func GetAllSchemeDetails() ([]SchemeDetails, bool) {
 	cSchemeDetails := C.varnam_get_all_scheme_details()
@@ -740,3 +1246,71 @@ func GetAllSchemeDetails() ([]SchemeDetails, bool) {
 
 	return schemeDetails, false
 }
+
+// ValidateVST check a VST for problems
+func ValidateVST(vstPath string) (VSTReport, error) {
+	var report VSTReport
+
+	cVSTPath := C.CString(vstPath)
+	defer C.free(unsafe.Pointer(cVSTPath))
+
+	var cReport *C.VSTReport
+	code := C.vm_validate_vst(cVSTPath, &cReport)
+	if code != C.VARNAM_SUCCESS {
+		cStr := C.varnam_get_last_error(-1)
+		defer C.free(unsafe.Pointer(cStr))
+
+		return report, &VarnamError{
+			ErrorCode: int(code),
+			Message:   C.GoString(cStr),
+		}
+	}
+	defer C.destroyVSTReport(cReport)
+
+	report.SchemaVersion = int(cReport.SchemaVersion)
+	report.SymbolsCount = int(cReport.SymbolsCount)
+
+	i := 0
+	for i < int(C.varray_length(cReport.Issues)) {
+		cIssue := (*C.VSTIssue)(C.varray_get(cReport.Issues, C.int(i)))
+		report.Issues = append(report.Issues, VSTIssue{
+			Severity: int(cIssue.Severity),
+			Check:    C.GoString(cIssue.Check),
+			SymbolID: int(cIssue.SymbolID),
+			Message:  C.GoString(cIssue.Message),
+		})
+		i++
+	}
+
+	return report, nil
+}
+
+// DiffVST compare VST at vstPathB against VST at vstPathA.
+// Output is human readable text or JSON
+func DiffVST(vstPathA string, vstPathB string, jsonOutput bool) (string, error) {
+	cVSTPathA := C.CString(vstPathA)
+	defer C.free(unsafe.Pointer(cVSTPathA))
+
+	cVSTPathB := C.CString(vstPathB)
+	defer C.free(unsafe.Pointer(cVSTPathB))
+
+	format := C.int(C.VARNAM_DIFF_FORMAT_TEXT)
+	if jsonOutput {
+		format = C.VARNAM_DIFF_FORMAT_JSON
+	}
+
+	var cOutput *C.char
+	code := C.vm_diff_vst(cVSTPathA, cVSTPathB, format, &cOutput)
+	if code != C.VARNAM_SUCCESS {
+		cStr := C.varnam_get_last_error(-1)
+		defer C.free(unsafe.Pointer(cStr))
+
+		return "", &VarnamError{
+			ErrorCode: int(code),
+			Message:   C.GoString(cStr),
+		}
+	}
+	defer C.free(unsafe.Pointer(cOutput))
+
+	return C.GoString(cOutput), nil
+}
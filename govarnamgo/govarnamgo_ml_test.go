@@ -32,6 +32,165 @@ func TestTransliterateAdvanced(t *testing.T) {
 	assertEqual(t, result.TokenizerSuggestions[0].Word, "നിത്യം")
 }
 
+func TestSession(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+
+	session, err := varnam.NewSession()
+	checkError(err)
+
+	for _, char := range "nithyam" {
+		session.Append(string(char))
+	}
+	session.Backspace()
+	session.Append("m")
+	assertEqual(t, session.Input(), "nithyam")
+
+	result, err := session.Suggestions(context.Background())
+	checkError(err)
+
+	expected, err := varnam.TransliterateAdvanced(context.Background(), "nithyam")
+	checkError(err)
+	assertEqual(t, result.TokenizerSuggestions[0].Word, expected.TokenizerSuggestions[0].Word)
+
+	session.Reset()
+	assertEqual(t, session.Input(), "")
+
+	checkError(session.Close())
+	assertEqual(t, session.Close() != nil, true)
+
+	// Sessions are closed with their handle
+	other, err := InitFromID("ml")
+	checkError(err)
+
+	session, err = other.NewSession()
+	checkError(err)
+
+	checkError(other.Close())
+	assertEqual(t, session.Close() != nil, true)
+}
+
+func TestExplainSuggestions(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+
+	// Default config
+	config := Config{DictionarySuggestionsLimit: 5, PatternDictionarySuggestionsLimit: 5, TokenizerSuggestionsLimit: 10, TokenizerSuggestionsAlways: true}
+
+	config.ExplainSuggestions = true
+	varnam.SetConfig(config)
+
+	result, err := varnam.TransliterateAdvanced(context.Background(), "nithyam")
+	checkError(err)
+
+	sug := result.TokenizerSuggestions[0]
+	assertEqual(t, sug.Source, VARNAM_SOURCE_TOKENIZER)
+	assertEqual(t, sug.Score.SymbolWeight, sug.Weight)
+	assertEqual(t, result.GreedyTokenized[0].Source, VARNAM_SOURCE_GREEDY)
+
+	config.ExplainSuggestions = false
+	varnam.SetConfig(config)
+
+	result, err = varnam.TransliterateAdvanced(context.Background(), "nithyam")
+	checkError(err)
+	assertEqual(t, result.TokenizerSuggestions[0].Score == nil, true)
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+
+	checkError(varnam.Learn("വയൽ", 0))
+	defer varnam.Unlearn("വയൽ")
+
+	// Default config
+	config := Config{DictionarySuggestionsLimit: 5, PatternDictionarySuggestionsLimit: 5, TokenizerSuggestionsLimit: 10, TokenizerSuggestionsAlways: true}
+	defer varnam.SetConfig(config)
+
+	config.ExplainSuggestions = true
+	config.FuzzyMatchMaxEdits = 1
+	varnam.SetConfig(config)
+
+	// "a" typed as "s"
+	result, err := varnam.TransliterateAdvanced(context.Background(), "vsyal")
+	checkError(err)
+
+	found := false
+	for _, sug := range result.DictionarySuggestions {
+		if sug.Word == "വയൽ" {
+			found = true
+			assertEqual(t, sug.Score.EditPenalty < 0, true)
+		}
+	}
+	assertEqual(t, found, true)
+}
+
+func TestPhoneticMatch(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+
+	checkError(varnam.Learn("തത്ത", 0))
+	defer varnam.Unlearn("തത്ത")
+
+	// Default config
+	config := Config{DictionarySuggestionsLimit: 5, PatternDictionarySuggestionsLimit: 5, TokenizerSuggestionsLimit: 10, TokenizerSuggestionsAlways: true}
+	defer varnam.SetConfig(config)
+
+	config.PhoneticMatch = true
+	varnam.SetConfig(config)
+
+	// "thaththa" typed as "dada"
+	result, err := varnam.TransliterateAdvanced(context.Background(), "dada")
+	checkError(err)
+
+	found := false
+	for _, sug := range result.DictionarySuggestions {
+		if sug.Word == "തത്ത" {
+			found = true
+		}
+	}
+	assertEqual(t, found, true)
+}
+
+func TestCompactLearnings(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+
+	// Half-life is not set in default config
+	_, err := varnam.CompactLearnings()
+	assertEqual(t, err != nil, true)
+	assertEqual(t, varnam.GetLastError(), "learning half-life is not set")
+}
+
+func TestWeightedRanker(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+
+	// Default config
+	config := Config{DictionarySuggestionsLimit: 5, PatternDictionarySuggestionsLimit: 5, TokenizerSuggestionsLimit: 10, TokenizerSuggestionsAlways: true}
+	defer varnam.SetConfig(config)
+
+	// Only tokenizer results count
+	config.Ranker = &WeightedRankerConfig{
+		SourceWeights: map[int]int{
+			VARNAM_SOURCE_EXACT_WORD:         1,
+			VARNAM_SOURCE_EXACT_MATCH:        1,
+			VARNAM_SOURCE_DICTIONARY:         1,
+			VARNAM_SOURCE_PATTERN_DICTIONARY: 1,
+			VARNAM_SOURCE_GREEDY:             1,
+			VARNAM_SOURCE_TOKENIZER:          1000,
+		},
+		RecencyBonus:    -1,
+		ShortWordLength: -1,
+	}
+	varnam.SetConfig(config)
+
+	sugs, err := varnam.Transliterate(context.Background(), "nithyam")
+	checkError(err)
+	assertEqual(t, sugs[0].Source, VARNAM_SOURCE_TOKENIZER)
+
+	config.Ranker.SourceWeights = map[int]int{VARNAM_SOURCE_TOKENIZER: 1}
+	varnam.SetConfig(config)
+
+	sugs, err = varnam.Transliterate(context.Background(), "nithyam")
+	checkError(err)
+	assertEqual(t, sugs[0].Source != VARNAM_SOURCE_TOKENIZER, true)
+}
+
 func TestReverseTransliterate(t *testing.T) {
 	varnam := getVarnamInstance("ml")
 
@@ -97,3 +256,31 @@ func TestSearchSymbolTable(t *testing.T) {
 
 	assertEqual(t, result[0].Value1, "ല")
 }
+
+func TestTransliterateText(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+
+	segments, err := varnam.TransliterateText(context.Background(), "nithyam, nithyam")
+	checkError(err)
+
+	assertEqual(t, len(segments), 3)
+	assertEqual(t, segments[0].Suggestions[0].Word, "നിത്യം")
+	assertEqual(t, segments[1].Text, ", ")
+	assertEqual(t, segments[1].IsWord, false)
+	assertEqual(t, segments[2].Start, 9)
+	assertEqual(t, segments[2].Suggestions[0].Word, "നിത്യം")
+}
+
+func TestPredictNextWords(t *testing.T) {
+	varnam := getVarnamInstance("ml")
+
+	varnam.Learn("ഇന്ന്", 0)
+	varnam.Learn("വന്നു", 0)
+
+	sugs, err := varnam.PredictNextWords(context.Background(), []string{"ഇന്ന്"}, 5)
+	checkError(err)
+
+	assertEqual(t, len(sugs), 1)
+	assertEqual(t, sugs[0].Word, "വന്നു")
+	assertEqual(t, sugs[0].Weight, 1)
+}
@@ -0,0 +1,584 @@
+package native
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+/**
+ * Go bindings without the C library. Has the same API as
+ * govarnamgo, but uses govarnam package directly. Go apps
+ * can use this without building & installing libgovarnam.so
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/varnamproject/govarnam/govarnam"
+)
+
+// Config  config values
+type Config struct {
+	IndicDigits                       bool
+	DictionaryMatchExact              bool
+	DictionarySuggestionsLimit        int
+	PatternDictionarySuggestionsLimit int
+	TokenizerSuggestionsLimit         int
+	TokenizerSuggestionsAlways        bool
+
+	// Maximum transliteration results to cache, 0 disables it
+	CacheSize int
+
+	// Fill Suggestion.Score with how its weight was made
+	ExplainSuggestions bool
+
+	// Rank with the weighted ranker instead of the default one
+	Ranker *WeightedRankerConfig
+
+	// Learnt weights halve every this many seconds since the
+	// word was last learnt. 0 disables it
+	LearningHalfLife int
+
+	// Typos tolerated in input when no dictionary word is found,
+	// at most 2. 0 disables it
+	FuzzyMatchMaxEdits int
+
+	// Maximum inputs with typos fixed searched for a
+	// transliteration. 0 keeps the default (200)
+	FuzzyMatchCostLimit int
+
+	// Suggest learnt words that sound like the input
+	PhoneticMatch bool
+
+	// How weights of a word in more than one dictionary layer
+	// are combined, one of VARNAM_LAYER_WEIGHT_*
+	LayerWeightPolicy int
+}
+
+// WeightedRankerConfig values of weighted ranker, see
+// govarnam.WeightedRanker. 0 keeps the default value,
+// use -1 to disable recency bonus or short words.
+type WeightedRankerConfig struct {
+	// VARNAM_SOURCE_* => percentage of weight counted
+	SourceWeights   map[int]int
+	RecencyBonus    int
+	RecencyHalfLife int
+	ShortWordLength int
+}
+
+// VarnamHandle for making things easier
+type VarnamHandle struct {
+	varnam *govarnam.Varnam
+
+	errMutex sync.Mutex
+	err      error
+}
+
+// Suggestion suggestion
+type Suggestion = govarnam.Suggestion
+
+// TransliterationResult result
+type TransliterationResult = govarnam.TransliterationResult
+
+// TextSegment a word or non-word part of a text
+type TextSegment = govarnam.TextSegment
+
+// SchemeDetails of VST
+type SchemeDetails = govarnam.SchemeDetails
+
+// LearnStatus output of bulk learn
+type LearnStatus = govarnam.LearnStatus
+
+// LearningsProgress words & patterns done so
+// far by ImportWithProgress or ExportWithProgress
+type LearningsProgress = govarnam.LearningsProgress
+
+// Symbol result from VST
+type Symbol = govarnam.Symbol
+
+// ScoreBreakdown parts of the weight of a suggestion
+type ScoreBreakdown = govarnam.ScoreBreakdown
+
+// CacheStats counters of transliteration cache
+type CacheStats = govarnam.CacheStats
+
+// Severity of VSTIssue
+const (
+	VARNAM_VST_ISSUE_ERROR   = govarnam.VARNAM_VST_ISSUE_ERROR
+	VARNAM_VST_ISSUE_WARNING = govarnam.VARNAM_VST_ISSUE_WARNING
+)
+
+// Source of Suggestion
+const (
+	VARNAM_SOURCE_EXACT_WORD         = govarnam.VARNAM_SOURCE_EXACT_WORD
+	VARNAM_SOURCE_EXACT_MATCH        = govarnam.VARNAM_SOURCE_EXACT_MATCH
+	VARNAM_SOURCE_DICTIONARY         = govarnam.VARNAM_SOURCE_DICTIONARY
+	VARNAM_SOURCE_PATTERN_DICTIONARY = govarnam.VARNAM_SOURCE_PATTERN_DICTIONARY
+	VARNAM_SOURCE_TOKENIZER          = govarnam.VARNAM_SOURCE_TOKENIZER
+	VARNAM_SOURCE_GREEDY             = govarnam.VARNAM_SOURCE_GREEDY
+)
+
+// How weights of a word in more than one dictionary
+// layer are combined, see Config.LayerWeightPolicy
+const (
+	VARNAM_LAYER_WEIGHT_OVERRIDE = govarnam.VARNAM_LAYER_WEIGHT_OVERRIDE
+	VARNAM_LAYER_WEIGHT_MAX      = govarnam.VARNAM_LAYER_WEIGHT_MAX
+	VARNAM_LAYER_WEIGHT_SUM      = govarnam.VARNAM_LAYER_WEIGHT_SUM
+)
+
+// How weights of a word in both are combined by MergeLearnings
+const (
+	VARNAM_MERGE_WEIGHT_MAX = govarnam.VARNAM_MERGE_WEIGHT_MAX
+	VARNAM_MERGE_WEIGHT_SUM = govarnam.VARNAM_MERGE_WEIGHT_SUM
+)
+
+// VSTIssue a problem found in VST
+type VSTIssue = govarnam.VSTIssue
+
+// VSTReport result of ValidateVST
+type VSTReport = govarnam.VSTReport
+
+// MergeReport changes made by MergeLearnings,
+// or to be made in a dry run
+type MergeReport = govarnam.MergeReport
+
+// Keep error for GetLastError(). Successful
+// calls don't clear the last error.
+func (handle *VarnamHandle) setError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	handle.errMutex.Lock()
+	handle.err = err
+	handle.errMutex.Unlock()
+
+	return err
+}
+
+// GetVersion get library version
+func GetVersion() string {
+	return govarnam.VersionString
+}
+
+// GetBuild get library build version
+func GetBuild() string {
+	return govarnam.BuildString
+}
+
+// Init Initialize
+func Init(vstLoc string, dictLoc string) (*VarnamHandle, error) {
+	varnam, err := govarnam.Init(vstLoc, dictLoc)
+	if err != nil {
+		return nil, err
+	}
+	return &VarnamHandle{varnam: varnam}, nil
+}
+
+// InitFromID Initialize
+func InitFromID(id string) (*VarnamHandle, error) {
+	varnam, err := govarnam.InitFromID(id)
+	if err != nil {
+		return nil, err
+	}
+	return &VarnamHandle{varnam: varnam}, nil
+}
+
+// InitFromIDWithProfile Initialize with learnings stored in
+// the profile. Profile is made if it doesn't exist
+func InitFromIDWithProfile(id string, profile string) (*VarnamHandle, error) {
+	varnam, err := govarnam.InitFromIDWithProfile(id, profile)
+	if err != nil {
+		return nil, err
+	}
+	return &VarnamHandle{varnam: varnam}, nil
+}
+
+// GetLastError get last error
+func (handle *VarnamHandle) GetLastError() string {
+	handle.errMutex.Lock()
+	defer handle.errMutex.Unlock()
+
+	if handle.err == nil {
+		return ""
+	}
+	return handle.err.Error()
+}
+
+// Close db connections and end varnam
+func (handle *VarnamHandle) Close() error {
+	return handle.setError(handle.varnam.Close())
+}
+
+// Debug turn debug on/off. Not safe to call
+// while the handle is being used
+func (handle *VarnamHandle) Debug(val bool) {
+	handle.varnam.Debug = val
+}
+
+// SetConfig set config. Not safe to call
+// while the handle is being used
+func (handle *VarnamHandle) SetConfig(config Config) {
+	handle.varnam.LangRules.IndicDigits = config.IndicDigits
+	handle.varnam.DictionaryMatchExact = config.DictionaryMatchExact
+	handle.varnam.DictionarySuggestionsLimit = config.DictionarySuggestionsLimit
+	handle.varnam.PatternDictionarySuggestionsLimit = config.PatternDictionarySuggestionsLimit
+	handle.varnam.TokenizerSuggestionsLimit = config.TokenizerSuggestionsLimit
+	handle.varnam.TokenizerSuggestionsAlways = config.TokenizerSuggestionsAlways
+	handle.varnam.ExplainSuggestions = config.ExplainSuggestions
+	handle.varnam.Ranker = makeRanker(config.Ranker)
+	handle.varnam.LearningHalfLife = config.LearningHalfLife
+	handle.varnam.FuzzyMatchMaxEdits = config.FuzzyMatchMaxEdits
+	if config.FuzzyMatchCostLimit == 0 {
+		handle.varnam.FuzzyMatchCostLimit = govarnam.VARNAM_FUZZY_MATCH_COST_LIMIT
+	} else {
+		handle.varnam.FuzzyMatchCostLimit = config.FuzzyMatchCostLimit
+	}
+	handle.varnam.PhoneticMatch = config.PhoneticMatch
+	handle.varnam.LayerWeightPolicy = config.LayerWeightPolicy
+	handle.varnam.EnableCache(config.CacheSize)
+}
+
+func makeRanker(config *WeightedRankerConfig) govarnam.Ranker {
+	if config == nil {
+		return nil
+	}
+
+	ranker := govarnam.NewWeightedRanker()
+
+	for source, weight := range config.SourceWeights {
+		ranker.SourceWeights[source] = weight
+	}
+
+	if config.RecencyBonus != 0 {
+		ranker.RecencyBonus = config.RecencyBonus
+	}
+	if config.RecencyHalfLife != 0 {
+		ranker.RecencyHalfLife = config.RecencyHalfLife
+	}
+	if config.ShortWordLength != 0 {
+		ranker.ShortWordLength = config.ShortWordLength
+	}
+
+	return ranker
+}
+
+// Transliterate transilterate. Returns ctx.Err() if cancelled
+func (handle *VarnamHandle) Transliterate(ctx context.Context, word string) ([]Suggestion, error) {
+	// Buffered so that the goroutine won't block
+	// if ctx got cancelled in between
+	channel := make(chan []Suggestion, 1)
+
+	go handle.varnam.TransliterateWithContext(ctx, word, channel)
+
+	select {
+	case <-ctx.Done():
+		return nil, handle.setError(ctx.Err())
+	case result := <-channel:
+		return result, nil
+	}
+}
+
+// TransliterateAdvanced transilterate. Returns ctx.Err() if cancelled
+func (handle *VarnamHandle) TransliterateAdvanced(ctx context.Context, word string) (TransliterationResult, error) {
+	channel := make(chan TransliterationResult, 1)
+
+	go handle.varnam.TransliterateAdvancedWithContext(ctx, word, channel)
+
+	select {
+	case <-ctx.Done():
+		return TransliterationResult{}, handle.setError(ctx.Err())
+	case result := <-channel:
+		return result, nil
+	}
+}
+
+// TransliterateText transliterate a text with multiple
+// words, punctuations etc. Returns ctx.Err() if cancelled
+func (handle *VarnamHandle) TransliterateText(ctx context.Context, text string) ([]TextSegment, error) {
+	result, err := handle.varnam.TransliterateText(ctx, text)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, handle.setError(err)
+	}
+	return result, nil
+}
+
+// TransliterateGreedyTokenized transliterate but only tokenizer output
+func (handle *VarnamHandle) TransliterateGreedyTokenized(word string) []Suggestion {
+	return handle.varnam.TransliterateGreedyTokenized(word)
+}
+
+// ReverseTransliterate reverse transilterate
+func (handle *VarnamHandle) ReverseTransliterate(word string) ([]Suggestion, error) {
+	sugs, err := handle.varnam.ReverseTransliterate(word)
+	return sugs, handle.setError(err)
+}
+
+// Train train a pattern => word
+func (handle *VarnamHandle) Train(pattern string, word string) error {
+	return handle.setError(handle.varnam.Train(pattern, word))
+}
+
+// Learn a word. If already exist, increases weight
+func (handle *VarnamHandle) Learn(word string, weight int) error {
+	return handle.setError(handle.varnam.Learn(word, weight))
+}
+
+// Unlearn a word, remove from words DB and pattern if there is
+func (handle *VarnamHandle) Unlearn(word string) error {
+	return handle.setError(handle.varnam.Unlearn(word))
+}
+
+// LearnFromFile learn words from a file
+func (handle *VarnamHandle) LearnFromFile(filePath string) (LearnStatus, error) {
+	learnStatus, err := handle.varnam.LearnFromFile(filePath)
+	return learnStatus, handle.setError(err)
+}
+
+// TrainFromFile train pattern => word from a file
+func (handle *VarnamHandle) TrainFromFile(filePath string) (LearnStatus, error) {
+	learnStatus, err := handle.varnam.TrainFromFile(filePath)
+	return learnStatus, handle.setError(err)
+}
+
+// Export learnings to files
+func (handle *VarnamHandle) Export(filePath string, wordsPerFile int) error {
+	return handle.setError(handle.varnam.Export(filePath, wordsPerFile))
+}
+
+// ExportWithProgress export learnings to files, progress
+// is called after writing each file
+func (handle *VarnamHandle) ExportWithProgress(filePath string, wordsPerFile int, progress func(LearningsProgress)) error {
+	return handle.setError(handle.varnam.ExportWithProgress(filePath, wordsPerFile, progress))
+}
+
+// Import learnings from file
+func (handle *VarnamHandle) Import(filePath string) error {
+	return handle.setError(handle.varnam.Import(filePath))
+}
+
+// ImportWithProgress import learnings from file, progress is
+// called after every batch of words or patterns inserted
+func (handle *VarnamHandle) ImportWithProgress(filePath string, progress func(LearningsProgress)) error {
+	return handle.setError(handle.varnam.ImportWithProgress(filePath, progress))
+}
+
+// MergeLearnings merge learnings of another dictionary or a .vlf
+// export into the dictionary in use. strategy is one of
+// VARNAM_MERGE_WEIGHT_*. With dryRun, nothing is changed
+func (handle *VarnamHandle) MergeLearnings(filePath string, strategy int, dryRun bool) (MergeReport, error) {
+	report, err := handle.varnam.MergeLearnings(filePath, strategy, dryRun)
+	return report, handle.setError(err)
+}
+
+// ReIndexDictionary rebuild full-text search index of dictionary
+func (handle *VarnamHandle) ReIndexDictionary() error {
+	return handle.setError(handle.varnam.ReIndexDictionary())
+}
+
+// CompactLearnings store decayed weights of words. Needs
+// Config.LearningHalfLife. Returns number of words changed
+func (handle *VarnamHandle) CompactLearnings() (int, error) {
+	changed, err := handle.varnam.CompactLearnings()
+	return changed, handle.setError(err)
+}
+
+// ListProfiles get names of learnings profiles of the language
+func (handle *VarnamHandle) ListProfiles() ([]string, error) {
+	profiles, err := handle.varnam.ListProfiles()
+	return profiles, handle.setError(err)
+}
+
+// CreateProfile make an empty learnings profile
+func (handle *VarnamHandle) CreateProfile(profile string) error {
+	return handle.setError(handle.varnam.CreateProfile(profile))
+}
+
+// DeleteProfile delete a learnings profile and its words
+func (handle *VarnamHandle) DeleteProfile(profile string) error {
+	return handle.setError(handle.varnam.DeleteProfile(profile))
+}
+
+// SetBaseProfile search the words of a profile too, read
+// only. Empty profile removes the base profile
+func (handle *VarnamHandle) SetBaseProfile(profile string) error {
+	return handle.setError(handle.varnam.SetBaseProfile(profile))
+}
+
+// AttachDictionary search the words of a read only
+// dictionary too, after the dictionary in use
+func (handle *VarnamHandle) AttachDictionary(dictPath string) error {
+	return handle.setError(handle.varnam.AttachDictionary(dictPath))
+}
+
+// DetachDictionary stop searching an attached dictionary
+func (handle *VarnamHandle) DetachDictionary(dictPath string) error {
+	return handle.setError(handle.varnam.DetachDictionary(dictPath))
+}
+
+// GetAttachedDictionaries get paths of attached
+// dictionaries in the order they're searched
+func (handle *VarnamHandle) GetAttachedDictionaries() []string {
+	return handle.varnam.GetAttachedDictionaries()
+}
+
+// GetRecentlyLearntWords get recently learn words. Returns ctx.Err() if cancelled
+func (handle *VarnamHandle) GetRecentlyLearntWords(ctx context.Context, offset int, limit int) ([]Suggestion, error) {
+	result, err := handle.varnam.GetRecentlyLearntWords(ctx, offset, limit)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, handle.setError(err)
+	}
+	return result, nil
+}
+
+// GetSuggestions get suggestions for a word. Returns ctx.Err() if cancelled
+func (handle *VarnamHandle) GetSuggestions(ctx context.Context, word string) ([]Suggestion, error) {
+	result := handle.varnam.GetSuggestions(ctx, word)
+	if ctx.Err() != nil {
+		return nil, handle.setError(ctx.Err())
+	}
+	return result, nil
+}
+
+// PredictNextWords predict words that may come after
+// previous words. Returns ctx.Err() if cancelled
+func (handle *VarnamHandle) PredictNextWords(ctx context.Context, previousWords []string, limit int) ([]Suggestion, error) {
+	result := handle.varnam.PredictNextWords(ctx, previousWords, limit)
+	if ctx.Err() != nil {
+		return nil, handle.setError(ctx.Err())
+	}
+	return result, nil
+}
+
+// GetCacheStats get hit & miss counters of transliteration cache
+func (handle *VarnamHandle) GetCacheStats() CacheStats {
+	return handle.varnam.CacheStats()
+}
+
+// Session an incremental typing session
+type Session struct {
+	session *govarnam.Session
+}
+
+// NewSession start a typing session. Close it when done.
+func (handle *VarnamHandle) NewSession() (*Session, error) {
+	return &Session{handle.varnam.NewSession()}, nil
+}
+
+// Append add typed text, usually a single character
+func (session *Session) Append(text string) {
+	session.session.Append(text)
+}
+
+// Backspace remove the last character typed
+func (session *Session) Backspace() {
+	session.session.Backspace()
+}
+
+// Reset clear the input to start a new word
+func (session *Session) Reset() {
+	session.session.Reset()
+}
+
+// Input get the text typed so far
+func (session *Session) Input() string {
+	return session.session.Input()
+}
+
+// Suggestions transliterate the input typed so far.
+// Returns ctx.Err() when cancelled.
+func (session *Session) Suggestions(ctx context.Context) (TransliterationResult, error) {
+	return session.session.Suggestions(ctx)
+}
+
+// Close end the session. Nothing to free here,
+// it's for having the same API as govarnamgo.
+func (session *Session) Close() error {
+	return nil
+}
+
+// GetSchemeDetails get scheme details
+func (handle *VarnamHandle) GetSchemeDetails() SchemeDetails {
+	return handle.varnam.SchemeDetails
+}
+
+// GetVSTPath Get path to VST of current handle
+func (handle *VarnamHandle) GetVSTPath() string {
+	return handle.varnam.VSTPath
+}
+
+// NewSearchSymbol make a Symbol for SearchSymbolTable
+// with all fields unset
+func NewSearchSymbol() Symbol {
+	return govarnam.NewSearchSymbol()
+}
+
+// SearchSymbolTable search VST
+func (handle *VarnamHandle) SearchSymbolTable(ctx context.Context, searchCriteria Symbol) []Symbol {
+	results, err := handle.varnam.SearchSymbolTable(ctx, searchCriteria)
+	if err != nil {
+		handle.setError(err)
+		return nil
+	}
+	return results
+}
+
+// GetVSTDir Get path to directory containging the VSTs
+func GetVSTDir() string {
+	dir, _ := govarnam.FindVSTDir()
+	return dir
+}
+
+// CompileScheme make a VST from scheme source file
+func CompileScheme(srcPath string, vstPath string) error {
+	return govarnam.CompileScheme(srcPath, vstPath)
+}
+
+// DecompileVST write scheme source file of a VST
+func DecompileVST(vstPath string, srcPath string) error {
+	return govarnam.DecompileVSTToFile(vstPath, srcPath)
+}
+
+// GetAllSchemeDetails get all available scheme details.
+// Unlike govarnamgo, returns an error instead of bool.
+func GetAllSchemeDetails() ([]SchemeDetails, error) {
+	return govarnam.GetAllSchemeDetails()
+}
+
+// ValidateVST check a VST for problems
+func ValidateVST(vstPath string) (VSTReport, error) {
+	report, err := govarnam.ValidateVST(vstPath)
+	if err != nil {
+		return VSTReport{}, err
+	}
+	return *report, nil
+}
+
+// DiffVST compare VST at vstPathB against VST at vstPathA.
+// Output is human readable text or JSON
+func DiffVST(vstPathA string, vstPathB string, jsonOutput bool) (string, error) {
+	diff, err := govarnam.DiffVST(vstPathA, vstPathB)
+	if err != nil {
+		return "", err
+	}
+
+	if !jsonOutput {
+		return diff.Text(), nil
+	}
+
+	jsonData, err := json.Marshal(diff)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonData), nil
+}
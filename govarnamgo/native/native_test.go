@@ -0,0 +1,304 @@
+package native
+
+import (
+	"context"
+	"log"
+	"os"
+	"path"
+	"reflect"
+	"runtime/debug"
+	"testing"
+
+	"github.com/varnamproject/govarnam/govarnam"
+)
+
+var varnam *VarnamHandle
+
+func checkError(err error) {
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+func assertEqual(t *testing.T, a interface{}, b interface{}) {
+	if a == b {
+		return
+	}
+	debug.PrintStack()
+	t.Errorf("Received %v (type %v), expected %v (type %v)", a, reflect.TypeOf(a), b, reflect.TypeOf(b))
+}
+
+func TestTransliterate(t *testing.T) {
+	sugs, err := varnam.Transliterate(context.Background(), "nithyam")
+	checkError(err)
+	assertEqual(t, len(sugs) > 0, true)
+
+	result, err := varnam.TransliterateAdvanced(context.Background(), "nithyam")
+	checkError(err)
+	assertEqual(t, result.GreedyTokenized[0].Word, "നിത്യമ്")
+
+	segments, err := varnam.TransliterateText(context.Background(), "nithyam, nithyam")
+	checkError(err)
+	assertEqual(t, len(segments), 3)
+	assertEqual(t, segments[1].Text, ", ")
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	sugs, err := varnam.Transliterate(ctx, "nithyam")
+	assertEqual(t, err, context.Canceled)
+	assertEqual(t, len(sugs), 0)
+	assertEqual(t, varnam.GetLastError(), context.Canceled.Error())
+
+	_, err = varnam.TransliterateAdvanced(ctx, "nithyam")
+	assertEqual(t, err, context.Canceled)
+
+	_, err = varnam.GetSuggestions(ctx, "നി")
+	assertEqual(t, err, context.Canceled)
+}
+
+func TestLearn(t *testing.T) {
+	checkError(varnam.Learn("വയൽ", 0))
+
+	sugs, err := varnam.GetSuggestions(context.Background(), "വയ")
+	checkError(err)
+	assertEqual(t, sugs[0].Word, "വയൽ")
+
+	sugs, err = varnam.GetRecentlyLearntWords(context.Background(), 0, 10)
+	checkError(err)
+	assertEqual(t, sugs[0].Word, "വയൽ")
+
+	checkError(varnam.Unlearn("വയൽ"))
+
+	sugs, err = varnam.GetSuggestions(context.Background(), "വയ")
+	checkError(err)
+	assertEqual(t, len(sugs), 0)
+
+	// Errors are returned, not logged
+	err = varnam.Learn("", 0)
+	assertEqual(t, err != nil, true)
+	assertEqual(t, varnam.GetLastError(), err.Error())
+
+	// Last error stays after a successful call
+	_, err = varnam.GetSuggestions(context.Background(), "വയ")
+	checkError(err)
+	assertEqual(t, varnam.GetLastError() != "", true)
+}
+
+func TestSetConfig(t *testing.T) {
+	varnam.SetConfig(Config{IndicDigits: true, TokenizerSuggestionsLimit: 1})
+	defer varnam.SetConfig(Config{DictionarySuggestionsLimit: 5, PatternDictionarySuggestionsLimit: 5, TokenizerSuggestionsLimit: 10, TokenizerSuggestionsAlways: true})
+
+	sugs, err := varnam.Transliterate(context.Background(), "1")
+	checkError(err)
+	assertEqual(t, sugs[0].Word, "൧")
+}
+
+func TestCache(t *testing.T) {
+	varnam.SetConfig(Config{DictionarySuggestionsLimit: 5, PatternDictionarySuggestionsLimit: 5, TokenizerSuggestionsLimit: 10, TokenizerSuggestionsAlways: true, CacheSize: 10})
+	defer varnam.SetConfig(Config{DictionarySuggestionsLimit: 5, PatternDictionarySuggestionsLimit: 5, TokenizerSuggestionsLimit: 10, TokenizerSuggestionsAlways: true})
+
+	for i := 0; i < 2; i++ {
+		_, err := varnam.Transliterate(context.Background(), "nithyam")
+		checkError(err)
+	}
+
+	stats := varnam.GetCacheStats()
+	assertEqual(t, stats.Hits, uint64(1))
+	assertEqual(t, stats.Misses, uint64(1))
+}
+
+func TestSession(t *testing.T) {
+	session, err := varnam.NewSession()
+	checkError(err)
+	defer session.Close()
+
+	for _, char := range "nithyam" {
+		session.Append(string(char))
+	}
+	assertEqual(t, session.Input(), "nithyam")
+
+	result, err := session.Suggestions(context.Background())
+	checkError(err)
+
+	expected, err := varnam.TransliterateAdvanced(context.Background(), "nithyam")
+	checkError(err)
+	assertEqual(t, result.TokenizerSuggestions[0].Word, expected.TokenizerSuggestions[0].Word)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = session.Suggestions(ctx)
+	assertEqual(t, err, context.Canceled)
+}
+
+func TestWeightedRanker(t *testing.T) {
+	config := Config{DictionarySuggestionsLimit: 5, PatternDictionarySuggestionsLimit: 5, TokenizerSuggestionsLimit: 10, TokenizerSuggestionsAlways: true}
+	defer varnam.SetConfig(config)
+
+	config.Ranker = &WeightedRankerConfig{
+		SourceWeights: map[int]int{VARNAM_SOURCE_TOKENIZER: 1000},
+	}
+	varnam.SetConfig(config)
+
+	sugs, err := varnam.Transliterate(context.Background(), "nithyam")
+	checkError(err)
+	assertEqual(t, sugs[0].Source, VARNAM_SOURCE_TOKENIZER)
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	checkError(varnam.Learn("വയൽ", 0))
+	defer varnam.Unlearn("വയൽ")
+
+	config := Config{DictionarySuggestionsLimit: 5, PatternDictionarySuggestionsLimit: 5, TokenizerSuggestionsLimit: 10, TokenizerSuggestionsAlways: true}
+	defer varnam.SetConfig(config)
+
+	config.FuzzyMatchMaxEdits = 1
+	varnam.SetConfig(config)
+
+	// "a" typed as "s"
+	result, err := varnam.TransliterateAdvanced(context.Background(), "vsyal")
+	checkError(err)
+	assertEqual(t, result.DictionarySuggestions[0].Word, "വയൽ")
+}
+
+func TestPhoneticMatch(t *testing.T) {
+	checkError(varnam.Learn("തത്ത", 0))
+	defer varnam.Unlearn("തത്ത")
+
+	config := Config{DictionarySuggestionsLimit: 5, PatternDictionarySuggestionsLimit: 5, TokenizerSuggestionsLimit: 10, TokenizerSuggestionsAlways: true}
+	defer varnam.SetConfig(config)
+
+	config.PhoneticMatch = true
+	varnam.SetConfig(config)
+
+	// "thaththa" typed as "dada"
+	result, err := varnam.TransliterateAdvanced(context.Background(), "dada")
+	checkError(err)
+	assertEqual(t, result.DictionarySuggestions[0].Word, "തത്ത")
+}
+
+func TestProfiles(t *testing.T) {
+	base, err := InitFromIDWithProfile("ml", "base")
+	checkError(err)
+	defer varnam.DeleteProfile("base")
+	defer base.Close()
+
+	// Words unlearnt by other tests are hidden in base profile
+	checkError(base.Learn("മലയാളം", 0))
+
+	profiles, err := varnam.ListProfiles()
+	checkError(err)
+	assertEqual(t, reflect.DeepEqual(profiles, []string{"base", govarnam.VARNAM_DEFAULT_PROFILE}), true)
+
+	checkError(varnam.SetBaseProfile("base"))
+	defer varnam.SetBaseProfile("")
+
+	result, err := varnam.TransliterateAdvanced(context.Background(), "malayaaLam")
+	checkError(err)
+	assertEqual(t, result.ExactWords[0].Word, "മലയാളം")
+}
+
+func TestAttachDictionary(t *testing.T) {
+	base, err := InitFromIDWithProfile("ml", "curated")
+	checkError(err)
+	defer varnam.DeleteProfile("curated")
+	defer base.Close()
+
+	checkError(base.Learn("മലയാളം", 0))
+
+	checkError(varnam.AttachDictionary(base.varnam.DictPath))
+	assertEqual(t, reflect.DeepEqual(varnam.GetAttachedDictionaries(), []string{base.varnam.DictPath}), true)
+
+	result, err := varnam.TransliterateAdvanced(context.Background(), "malayaaLam")
+	checkError(err)
+	assertEqual(t, result.ExactWords[0].Word, "മലയാളം")
+
+	checkError(varnam.DetachDictionary(base.varnam.DictPath))
+	assertEqual(t, len(varnam.GetAttachedDictionaries()), 0)
+}
+
+func TestMergeLearnings(t *testing.T) {
+	team, err := InitFromIDWithProfile("ml", "team")
+	checkError(err)
+	defer varnam.DeleteProfile("team")
+	defer team.Close()
+
+	checkError(team.Learn("മലയാളം", 0))
+
+	report, err := varnam.MergeLearnings(team.varnam.DictPath, VARNAM_MERGE_WEIGHT_MAX, true)
+	checkError(err)
+	assertEqual(t, reflect.DeepEqual(report.AddedWords, []string{"മലയാളം"}), true)
+
+	result, err := varnam.TransliterateAdvanced(context.Background(), "malayaaLam")
+	checkError(err)
+	assertEqual(t, len(result.ExactWords), 0)
+
+	_, err = varnam.MergeLearnings(team.varnam.DictPath, VARNAM_MERGE_WEIGHT_MAX, false)
+	checkError(err)
+	defer varnam.Unlearn("മലയാളം")
+
+	result, err = varnam.TransliterateAdvanced(context.Background(), "malayaaLam")
+	checkError(err)
+	assertEqual(t, result.ExactWords[0].Word, "മലയാളം")
+}
+
+func TestExportImportProgress(t *testing.T) {
+	checkError(varnam.Learn("തത്ത", 0))
+	defer varnam.Unlearn("തത്ത")
+
+	exportPath := path.Join(t.TempDir(), "export")
+
+	var exported LearningsProgress
+	checkError(varnam.ExportWithProgress(exportPath, 1000, func(progress LearningsProgress) {
+		exported = progress
+	}))
+	assertEqual(t, exported.Words > 0, true)
+
+	var imported LearningsProgress
+	checkError(varnam.ImportWithProgress(exportPath+"-1.vlf", func(progress LearningsProgress) {
+		imported = progress
+	}))
+	assertEqual(t, imported.Words, exported.Words)
+}
+
+func TestCompactLearnings(t *testing.T) {
+	config := Config{DictionarySuggestionsLimit: 5, PatternDictionarySuggestionsLimit: 5, TokenizerSuggestionsLimit: 10, TokenizerSuggestionsAlways: true}
+	defer varnam.SetConfig(config)
+
+	_, err := varnam.CompactLearnings()
+	assertEqual(t, err != nil, true)
+
+	config.LearningHalfLife = 30 * 24 * 60 * 60
+	varnam.SetConfig(config)
+
+	_, err = varnam.CompactLearnings()
+	checkError(err)
+}
+
+func TestSearchSymbolTable(t *testing.T) {
+	search := NewSearchSymbol()
+	search.Pattern = "ka"
+
+	symbols := varnam.SearchSymbolTable(context.Background(), search)
+	assertEqual(t, len(symbols) > 0, true)
+	assertEqual(t, symbols[0].Value1, "ക")
+}
+
+func TestMain(m *testing.M) {
+	testTempDir, err := os.MkdirTemp("", "govarnamgo_native_test")
+	checkError(err)
+
+	govarnam.SetLearningsDir(testTempDir)
+
+	varnam, err = InitFromID("ml")
+	checkError(err)
+
+	code := m.Run()
+
+	varnam.Close()
+	os.RemoveAll(testTempDir)
+
+	os.Exit(code)
+}
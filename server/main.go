@@ -0,0 +1,83 @@
+package main
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/varnamproject/govarnam/govarnam"
+)
+
+func main() {
+	versionFlag := flag.Bool("version", false, "Show version information")
+	debugFlag := flag.Bool("debug", false, "Enable debugging outputs")
+
+	addrFlag := flag.String("addr", "127.0.0.1:8123", "Address to listen on")
+	schemesFlag := flag.String("s", "", "Comma separated scheme IDs to load on start. Other schemes are loaded on first request")
+	timeoutFlag := flag.Duration("timeout", 10*time.Second, "Maximum time for a request. 0 for no limit")
+	cacheFlag := flag.Int("cache", 1000, "Number of transliteration results to cache per scheme. 0 disables it")
+
+	flag.Parse()
+
+	if *versionFlag {
+		fmt.Println(govarnam.VersionString)
+		return
+	}
+
+	s := newServer(*timeoutFlag, *cacheFlag, *debugFlag)
+
+	if *schemesFlag != "" {
+		for _, schemeID := range strings.Split(*schemesFlag, ",") {
+			_, err := s.getScheme(strings.TrimSpace(schemeID))
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	httpServer := &http.Server{
+		Addr:    *addrFlag,
+		Handler: s,
+	}
+
+	// Closed when requests being served are done
+	shutdownDone := make(chan bool)
+
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+
+		err := httpServer.Shutdown(context.Background())
+		if err != nil {
+			log.Print(err)
+		}
+		close(shutdownDone)
+	}()
+
+	log.Printf("varnamd listening on %s", *addrFlag)
+
+	err := httpServer.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+
+	// ListenAndServe returns as soon as Shutdown starts
+	<-shutdownDone
+
+	// Learnings DB should be closed properly
+	s.close()
+}
@@ -0,0 +1,405 @@
+package main
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/varnamproject/govarnam/govarnam"
+)
+
+type server struct {
+	sync.Mutex
+	schemes map[string]*govarnam.Varnam
+
+	// Maximum time for a request. 0 for no limit
+	timeout time.Duration
+
+	// Transliteration cache size of each scheme
+	cacheSize int
+
+	debug bool
+}
+
+func newServer(timeout time.Duration, cacheSize int, debug bool) *server {
+	return &server{
+		schemes:   map[string]*govarnam.Varnam{},
+		timeout:   timeout,
+		cacheSize: cacheSize,
+		debug:     debug,
+	}
+}
+
+// Error made by bad input. Sent with status 400
+type requestError struct {
+	msg string
+}
+
+func (err requestError) Error() string {
+	return err.msg
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return requestError{fmt.Sprintf(format, args...)}
+}
+
+// Get the instance of a scheme, initializing it if needed
+func (s *server) getScheme(schemeID string) (*govarnam.Varnam, error) {
+	if schemeID == "" {
+		return nil, badRequest("scheme_id is empty")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if varnam, found := s.schemes[schemeID]; found {
+		return varnam, nil
+	}
+
+	varnam, err := govarnam.InitFromID(schemeID)
+	if err != nil {
+		return nil, badRequest("%s", err.Error())
+	}
+	varnam.Debug = s.debug
+	varnam.EnableCache(s.cacheSize)
+
+	s.schemes[schemeID] = varnam
+
+	return varnam, nil
+}
+
+func (s *server) close() {
+	s.Lock()
+	defer s.Unlock()
+
+	for schemeID, varnam := range s.schemes {
+		varnam.Close()
+		delete(s.schemes, schemeID)
+	}
+}
+
+type transliterateArgs struct {
+	SchemeID      string   `json:"scheme_id"`
+	Word          string   `json:"word"`
+	PreviousWords []string `json:"previous_words"`
+}
+
+type learnArgs struct {
+	SchemeID string `json:"scheme_id"`
+	Word     string `json:"word"`
+	Weight   int    `json:"weight"`
+}
+
+type trainArgs struct {
+	SchemeID string `json:"scheme_id"`
+	Pattern  string `json:"pattern"`
+	Word     string `json:"word"`
+}
+
+type successResponse struct {
+	Success bool `json:"success"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type method func(s *server, ctx context.Context, body []byte) (interface{}, error)
+
+// Methods and their HTTP paths. Methods taking
+// a body are served only with POST requests.
+var methods = map[string]method{
+	"/schemes":                schemesMethod,
+	"/transliterate":          transliterateMethod,
+	"/transliterate-advanced": transliterateAdvancedMethod,
+	"/reverse-transliterate":  reverseTransliterateMethod,
+	"/learn":                  learnMethod,
+	"/unlearn":                unlearnMethod,
+	"/train":                  trainMethod,
+	"/suggestions":            suggestionsMethod,
+}
+
+func decodeArgs(body []byte, args interface{}) error {
+	err := json.Unmarshal(body, args)
+	if err != nil {
+		return badRequest("invalid JSON body: %s", err.Error())
+	}
+	return nil
+}
+
+// JSON encodes nil slices as null
+func nonNilSuggestions(sugs []govarnam.Suggestion) []govarnam.Suggestion {
+	if sugs == nil {
+		return []govarnam.Suggestion{}
+	}
+	return sugs
+}
+
+func schemesMethod(s *server, ctx context.Context, body []byte) (interface{}, error) {
+	schemeDetails, err := govarnam.GetAllSchemeDetails()
+	if err != nil {
+		return nil, err
+	}
+	if schemeDetails == nil {
+		schemeDetails = []govarnam.SchemeDetails{}
+	}
+	return schemeDetails, nil
+}
+
+func getTransliterateArgs(s *server, body []byte) (*transliterateArgs, *govarnam.Varnam, error) {
+	var args transliterateArgs
+	err := decodeArgs(body, &args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if args.Word == "" {
+		return nil, nil, badRequest("word is empty")
+	}
+
+	varnam, err := s.getScheme(args.SchemeID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &args, varnam, nil
+}
+
+func transliterateMethod(s *server, ctx context.Context, body []byte) (interface{}, error) {
+	args, varnam, err := getTransliterateArgs(s, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Buffered so that the goroutine won't block
+	// if the request got cancelled in between
+	channel := make(chan []govarnam.Suggestion, 1)
+
+	go func() {
+		if len(args.PreviousWords) > 0 {
+			varnam.TransliterateWithPreviousWords(ctx, args.PreviousWords, args.Word, channel)
+		} else {
+			varnam.TransliterateWithContext(ctx, args.Word, channel)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-channel:
+		return nonNilSuggestions(result), nil
+	}
+}
+
+func transliterateAdvancedMethod(s *server, ctx context.Context, body []byte) (interface{}, error) {
+	args, varnam, err := getTransliterateArgs(s, body)
+	if err != nil {
+		return nil, err
+	}
+
+	channel := make(chan govarnam.TransliterationResult, 1)
+
+	go func() {
+		if len(args.PreviousWords) > 0 {
+			varnam.TransliterateAdvancedWithPreviousWords(ctx, args.PreviousWords, args.Word, channel)
+		} else {
+			varnam.TransliterateAdvancedWithContext(ctx, args.Word, channel)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-channel:
+		result.ExactWords = nonNilSuggestions(result.ExactWords)
+		result.ExactMatches = nonNilSuggestions(result.ExactMatches)
+		result.DictionarySuggestions = nonNilSuggestions(result.DictionarySuggestions)
+		result.PatternDictionarySuggestions = nonNilSuggestions(result.PatternDictionarySuggestions)
+		result.TokenizerSuggestions = nonNilSuggestions(result.TokenizerSuggestions)
+		result.GreedyTokenized = nonNilSuggestions(result.GreedyTokenized)
+		return result, nil
+	}
+}
+
+func reverseTransliterateMethod(s *server, ctx context.Context, body []byte) (interface{}, error) {
+	args, varnam, err := getTransliterateArgs(s, body)
+	if err != nil {
+		return nil, err
+	}
+
+	sugs, err := varnam.ReverseTransliterate(args.Word)
+	if err != nil {
+		return nil, err
+	}
+
+	return nonNilSuggestions(sugs), nil
+}
+
+func suggestionsMethod(s *server, ctx context.Context, body []byte) (interface{}, error) {
+	args, varnam, err := getTransliterateArgs(s, body)
+	if err != nil {
+		return nil, err
+	}
+
+	sugs := varnam.GetSuggestions(ctx, args.Word)
+
+	// GetSuggestions returns empty result when cancelled
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return nonNilSuggestions(sugs), nil
+}
+
+func learnMethod(s *server, ctx context.Context, body []byte) (interface{}, error) {
+	var args learnArgs
+	err := decodeArgs(body, &args)
+	if err != nil {
+		return nil, err
+	}
+
+	varnam, err := s.getScheme(args.SchemeID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = varnam.Learn(args.Word, args.Weight)
+	if err != nil {
+		return nil, badRequest("%s", err.Error())
+	}
+
+	return successResponse{true}, nil
+}
+
+func unlearnMethod(s *server, ctx context.Context, body []byte) (interface{}, error) {
+	var args learnArgs
+	err := decodeArgs(body, &args)
+	if err != nil {
+		return nil, err
+	}
+
+	varnam, err := s.getScheme(args.SchemeID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = varnam.Unlearn(args.Word)
+	if err != nil {
+		return nil, badRequest("%s", err.Error())
+	}
+
+	return successResponse{true}, nil
+}
+
+func trainMethod(s *server, ctx context.Context, body []byte) (interface{}, error) {
+	var args trainArgs
+	err := decodeArgs(body, &args)
+	if err != nil {
+		return nil, err
+	}
+
+	varnam, err := s.getScheme(args.SchemeID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = varnam.Train(args.Pattern, args.Word)
+	if err != nil {
+		return nil, badRequest("%s", err.Error())
+	}
+
+	return successResponse{true}, nil
+}
+
+// Words are short, a bigger body is a mistake
+const maxBodySize = 1 << 20
+
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxBodySize {
+		return nil, fmt.Errorf("body is too large")
+	}
+	return body, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+
+	handler, found := methods[path]
+	if !found {
+		writeJSON(w, http.StatusNotFound, errorResponse{"not found"})
+		return
+	}
+
+	var body []byte
+	if path == "/schemes" {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{"method not allowed"})
+			return
+		}
+	} else {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{"method not allowed"})
+			return
+		}
+
+		var err error
+		body, err = readBody(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+			return
+		}
+	}
+
+	// Context is cancelled when client closes connection
+	ctx := r.Context()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	result, err := handler(s, ctx, body)
+	if err != nil {
+		status := http.StatusInternalServerError
+
+		var reqErr requestError
+		if errors.As(err, &reqErr) {
+			status = http.StatusBadRequest
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			status = http.StatusGatewayTimeout
+		} else if errors.Is(err, context.Canceled) {
+			status = http.StatusRequestTimeout
+		}
+
+		if s.debug {
+			fmt.Println(r.URL.Path, err)
+		}
+
+		writeJSON(w, status, errorResponse{err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
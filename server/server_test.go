@@ -0,0 +1,149 @@
+package main
+
+/**
+ * govarnam - An Indian language transliteration library
+ * Copyright Subin Siby <mail at subinsb (.) com>, 2021
+ * Licensed under AGPL-3.0-only. See LICENSE.txt
+ */
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/varnamproject/govarnam/govarnam"
+)
+
+var testServer *server
+
+func assertEqual(t *testing.T, value1 interface{}, value2 interface{}) {
+	if !reflect.DeepEqual(value1, value2) {
+		t.Errorf("Expected %v (type %v), Received %v (type %v)", value2, reflect.TypeOf(value2), value1, reflect.TypeOf(value1))
+	}
+}
+
+func request(method string, path string, body interface{}, result interface{}) int {
+	var reader *bytes.Reader
+	if body == nil {
+		reader = bytes.NewReader(nil)
+	} else if raw, ok := body.(string); ok {
+		reader = bytes.NewReader([]byte(raw))
+	} else {
+		data, err := json.Marshal(body)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	recorder := httptest.NewRecorder()
+	testServer.ServeHTTP(recorder, httptest.NewRequest(method, path, reader))
+
+	if result != nil {
+		err := json.Unmarshal(recorder.Body.Bytes(), result)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	return recorder.Code
+}
+
+func TestSchemes(t *testing.T) {
+	var schemes []govarnam.SchemeDetails
+	assertEqual(t, request("GET", "/schemes", nil, &schemes), http.StatusOK)
+
+	found := false
+	for _, scheme := range schemes {
+		if scheme.Identifier == "ml" {
+			found = true
+		}
+	}
+	assertEqual(t, found, true)
+
+	assertEqual(t, request("POST", "/schemes", nil, nil), http.StatusMethodNotAllowed)
+}
+
+func TestTransliterate(t *testing.T) {
+	var sugs []govarnam.Suggestion
+	assertEqual(t, request("POST", "/transliterate", transliterateArgs{SchemeID: "ml", Word: "nithyam"}, &sugs), http.StatusOK)
+	assertEqual(t, len(sugs) > 0, true)
+
+	var result govarnam.TransliterationResult
+	assertEqual(t, request("POST", "/transliterate-advanced", transliterateArgs{SchemeID: "ml", Word: "nithyam"}, &result), http.StatusOK)
+	assertEqual(t, len(result.GreedyTokenized) > 0, true)
+
+	sugs = nil
+	assertEqual(t, request("POST", "/reverse-transliterate", transliterateArgs{SchemeID: "ml", Word: "നിത്യം"}, &sugs), http.StatusOK)
+	assertEqual(t, len(sugs) > 0, true)
+}
+
+func TestLearnAndUnlearn(t *testing.T) {
+	var response successResponse
+	assertEqual(t, request("POST", "/learn", learnArgs{SchemeID: "ml", Word: "വയലിൽ"}, &response), http.StatusOK)
+	assertEqual(t, response.Success, true)
+
+	var sugs []govarnam.Suggestion
+	assertEqual(t, request("POST", "/suggestions", transliterateArgs{SchemeID: "ml", Word: "വയ"}, &sugs), http.StatusOK)
+	assertEqual(t, len(sugs) > 0 && sugs[0].Word == "വയലിൽ", true)
+
+	assertEqual(t, request("POST", "/unlearn", learnArgs{SchemeID: "ml", Word: "വയലിൽ"}, nil), http.StatusOK)
+
+	sugs = nil
+	assertEqual(t, request("POST", "/suggestions", transliterateArgs{SchemeID: "ml", Word: "വയ"}, &sugs), http.StatusOK)
+	assertEqual(t, sugs, []govarnam.Suggestion{})
+
+	assertEqual(t, request("POST", "/train", trainArgs{SchemeID: "ml", Pattern: "india", Word: "ഇന്ത്യ"}, nil), http.StatusOK)
+}
+
+func TestErrors(t *testing.T) {
+	var response errorResponse
+
+	assertEqual(t, request("POST", "/transliterate", "{", &response), http.StatusBadRequest)
+	assertEqual(t, response.Error != "", true)
+
+	assertEqual(t, request("POST", "/transliterate", transliterateArgs{SchemeID: "ml"}, nil), http.StatusBadRequest)
+	assertEqual(t, request("POST", "/transliterate", transliterateArgs{SchemeID: "unknown", Word: "a"}, nil), http.StatusBadRequest)
+	assertEqual(t, request("POST", "/learn", learnArgs{SchemeID: "ml", Word: "a"}, nil), http.StatusBadRequest)
+	assertEqual(t, request("GET", "/transliterate", nil, nil), http.StatusMethodNotAllowed)
+	assertEqual(t, request("GET", "/unknown", nil, nil), http.StatusNotFound)
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := transliterateMethod(testServer, ctx, []byte(`{"scheme_id": "ml", "word": "nithyam"}`))
+	assertEqual(t, err, context.Canceled)
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	_, err = transliterateAdvancedMethod(testServer, ctx, []byte(`{"scheme_id": "ml", "word": "nithyam"}`))
+	assertEqual(t, err, context.DeadlineExceeded)
+}
+
+func TestMain(m *testing.M) {
+	testTempDir, err := os.MkdirTemp("", "varnamd_test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	govarnam.SetLearningsDir(testTempDir)
+
+	testServer = newServer(0, 100, false)
+
+	code := m.Run()
+
+	testServer.close()
+	os.RemoveAll(testTempDir)
+
+	os.Exit(code)
+}